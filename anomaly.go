@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"deploy/history"
+)
+
+const (
+	anomalySamples = 20
+	anomalyMinimum = 5
+)
+
+var anomalyThreshold = 2.0
+
+func parseAnomalyThreshold(value string) (float64, error) {
+	if value == "" {
+		return 2, nil
+	}
+
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 1 {
+		return 0, fmt.Errorf("invalid anomaly threshold %q, expected a multiplier greater than 1", value)
+	}
+
+	return threshold, nil
+}
+
+func (deployment *Deployment) durationAnomaly(duration time.Duration) (Field, bool) {
+	var durations []time.Duration
+	store.View(func(state *State) {
+		durations = history.Durations(state.History, deployment.Environment.Name, deployment.Key, anomalySamples)
+	})
+
+	if len(durations) < anomalyMinimum {
+		return Field{}, false
+	}
+
+	median := history.Median(durations)
+	if float64(duration) <= float64(median)*anomalyThreshold {
+		return Field{}, false
+	}
+
+	return Field{
+		Name:  "⚠️ Slow Deployment",
+		Value: fmt.Sprintf("Took %s, %.1fx the median of %s over the last %d successful deployments.", duration.Round(time.Second), float64(duration)/float64(median), median.Round(time.Second), len(durations)),
+	}, true
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func archiveEnabled() bool {
+	return data.LogsBucket != ""
+}
+
+func s3Object(bucket, key string) string {
+	endpoint := data.LogsEndpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + awsCredentials().Region + ".amazonaws.com"
+	}
+
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, key)
+}
+
+func archiveObject(name string) string {
+	return s3Object(data.LogsBucket, data.LogsPrefix+name)
+}
+
+func archiveURL(environment, id string) string {
+	return archiveObject(url.PathEscape(environment) + "/" + id + ".log")
+}
+
+func archiveLog(ctx context.Context, environment, id string, output []byte) (string, error) {
+	return uploadArchive(ctx, archiveURL(environment, id), "text/plain; charset=utf-8", output)
+}
+
+func uploadArchive(ctx context.Context, object, contentType string, body []byte) (string, error) {
+	if _, err := s3Request(ctx, http.MethodPut, object, contentType, body); err != nil {
+		return "", err
+	}
+
+	return presignArchive(object)
+}
+
+func s3Request(ctx context.Context, method, object, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, object, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	signAWSRequest(req, body, awsCredentials(), "s3", time.Now())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http.Do(): %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("unexpected status %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func presignArchive(object string) (string, error) {
+	expires, err := time.ParseDuration(data.LogsExpiry)
+	if err != nil {
+		return "", fmt.Errorf("invalid LOGS_S3_EXPIRY: %w", err)
+	}
+
+	target, err := url.Parse(object)
+	if err != nil {
+		return "", err
+	}
+
+	return presignAWSURL(target, awsCredentials(), "s3", min(expires, 7*24*time.Hour), time.Now()), nil
+}
+
+func presignAWSURL(target *url.URL, credentials AWSCredentials, service string, expires time.Duration, now time.Time) string {
+	stamp := now.UTC().Format("20060102T150405Z")
+	date := stamp[:8]
+	scope := date + "/" + credentials.Region + "/" + service + "/aws4_request"
+
+	query := target.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", stamp)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	encoded := awsCanonicalQuery(query)
+
+	request := strings.Join([]string{http.MethodGet, target.EscapedPath(), encoded, "host:" + target.Host + "\n", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", stamp, scope, sha256Hex([]byte(request))}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(awsSigningKey(credentials, date, service), toSign))
+
+	signed := *target
+	signed.RawQuery = encoded + "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+func (deployment *Deployment) archive(output []byte) []Field {
+	if !archiveEnabled() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(deployment.context(), 30*time.Second)
+	defer cancel()
+
+	link, err := archiveLog(ctx, deployment.Environment.Name, deployment.ID, []byte(cleanOutput(string(output))))
+	if err != nil {
+		log.Printf("archiveLog(): %v", err)
+		return nil
+	}
+
+	return []Field{{Name: "Full Log", Value: fmt.Sprintf("[%s.log](%s)", deployment.ID, link)}}
+}
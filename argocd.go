@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"deploy/engine"
+)
+
+type argoApplication struct {
+	Status struct {
+		Sync struct {
+			Status   string `json:"status"`
+			Revision string `json:"revision"`
+		} `json:"sync"`
+		Health struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"health"`
+		OperationState *struct {
+			Phase     string    `json:"phase"`
+			Message   string    `json:"message"`
+			StartedAt time.Time `json:"startedAt"`
+		} `json:"operationState"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+func (app *argoApplication) phase() string {
+	if app.Status.OperationState == nil {
+		return ""
+	}
+	return app.Status.OperationState.Phase
+}
+
+func (app *argoApplication) summary() string {
+	summary := fmt.Sprintf("Argo CD: %s / %s", app.Status.Sync.Status, app.Status.Health.Status)
+	if phase := app.phase(); phase != "" {
+		summary += " (operation " + phase + ")"
+	}
+	return summary
+}
+
+func (app *argoApplication) conditions() string {
+	lines := []string{}
+	if app.Status.OperationState != nil && app.Status.OperationState.Message != "" {
+		lines = append(lines, app.Status.OperationState.Message)
+	}
+	if app.Status.Health.Message != "" {
+		lines = append(lines, app.Status.Health.Message)
+	}
+	for _, condition := range app.Status.Conditions {
+		lines = append(lines, condition.Type+": "+condition.Message)
+	}
+	return strings.Join(lines, "; ")
+}
+
+func runArgoCD(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer, report func(string)) error {
+	with := map[string]string{}
+	for key, value := range step.With {
+		with[key] = request.Expand(value)
+	}
+
+	server, name := strings.TrimSuffix(with["server"], "/"), with["app"]
+	if server == "" || name == "" {
+		return errors.New("argocd steps need a server and an app")
+	}
+
+	token := secret(with["token"], os.Getenv(with["token"]))
+	authorize := func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout(step))
+	defer cancel()
+
+	target := server + "/api/v1/applications/" + url.PathEscape(name)
+	sync := map[string]any{"prune": with["prune"] == "true"}
+	if with["revision"] != "" {
+		sync["revision"] = with["revision"]
+	}
+
+	requested := time.Now().Truncate(time.Second)
+	if _, err := apiRequest(ctx, http.MethodPost, target+"/sync", sync, authorize, nil); err != nil {
+		return fmt.Errorf("argocd sync: %w", err)
+	}
+	fmt.Fprintf(output, "Requested Argo CD sync of %s\n", name)
+
+	app := &argoApplication{}
+	err := poll(ctx, pollInterval(with), func() (bool, error) {
+		*app = argoApplication{}
+		if _, err := apiRequest(ctx, http.MethodGet, target, nil, authorize, app); err != nil {
+			return false, err
+		}
+		report(app.summary())
+		if app.Status.OperationState == nil || app.Status.OperationState.StartedAt.Before(requested) {
+			return false, nil
+		}
+
+		switch app.phase() {
+		case "Failed", "Error":
+			return false, fmt.Errorf("sync %s", strings.ToLower(app.phase()))
+		case "Succeeded":
+			return app.Status.Sync.Status == "Synced" && app.Status.Health.Status == "Healthy", nil
+		}
+		return false, nil
+	})
+
+	fmt.Fprintln(output, app.summary())
+	if conditions := app.conditions(); conditions != "" {
+		fmt.Fprintln(output, conditions)
+	}
+
+	if err != nil {
+		if conditions := app.conditions(); conditions != "" {
+			return fmt.Errorf("argocd %s: %w (%s)", name, err, conditions)
+		}
+		return fmt.Errorf("argocd %s: %w", name, err)
+	}
+
+	fmt.Fprintf(output, "%s is Synced and Healthy at %.7s\n", name, app.Status.Sync.Revision)
+	return nil
+}
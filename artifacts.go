@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var tagPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+func deployArtifact(ctx context.Context, deployment *Deployment) ([]byte, error) {
+	output := &bytes.Buffer{}
+	entry, tag, location := deployment.Entry, deployment.Branch, deployment.Environment.Location
+
+	release, err := getGithubRelease(ctx, entry.Repository, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ReplaceAll(entry.Asset, "${TAG}", tag)
+	asset, ok := release.Asset(name)
+	if !ok {
+		return nil, fmt.Errorf("asset %s not found in release %s", name, tag)
+	}
+
+	tmp, err := os.MkdirTemp("", "deploy-artifact-")
+	if err != nil {
+		return nil, fmt.Errorf("os.MkdirTemp(): %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, asset.Name)
+	sum, err := downloadGithubAsset(ctx, asset, path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(output, "Downloaded %s (%d bytes, sha256 %s)\n", asset.Name, asset.Size, sum)
+
+	if err := verifyArtifact(ctx, entry, release, tag, tmp, asset.Name, sum); err != nil {
+		return output.Bytes(), err
+	}
+
+	if err := unpack(path, location); err != nil {
+		return output.Bytes(), err
+	}
+	fmt.Fprintf(output, "Unpacked %s into %s\n", asset.Name, location)
+
+	if entry.Restart == "" {
+		return output.Bytes(), nil
+	}
+
+	out, err := deployment.execute(ctx, location, entry.Restart, "${TAG}", tag)
+	output.Write(out)
+	if err != nil {
+		return output.Bytes(), fmt.Errorf("restart: %w", err)
+	}
+
+	return output.Bytes(), nil
+}
+
+func unpack(path, dest string) error {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return fmt.Errorf("os.MkdirAll(): %w", err)
+	}
+
+	switch name := filepath.Base(path); {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return untar(path, dest)
+	case strings.HasSuffix(name, ".zip"):
+		return unzip(path, dest)
+	default:
+		return extractFile(filepath.Join(dest, name), 0o755, func() (io.ReadCloser, error) { return os.Open(path) })
+	}
+}
+
+func untar(path, dest string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("os.Open(): %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("gzip.NewReader(): %w", err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("tar.Next(): %w", err)
+		}
+
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("archive entry %s escapes destination", header.Name)
+		}
+
+		target := filepath.Join(dest, header.Name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("os.MkdirAll(): %w", err)
+			}
+		case tar.TypeReg:
+			if err := extractFile(target, header.FileInfo().Mode(), func() (io.ReadCloser, error) { return io.NopCloser(reader), nil }); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unzip(path, dest string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("zip.OpenReader(): %w", err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !filepath.IsLocal(file.Name) {
+			return fmt.Errorf("archive entry %s escapes destination", file.Name)
+		}
+
+		target := filepath.Join(dest, file.Name)
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("os.MkdirAll(): %w", err)
+			}
+			continue
+		}
+
+		if err := extractFile(target, file.Mode(), func() (io.ReadCloser, error) { return file.Open() }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractFile(target string, mode os.FileMode, open func() (io.ReadCloser, error)) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("os.MkdirAll(): %w", err)
+	}
+
+	src, err := open()
+	if err != nil {
+		return fmt.Errorf("open(): %w", err)
+	}
+	defer src.Close()
+
+	tmp := target + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return fmt.Errorf("os.OpenFile(): %w", err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("io.Copy(): %w", err)
+	}
+
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("file.Close(): %w", err)
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("os.Rename(): %w", err)
+	}
+
+	return nil
+}
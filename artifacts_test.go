@@ -0,0 +1,94 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var archiveTests = []struct {
+	name  string
+	entry string
+	safe  bool
+}{
+	{"Plain", "app/bin/server", true},
+	{"Dotted", "app/./bin/../server", true},
+	{"Parent", "../evil", false},
+	{"NestedParent", "app/../../evil", false},
+	{"Absolute", "/tmp/evil", false},
+}
+
+func writeTar(t *testing.T, path, entry string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+	if err := archive.WriteHeader(&tar.Header{Name: entry, Mode: 0o644, Size: 2, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	archive.Write([]byte("ok"))
+	archive.Close()
+	gz.Close()
+}
+
+func writeZip(t *testing.T, path, entry string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	w, err := archive.Create(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("ok"))
+	archive.Close()
+}
+
+func TestExtractTraversal(t *testing.T) {
+	extractors := []struct {
+		name    string
+		write   func(*testing.T, string, string)
+		extract func(string, string) error
+	}{
+		{"untar", writeTar, untar},
+		{"unzip", writeZip, unzip},
+	}
+
+	for _, extractor := range extractors {
+		for _, test := range archiveTests {
+			t.Run(extractor.name+"/"+test.name, func(t *testing.T) {
+				root := t.TempDir()
+				archive, dest := filepath.Join(root, "artifact"), filepath.Join(root, "dest")
+				extractor.write(t, archive, test.entry)
+
+				err := extractor.extract(archive, dest)
+				if test.safe != (err == nil) {
+					t.Fatalf("%s(%q) error = %v, want safe = %v", extractor.name, test.entry, err, test.safe)
+				}
+
+				if _, err := os.Stat(filepath.Join(root, "evil")); err == nil {
+					t.Errorf("%s(%q) wrote outside the destination", extractor.name, test.entry)
+				}
+				if test.safe {
+					if body, err := os.ReadFile(filepath.Join(dest, test.entry)); err != nil || string(body) != "ok" {
+						t.Errorf("%s(%q) = %q, %v, want the extracted file", extractor.name, test.entry, body, err)
+					}
+				}
+			})
+		}
+	}
+}
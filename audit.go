@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"deploy/audit"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type AuditEvent = audit.Event
+
+var auditLog = &audit.Logger{}
+
+func loadAudit() error {
+	if data.AuditSyslog != "" {
+		sink, err := audit.NewSyslogSink(data.AuditSyslog, "deploy")
+		if err != nil {
+			return fmt.Errorf("AUDIT_SYSLOG: %w", err)
+		}
+		auditLog.Sinks = append(auditLog.Sinks, sink)
+	}
+
+	if data.AuditURL != "" {
+		auditLog.Sinks = append(auditLog.Sinks, &audit.HTTPSink{URL: data.AuditURL, Token: secret("AUDIT_TOKEN", data.AuditToken)})
+	}
+
+	return nil
+}
+
+func auditRejected(environment *Environment, author *discordgo.User, credential string, args []string, err error) {
+	event := &audit.Event{Type: "attempt", Status: "rejected", Environment: environment.Name, Actor: author.ID, Username: author.Username, Detail: map[string]string{"error": err.Error()}}
+	if credential != "" {
+		event.Detail["credential"] = credential
+	}
+	if len(args) > 1 {
+		event.Ref, event.Key = args[0], args[1]
+	}
+	auditLog.Record(event)
+}
+
+func (deployment *Deployment) audit(kind, status string, actor *discordgo.User, detail map[string]string) {
+	if actor == nil {
+		actor = deployment.Author
+	}
+
+	if deployment.Credential != "" {
+		detail = maps.Clone(detail)
+		if detail == nil {
+			detail = map[string]string{}
+		}
+		detail["credential"] = deployment.Credential
+	}
+
+	auditLog.Record(&audit.Event{
+		Type:        kind,
+		Status:      status,
+		Deployment:  deployment.ID,
+		Environment: deployment.Environment.Name,
+		Key:         deployment.Key,
+		Ref:         deployment.Branch,
+		SHA:         deployment.SHA,
+		Actor:       actor.ID,
+		Username:    actor.Username,
+		Detail:      detail,
+	})
+}
+
+func auditLock(status string, environment *Environment, lock *Lock, actor *discordgo.User) {
+	event := &audit.Event{Type: "lock", Status: status, Environment: environment.Name, Actor: lock.Author, Username: lock.Username, Detail: map[string]string{"holder": lock.Author, "reason": lock.Reason, "expires": lock.Expires.Format(time.RFC3339)}}
+	if actor != nil {
+		event.Actor, event.Username = actor.ID, actor.Username
+	}
+	auditLog.Record(event)
+}
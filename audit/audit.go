@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"deploy/notify"
+)
+
+type Event struct {
+	Time        time.Time         `json:"time"`
+	Type        string            `json:"type"`
+	Status      string            `json:"status"`
+	Deployment  string            `json:"deployment,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Key         string            `json:"key,omitempty"`
+	Ref         string            `json:"ref,omitempty"`
+	SHA         string            `json:"sha,omitempty"`
+	Actor       string            `json:"actor,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Detail      map[string]string `json:"detail,omitempty"`
+}
+
+type Sink interface {
+	Write(ctx context.Context, event *Event) error
+}
+
+type Logger struct {
+	Sinks []Sink
+
+	wg sync.WaitGroup
+}
+
+func (logger *Logger) Record(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	for _, sink := range logger.Sinks {
+		logger.wg.Add(1)
+		go func() {
+			defer logger.wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := sink.Write(ctx, event); err != nil {
+				log.Printf("audit.Write(): %v", err)
+			}
+		}()
+	}
+}
+
+func (logger *Logger) Wait() {
+	logger.wg.Wait()
+}
+
+type HTTPSink struct {
+	URL   string
+	Token string
+}
+
+func (sink *HTTPSink) Write(ctx context.Context, event *Event) error {
+	headers := []string{}
+	if sink.Token != "" {
+		headers = append(headers, "Authorization", "Bearer "+sink.Token)
+	}
+	return notify.PostJSON(ctx, sink.URL, event, headers...)
+}
+
+type SyslogSink struct {
+	Network string
+	Address string
+	Tag     string
+}
+
+func NewSyslogSink(address, tag string) (*SyslogSink, error) {
+	target, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("url.Parse(): %w", err)
+	}
+
+	sink := &SyslogSink{Network: target.Scheme, Address: target.Host, Tag: tag}
+	switch target.Scheme {
+	case "udp", "tcp":
+		if target.Port() == "" {
+			sink.Address = net.JoinHostPort(target.Hostname(), "514")
+		}
+	case "unix", "unixgram":
+		sink.Address = target.Path
+	default:
+		return nil, fmt.Errorf("unsupported syslog scheme %q, expected udp, tcp, unix or unixgram", target.Scheme)
+	}
+
+	return sink, nil
+}
+
+func (sink *SyslogSink) message(event *Event) ([]byte, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal(): %w", err)
+	}
+
+	severity := 5
+	if event.Status == "failed" || event.Status == "rejected" || event.Status == "denied" {
+		severity = 4
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	const facility = 13
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ", facility*8+severity, event.Time.Format(time.RFC3339Nano), hostname, sink.Tag, os.Getpid(), event.Type)
+	return append([]byte(header), body...), nil
+}
+
+func (sink *SyslogSink) Write(ctx context.Context, event *Event) error {
+	message, err := sink.message(event)
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, sink.Network, sink.Address)
+	if err != nil {
+		return fmt.Errorf("net.Dial(): %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if sink.Network == "tcp" {
+		message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	}
+
+	_, err = conn.Write(message)
+	return err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var defaultPullRequestActions = []string{"opened", "reopened", "synchronize"}
+
+type AutoDeploy struct {
+	Event      string   `json:"event"`
+	Branches   []string `json:"branches"`
+	Actions    []string `json:"actions"`
+	Repository string   `json:"repository"`
+	Key        string   `json:"key"`
+	Batch      string   `json:"batch"`
+}
+
+type pushBatch struct {
+	first  *WebhookEvent
+	latest *WebhookEvent
+	pushes int
+}
+
+var (
+	batchesMu sync.Mutex
+	batches   = map[string]*pushBatch{}
+)
+
+func (batch *pushBatch) commits() string {
+	if batch.first.Before == "" || batch.first.Before == deletedSHA {
+		return fmt.Sprintf("%.7s (%d pushes)", batch.latest.SHA, batch.pushes)
+	}
+	return fmt.Sprintf("%.7s..%.7s (%d pushes)", batch.first.Before, batch.latest.SHA, batch.pushes)
+}
+
+func (rule *AutoDeploy) batchWindow() time.Duration {
+	if rule.Event != "push" {
+		return 0
+	}
+
+	window, err := time.ParseDuration(rule.Batch)
+	if err != nil || window <= 0 {
+		return 0
+	}
+	return window
+}
+
+func batchPush(session *discordgo.Session, environment *Environment, rule *AutoDeploy, event *WebhookEvent) {
+	key := environment.Name + "/" + rule.Key + "/" + event.Branch
+
+	batchesMu.Lock()
+	defer batchesMu.Unlock()
+
+	if batch, ok := batches[key]; ok {
+		batch.latest = event
+		batch.pushes++
+		return
+	}
+
+	batch := &pushBatch{first: event, latest: event, pushes: 1}
+	batches[key] = batch
+	session.ChannelMessageSend(environment.Channel, text(environment.Channel, "autodeploy.batching", "branch", event.Branch, "window", rule.batchWindow(), "key", rule.Key))
+
+	time.AfterFunc(rule.batchWindow(), func() {
+		batchesMu.Lock()
+		delete(batches, key)
+		commits := ""
+		if batch.pushes > 1 {
+			commits = batch.commits()
+		}
+		latest := batch.latest
+		batchesMu.Unlock()
+
+		runAutoDeploy(session, environment, rule, latest, commits)
+	})
+}
+
+func (rule *AutoDeploy) matches(environment *Environment, event *WebhookEvent) bool {
+	if rule.Event != event.Name || (rule.Repository != "" && !strings.EqualFold(rule.Repository, event.Repository)) {
+		return false
+	}
+
+	if event.Name == "pull_request" {
+		actions := rule.Actions
+		if len(actions) == 0 {
+			actions = defaultPullRequestActions
+		}
+		if !slices.Contains(actions, event.Action) {
+			return false
+		}
+	}
+
+	branches := rule.Branches
+	if len(branches) == 0 {
+		branches = []string{environment.Branch}
+	}
+
+	return slices.ContainsFunc(branches, func(pattern string) bool {
+		matched, err := path.Match(pattern, event.Branch)
+		return err == nil && matched
+	})
+}
+
+func autoDeploy(session *discordgo.Session, event *WebhookEvent) {
+	previewEvent(session, event)
+
+	for _, name := range slices.Sorted(maps.Keys(Environments)) {
+		environment := Environments[name]
+		for _, rule := range environment.AutoDeploy {
+			if !rule.matches(environment, event) {
+				continue
+			}
+
+			if entry, ok := Commands[rule.Key]; ok && entry.TOTP {
+				session.ChannelMessageSend(environment.Channel, text(environment.Channel, "autodeploy.totp", "branch", event.Branch, "environment", environment.Name, "key", rule.Key))
+				continue
+			}
+
+			if rule.batchWindow() > 0 {
+				batchPush(session, environment, rule, event)
+				continue
+			}
+			runAutoDeploy(session, environment, rule, event, "")
+		}
+	}
+}
+
+func runAutoDeploy(session *discordgo.Session, environment *Environment, rule *AutoDeploy, event *WebhookEvent, commits string) {
+	reason := text(environment.Channel, "autodeploy.reason", "event", event.describe(), "sha", fmt.Sprintf("%.7s", event.SHA))
+	deployment, _, err := newDeployment(environment, session.State.User, []string{event.Branch, rule.Key, reason})
+	if err != nil {
+		session.ChannelMessageSend(environment.Channel, text(environment.Channel, "autodeploy.skipped", "branch", event.Branch, "environment", environment.Name, "error", err.Error()))
+		return
+	}
+	deployment.Commits = commits
+	if event.SHA != "" {
+		deployment.SHA = event.SHA
+	}
+
+	log.Printf("Auto-deploying %s@%s to %s after %s", rule.Key, event.Branch, environment.Name, event.describe())
+	if commits != "" {
+		session.ChannelMessageSend(environment.Channel, deployment.text(environment.Channel, "autodeploy.batched", "branch", event.Branch, "commits", commits))
+	} else {
+		session.ChannelMessageSend(environment.Channel, deployment.text(environment.Channel, "autodeploy.started", "branch", event.Branch, "event", event.describe()))
+	}
+	startDeployment(session, environment.Channel, deployment)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+type AWSCredentials struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+func awsCredentials() AWSCredentials {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	return AWSCredentials{
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func hmacSHA256(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func awsSigningKey(credentials AWSCredentials, date, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, credentials.Region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func awsCanonicalHeaders(req *http.Request) (string, string) {
+	names := []string{"host"}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); lower != "authorization" && lower != "user-agent" {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		value := req.Host
+		if name != "host" {
+			value = strings.Join(req.Header.Values(name), ",")
+		}
+		canonical.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	return canonical.String(), strings.Join(names, ";")
+}
+
+// awsCanonicalQuery encodes the query as SigV4 expects: pairs sorted by key
+// and value, with spaces as %20 rather than url.Values' +.
+func awsCanonicalQuery(query url.Values) string {
+	escape := func(value string) string { return strings.ReplaceAll(url.QueryEscape(value), "+", "%20") }
+
+	pairs := []string{}
+	for _, key := range slices.SortedFunc(maps.Keys(query), func(a, b string) int { return strings.Compare(escape(a), escape(b)) }) {
+		values := []string{}
+		for _, value := range query[key] {
+			values = append(values, escape(value))
+		}
+		sort.Strings(values)
+
+		for _, value := range values {
+			pairs = append(pairs, escape(key)+"="+value)
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func signAWSRequest(req *http.Request, body []byte, credentials AWSCredentials, service string, now time.Time) {
+	if req.Host == "" {
+		req.Host = req.URL.Host
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	date := stamp[:8]
+	hash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", hash)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers, signed := awsCanonicalHeaders(req)
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	request := strings.Join([]string{req.Method, path, awsCanonicalQuery(req.URL.Query()), headers, signed, hash}, "\n")
+	scope := date + "/" + credentials.Region + "/" + service + "/aws4_request"
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", stamp, scope, sha256Hex([]byte(request))}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(awsSigningKey(credentials, date, service), toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+credentials.AccessKeyID+"/"+scope+", SignedHeaders="+signed+", Signature="+signature)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestAWSCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		query url.Values
+		want  string
+	}{
+		{url.Values{"prefix": {"state backups/"}}, "prefix=state%20backups%2F"},
+		{url.Values{"b": {"2", "1"}, "a": {"x+y"}}, "a=x%2By&b=1&b=2"},
+		{url.Values{"list-type": {"2"}, "list": {"*"}}, "list=%2A&list-type=2"},
+	}
+
+	for _, test := range tests {
+		if got := awsCanonicalQuery(test.query); got != test.want {
+			t.Errorf("awsCanonicalQuery(%v) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
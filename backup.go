@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var (
+	errBackupDisabled = errors.New("STATE_BACKUP is not set")
+	backupInterval    time.Duration
+	backupKeep        = 14
+)
+
+var adminCommands = map[string]func(*discordgo.Session, *discordgo.MessageCreate, []string){
+	"backup": adminBackup,
+	"gc":     adminGC,
+}
+
+func parseStateBackup(interval, keep string) (time.Duration, int, error) {
+	count, err := strconv.Atoi(keep)
+	if err != nil || count < 1 {
+		return 0, 0, fmt.Errorf("invalid STATE_BACKUP_KEEP %q, expected a positive number", keep)
+	}
+
+	if interval == "" {
+		return 0, count, nil
+	}
+
+	every, err := time.ParseDuration(interval)
+	if err != nil || every < time.Minute {
+		return 0, 0, fmt.Errorf("invalid STATE_BACKUP_INTERVAL %q, expected a duration of at least 1m", interval)
+	}
+
+	return every, count, nil
+}
+
+func (store *Store) Snapshot() ([]byte, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	body, err := json.MarshalIndent(&store.state, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json.MarshalIndent(): %w", err)
+	}
+	return body, nil
+}
+
+func s3Location(target string) (bucket, key string, ok bool) {
+	location, ok := strings.CutPrefix(target, "s3://")
+	if !ok {
+		return "", "", false
+	}
+	bucket, key, _ = strings.Cut(location, "/")
+	return bucket, key, bucket != ""
+}
+
+func backupState(ctx context.Context) (string, error) {
+	if data.StateBackup == "" {
+		return "", errBackupDisabled
+	}
+
+	body, err := store.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	name := "state-" + time.Now().UTC().Format("20060102T150405Z") + ".json"
+
+	if bucket, prefix, ok := s3Location(data.StateBackup); ok {
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		if _, err := s3Request(ctx, http.MethodPut, s3Object(bucket, prefix+name), "application/json", body); err != nil {
+			return "", fmt.Errorf("s3Request(): %w", err)
+		}
+		pruneS3Backups(ctx, bucket, prefix)
+		return "s3://" + bucket + "/" + prefix + name, nil
+	}
+
+	if err := os.MkdirAll(data.StateBackup, 0o700); err != nil {
+		return "", fmt.Errorf("os.MkdirAll(): %w", err)
+	}
+
+	file := filepath.Join(data.StateBackup, name)
+	if err := os.WriteFile(file, body, 0o600); err != nil {
+		return "", fmt.Errorf("os.WriteFile(): %w", err)
+	}
+	pruneBackups(data.StateBackup)
+
+	return file, nil
+}
+
+func pruneBackups(dir string) {
+	backups, err := filepath.Glob(filepath.Join(dir, "state-*.json"))
+	if err != nil || len(backups) <= backupKeep {
+		return
+	}
+
+	slices.Sort(backups)
+	for _, backup := range backups[:len(backups)-backupKeep] {
+		if err := os.Remove(backup); err != nil {
+			log.Printf("os.Remove(): %v", err)
+		}
+	}
+}
+
+func listS3Backups(ctx context.Context, bucket, prefix string) ([]string, error) {
+	keys := []string{}
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix + "state-"}}
+	for {
+		body, err := s3Request(ctx, http.MethodGet, s3Object(bucket, "")+"?"+awsCanonicalQuery(query), "", nil)
+		if err != nil {
+			return nil, fmt.Errorf("s3Request(): %w", err)
+		}
+
+		var listing struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &listing); err != nil {
+			return nil, fmt.Errorf("xml.Unmarshal(): %w", err)
+		}
+
+		for _, object := range listing.Contents {
+			if strings.HasSuffix(object.Key, ".json") {
+				keys = append(keys, object.Key)
+			}
+		}
+
+		if !listing.IsTruncated || listing.NextContinuationToken == "" {
+			return keys, nil
+		}
+		query.Set("continuation-token", listing.NextContinuationToken)
+	}
+}
+
+func pruneS3Backups(ctx context.Context, bucket, prefix string) {
+	backups, err := listS3Backups(ctx, bucket, prefix)
+	if err != nil {
+		log.Printf("listS3Backups(): %v", err)
+		return
+	}
+	if len(backups) <= backupKeep {
+		return
+	}
+
+	slices.Sort(backups)
+	for _, backup := range backups[:len(backups)-backupKeep] {
+		if _, err := s3Request(ctx, http.MethodDelete, s3Object(bucket, backup), "", nil); err != nil {
+			log.Printf("s3Request(): %v", err)
+		}
+	}
+}
+
+func scheduleBackups(interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		location, err := backupState(ctx)
+		cancel()
+
+		if err != nil {
+			log.Printf("backupState(): %v", err)
+			continue
+		}
+		log.Printf("State backed up to %s", location)
+	}
+}
+
+func restoreState(source, path string) error {
+	var body []byte
+	var err error
+	if bucket, key, ok := s3Location(source); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		body, err = s3Request(ctx, http.MethodGet, s3Object(bucket, key), "", nil)
+	} else {
+		body, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", source, err)
+	}
+
+	if err := json.Unmarshal(body, &State{}); err != nil {
+		return fmt.Errorf("%s is not a state backup: %w", source, err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Rename(path, path+".pre-restore"); err != nil {
+			return fmt.Errorf("os.Rename(): %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		return fmt.Errorf("os.WriteFile(): %w", err)
+	}
+	return nil
+}
+
+func admin(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if data.AdminRole == "" || !slices.Contains(message.Member.Roles, data.AdminRole) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	if len(args) == 0 || adminCommands[strings.ToLower(args[0])] == nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "admin.usage"))
+		return
+	}
+
+	adminCommands[strings.ToLower(args[0])](session, message, args[1:])
+}
+
+func adminBackup(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	location, err := backupState(ctx)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "backup.failed", "error", err.Error()))
+		return
+	}
+
+	log.Printf("State backed up to %s. Username: %s (%s)", location, message.Author.Username, message.Author.ID)
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "backup.created", "location", location))
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func slotKey(environment *Environment, key string) string {
+	return environment.Name + "/" + key
+}
+
+func idleSlot(deployment *Deployment) (active, idle string) {
+	names := slices.Sorted(maps.Keys(deployment.Entry.Slots))
+	store.View(func(state *State) { active = state.Slots[slotKey(deployment.Environment, deployment.Key)] })
+
+	for _, name := range names {
+		if name != active {
+			return active, name
+		}
+	}
+
+	return active, ""
+}
+
+func slotVars(deployment *Deployment, name string) []string {
+	return []string{"${LOCATION}", deployment.Entry.Slots[name].Location, "${SLOT}", name}
+}
+
+func deployBlueGreen(ctx context.Context, deployment *Deployment) ([]byte, string, string, error) {
+	output := &bytes.Buffer{}
+
+	active, idle := idleSlot(deployment)
+	if idle == "" {
+		return nil, "", "", fmt.Errorf("no idle slot configured for %s", deployment.Key)
+	}
+	deployment.Slot = idle
+	fmt.Fprintf(output, "Deploying %s to idle slot %s\n", deployment.Branch, idle)
+
+	out, err := deployment.execute(ctx, "", deployment.Entry.Command, slotVars(deployment, idle)...)
+	output.Write(out)
+	if err != nil {
+		return output.Bytes(), active, idle, fmt.Errorf("deploy %s: %w", idle, err)
+	}
+
+	if err := switchSlot(ctx, deployment, idle, output); err != nil {
+		return output.Bytes(), active, idle, err
+	}
+
+	return output.Bytes(), active, idle, nil
+}
+
+func switchSlot(ctx context.Context, deployment *Deployment, name string, output *bytes.Buffer) error {
+	entry := deployment.Entry
+	if health := entry.Slots[name].Health; health != "" {
+		if err := checkHealth(ctx, health, 10, 3*time.Second); err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "Slot %s is healthy\n", name)
+	}
+
+	if entry.Switch != "" {
+		out, err := deployment.execute(ctx, "", entry.Switch, slotVars(deployment, name)...)
+		output.Write(out)
+		if err != nil {
+			return fmt.Errorf("switch %s: %w", name, err)
+		}
+	}
+
+	if entry.SwitchURL != "" {
+		body, _ := json.Marshal(map[string]string{"slot": name, "location": entry.Slots[name].Location})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, entry.SwitchURL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("http.NewRequestWithContext(): %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("http.Do(): %w", err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("switch %s: load balancer returned %s", name, res.Status)
+		}
+	}
+
+	fmt.Fprintf(output, "Traffic switched to %s\n", name)
+	return store.Update(func(state *State) { state.Slots[slotKey(deployment.Environment, deployment.Key)] = name })
+}
+
+func slotRecord(environment *Environment, key, name string) (record *Record) {
+	store.View(func(state *State) {
+		for _, candidate := range slices.Backward(state.History) {
+			if candidate.Environment == environment.Name && candidate.Key == key && candidate.Slot == name && candidate.Status == "success" {
+				record = candidate
+				return
+			}
+		}
+	})
+	return record
+}
+
+// slotRevision is the ID of the latest deployment to touch the slot, failed
+// ones included, so a rollback button can tell whether the slot changed.
+func slotRevision(environment *Environment, key, name string) (id string) {
+	store.View(func(state *State) {
+		for _, candidate := range slices.Backward(state.History) {
+			if candidate.Environment == environment.Name && candidate.Key == key && candidate.Slot == name {
+				id = candidate.ID
+				return
+			}
+		}
+	})
+	return id
+}
+
+func blueGreenButtons(deployment *Deployment, previous string) []discordgo.MessageComponent {
+	return buttons(discordgo.Button{
+		Label:    text(deployment.Environment.Channel, "bluegreen.rollback", "slot", previous),
+		Style:    discordgo.DangerButton,
+		CustomID: fmt.Sprintf("bluegreen:%s:%s:%s:%s", deployment.Environment.Name, deployment.Key, previous, slotRevision(deployment.Environment, deployment.Key, previous)),
+	})
+}
+
+func blueGreenRollback(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 4 {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "bluegreen.expired"))
+		return
+	}
+
+	environment, key, name, revision := Environments[args[0]], args[1], args[2], args[3]
+	entry, ok := Commands[key]
+	if !ok || environment == nil || entry.Slots[name] == nil {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "bluegreen.invalid_slot", "slot", name))
+		return
+	}
+
+	if slotRevision(environment, key, name) != revision {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "bluegreen.redeployed", "slot", name))
+		return
+	}
+
+	author := interaction.Member.User
+	if current := lockOf(environment); current != nil && current.Author != author.ID {
+		respondEphemeral(session, interaction, current.describe(environment)+" - !unlock")
+		return
+	}
+
+	if err := respondUpdate(session, interaction, text(interaction.ChannelID, "bluegreen.rolling_back", "key", key, "slot", name)); err != nil {
+		return
+	}
+
+	deployment := &Deployment{
+		ID:          newID(),
+		Environment: environment,
+		Key:         key,
+		Entry:       entry,
+		Branch:      environment.Branch,
+		Author:      author,
+		Started:     time.Now(),
+		Slot:        name,
+		Reason:      text(interaction.ChannelID, "bluegreen.reason", "slot", name),
+	}
+	if record := slotRecord(environment, key, name); record != nil {
+		deployment.Branch, deployment.RefType, deployment.SHA = record.Ref, record.RefType, record.SHA
+	} else {
+		log.Printf("No successful deployment of %s recorded for slot %s, assuming %s", key, name, environment.Branch)
+	}
+	if current := lockOf(environment); current != nil {
+		deployment.LockedBy = current.Author
+	}
+	detail := map[string]string{"slot": name}
+
+	go func() {
+		queue, cancelQueue := context.WithCancelCause(context.Background())
+		defer cancelQueue(nil)
+		deployment.cancel = cancelQueue
+
+		err := scheduler.Acquire(queue, deployment, environment.OnConflict == "queue", func(conflict *ConflictError) {
+			session.ChannelMessageEdit(interaction.ChannelID, interaction.Message.ID, text(interaction.ChannelID, "rollback.queued", "location", conflict.Location, "other", conflict.Deployment.ID))
+		})
+		if err != nil {
+			if cause := context.Cause(queue); cause != nil {
+				err = cause
+			}
+			session.ChannelMessageEdit(interaction.ChannelID, interaction.Message.ID, text(interaction.ChannelID, "rollback.rejected", "error", err.Error()))
+			deployment.audit("rollback", "rejected", nil, map[string]string{"slot": name, "error": err.Error()})
+			return
+		}
+		defer scheduler.Release(deployment)
+
+		if slotRevision(environment, key, name) != revision {
+			session.ChannelMessageEdit(interaction.ChannelID, interaction.Message.ID, text(interaction.ChannelID, "bluegreen.redeployed_queued", "slot", name))
+			deployment.audit("rollback", "rejected", nil, map[string]string{"slot": name, "error": "slot redeployed"})
+			return
+		}
+		deployment.audit("rollback", "started", nil, detail)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		output := &bytes.Buffer{}
+		if err := switchSlot(ctx, deployment, name, output); err != nil {
+			recordDeployment(deployment, "failed", err)
+			storeLog(deployment, output.Bytes())
+			deployment.audit("rollback", "failed", nil, map[string]string{"slot": name, "error": err.Error()})
+			session.ChannelMessageEdit(interaction.ChannelID, interaction.Message.ID, text(interaction.ChannelID, "rollback.failed", "error", err.Error()))
+			log.Printf("switchSlot(): %v\n%s", err, output.String())
+			return
+		}
+
+		recordDeployment(deployment, "success", nil)
+		storeLog(deployment, output.Bytes())
+		deployment.audit("rollback", "success", nil, detail)
+		session.ChannelMessageEdit(interaction.ChannelID, interaction.Message.ID, text(interaction.ChannelID, "bluegreen.switched_back", "slot", name, "user", author.ID))
+		log.Printf("Rollback successful. Username: %s (%s) - Key: %s - Slot: %s - Branch: %s", author.Username, author.ID, key, name, deployment.Branch)
+	}()
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestSlotRecord(t *testing.T) {
+	testStore(t)
+	environment := &Environment{Name: "production", Branch: "main"}
+	store.Update(func(state *State) {
+		state.History = []*Record{
+			{ID: "1", Environment: "production", Key: "api", Slot: "blue", Ref: "v1.0.0", Status: "success"},
+			{ID: "2", Environment: "production", Key: "api", Slot: "green", Ref: "v1.1.0", Status: "success"},
+			{ID: "3", Environment: "production", Key: "api", Slot: "blue", Ref: "v1.2.0", Status: "failed"},
+			{ID: "4", Environment: "staging", Key: "api", Slot: "blue", Ref: "v1.3.0", Status: "success"},
+			{ID: "5", Environment: "production", Key: "web", Slot: "blue", Ref: "v2.0.0", Status: "success"},
+		}
+	})
+
+	tests := []struct {
+		key  string
+		slot string
+		want string
+	}{
+		{"api", "blue", "1"},
+		{"api", "green", "2"},
+		{"web", "blue", "5"},
+		{"web", "green", ""},
+	}
+
+	for _, test := range tests {
+		got := ""
+		if record := slotRecord(environment, test.key, test.slot); record != nil {
+			got = record.ID
+		}
+		if got != test.want {
+			t.Errorf("slotRecord(%s, %s) = %q, want %q", test.key, test.slot, got, test.want)
+		}
+	}
+}
+
+func TestSlotRevision(t *testing.T) {
+	testStore(t)
+	environment := &Environment{Name: "production"}
+	store.Update(func(state *State) {
+		state.History = []*Record{
+			{ID: "1", Environment: "production", Key: "api", Slot: "blue", Status: "success"},
+			{ID: "2", Environment: "production", Key: "api", Slot: "green", Status: "success"},
+			{ID: "3", Environment: "production", Key: "api", Slot: "blue", Status: "failed"},
+		}
+	})
+
+	// A failed deploy still overwrote the slot, so it counts as a new revision.
+	if got := slotRevision(environment, "api", "blue"); got != "3" {
+		t.Errorf("slotRevision(api, blue) = %q, want %q", got, "3")
+	}
+	if got := slotRevision(environment, "api", "green"); got != "2" {
+		t.Errorf("slotRevision(api, green) = %q, want %q", got, "2")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	errMissingRef        = errors.New("ref not found on remote")
+	defaultBranchPattern = regexp.MustCompile(`^[a-zA-Z0-9_./-]+$`)
+)
+
+const (
+	defaultBranchLength = 100
+	shellUnsafe         = "`$;|&<>(){}[]*?!#~^:\\'\" \t\r\n"
+)
+
+type BranchRules struct {
+	Pattern   string   `json:"pattern"`
+	MaxLength int      `json:"max_length"`
+	Forbidden []string `json:"forbidden"`
+
+	pattern *regexp.Regexp
+}
+
+func (rules *BranchRules) compile() (err error) {
+	if rules.Pattern != "" {
+		rules.pattern, err = regexp.Compile(rules.Pattern)
+	}
+	return err
+}
+
+func validBranch(environment *Environment, branch string) bool {
+	rules := environment.BranchRules
+	if rules == nil {
+		rules = &BranchRules{}
+	}
+
+	pattern, limit := defaultBranchPattern, defaultBranchLength
+	if rules.pattern != nil {
+		pattern = rules.pattern
+	}
+	if rules.MaxLength > 0 {
+		limit = rules.MaxLength
+	}
+
+	if branch == "" || len(branch) > limit || strings.HasPrefix(branch, "-") || strings.Contains(branch, "..") || strings.ContainsAny(branch, shellUnsafe) || !pattern.MatchString(branch) {
+		return false
+	}
+
+	for _, forbidden := range rules.Forbidden {
+		if matched, err := path.Match(forbidden, branch); err == nil && matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func branchAllowed(environment *Environment, branch string) bool {
+	if len(environment.Branches) == 0 {
+		return branch == environment.Branch
+	}
+
+	for _, pattern := range environment.Branches {
+		if matched, err := path.Match(pattern, branch); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lsRemote(ctx context.Context, deployment *Deployment, env []string, args ...string) ([]byte, error) {
+	remote := []string{"-C", deployment.Environment.Location, "ls-remote", "--exit-code"}
+	if deployment.Entry.Repository != "" {
+		remote = []string{"ls-remote", "--exit-code"}
+		args = append([]string{deployment.Entry.Repository}, args...)
+	} else {
+		args = append([]string{"origin"}, args...)
+	}
+
+	output := &bytes.Buffer{}
+	if err := gitEnv(ctx, env, output, append(remote, args...)...); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) && exit.ExitCode() == 2 {
+			return nil, errMissingRef
+		}
+		return nil, err
+	}
+
+	return output.Bytes(), nil
+}
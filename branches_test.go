@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidBranch(t *testing.T) {
+	rules := &BranchRules{Pattern: `^(main|release/[0-9.]+|feature/[a-z-]+)$`, MaxLength: 20, Forbidden: []string{"release/0.*"}}
+	if err := rules.compile(); err != nil {
+		t.Fatalf("compile(): %v", err)
+	}
+
+	loose := &BranchRules{Pattern: `.*`}
+	if err := loose.compile(); err != nil {
+		t.Fatalf("compile(): %v", err)
+	}
+
+	defaults, custom, permissive := &Environment{}, &Environment{BranchRules: rules}, &Environment{BranchRules: loose}
+
+	tests := []struct {
+		name        string
+		environment *Environment
+		branch      string
+		want        bool
+	}{
+		{"Simple", defaults, "main", true},
+		{"Nested", defaults, "feature/login-v2", true},
+		{"Tag", defaults, "v1.2.3", true},
+		{"SHA", defaults, "3f2a9c1", true},
+		{"MixedCase", defaults, "Feature/Login", true},
+		{"Empty", defaults, "", false},
+		{"Option", defaults, "--upload-pack=evil", false},
+		{"DotDot", defaults, "main..evil", false},
+		{"Space", defaults, "main evil", false},
+		{"Shell", defaults, "main;rm", false},
+		{"Subst", defaults, "$(id)", false},
+		{"AtLimit", defaults, strings.Repeat("a", defaultBranchLength), true},
+		{"TooLong", defaults, strings.Repeat("a", defaultBranchLength+1), false},
+		{"CustomPattern", custom, "release/1.4", true},
+		{"CustomMismatch", custom, "hotfix/1.4", false},
+		{"CustomLength", custom, "feature/abcdefghijklm", false},
+		{"Forbidden", custom, "release/0.9", false},
+		{"LoosePattern", permissive, "feature/ü+1@2", true},
+		{"LooseSemicolon", permissive, "main;id", false},
+		{"LooseSubst", permissive, "$(id)", false},
+		{"LooseBacktick", permissive, "`id`", false},
+		{"LoosePipe", permissive, "main|sh", false},
+		{"LooseAnd", permissive, "main&&id", false},
+		{"LooseRedirect", permissive, "main>out", false},
+		{"LooseBraces", permissive, "{a,b}", false},
+		{"LooseQuote", permissive, "main'", false},
+		{"LooseNewline", permissive, "main\nid", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := validBranch(test.environment, test.branch); got != test.want {
+				t.Errorf("validBranch(%q) = %v, want %v", test.branch, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var errAborted = errors.New("deployment aborted")
+
+func canaryWindow(entry *Entry) time.Duration {
+	if soak, err := time.ParseDuration(entry.Soak); err == nil && soak > 0 {
+		return soak
+	}
+
+	return 30 * time.Minute
+}
+
+func deployTargets(ctx context.Context, deployment *Deployment, command string, targets []string, output *bytes.Buffer) error {
+	for _, target := range targets {
+		fmt.Fprintf(output, "==> %s\n", target)
+		if deployment.Entry.Balancer != nil {
+			if err := deployment.deployBehindBalancer(ctx, command, target, output); err != nil {
+				return fmt.Errorf("%s: %w", target, err)
+			}
+			continue
+		}
+
+		out, err := deployment.execute(ctx, "", command, "${TARGET}", target)
+		output.Write(out)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+	}
+
+	return nil
+}
+
+func healthyTargets(ctx context.Context, deployment *Deployment, targets []string) error {
+	if deployment.Entry.Health == "" {
+		return nil
+	}
+
+	for _, target := range targets {
+		if err := checkHealth(ctx, deployment.expand(deployment.Entry.Health, "${TARGET}", target), 5, 3*time.Second); err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+	}
+
+	return nil
+}
+
+func deployCanary(ctx context.Context, deployment *Deployment, prompt func(string, []discordgo.MessageComponent)) ([]byte, error) {
+	output := &bytes.Buffer{}
+	entry := deployment.Entry
+
+	size := max(entry.Canary, 1)
+	if size >= len(entry.Targets) {
+		return nil, fmt.Errorf("canary size %d leaves no remaining targets", size)
+	}
+	canary, rest := entry.Targets[:size], entry.Targets[size:]
+
+	abort := func(reason error) ([]byte, error) {
+		if entry.Rollback != "" {
+			rollbackCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			defer cancel()
+
+			if err := deployTargets(rollbackCtx, deployment, entry.Rollback, canary, output); err != nil {
+				return output.Bytes(), fmt.Errorf("%w; rollback: %w", reason, err)
+			}
+		}
+		return output.Bytes(), reason
+	}
+
+	if err := deployTargets(ctx, deployment, entry.Command, canary, output); err != nil {
+		return abort(err)
+	}
+
+	if err := healthyTargets(ctx, deployment, canary); err != nil {
+		return abort(err)
+	}
+
+	id := newID()
+	decision, done := awaitDecision(id)
+	defer done()
+
+	channelID := deployment.Environment.Channel
+	content := deployment.text(channelID, "canary.prompt", "targets", strings.Join(canary, ", "), "remaining", len(rest))
+	var soak <-chan time.Time
+	if entry.Soak != "" {
+		content += "\n" + deployment.text(channelID, "canary.soak", "window", canaryWindow(entry))
+		soak = time.After(canaryWindow(entry))
+	}
+
+	prompt(content, decisionButtons(id,
+		discordgo.Button{Label: text(channelID, "canary.promote"), Style: discordgo.SuccessButton, CustomID: "promote"},
+		discordgo.Button{Label: text(channelID, "canary.abort"), Style: discordgo.DangerButton, CustomID: "abort"},
+	))
+
+	select {
+	case choice := <-decision:
+		if choice.Choice != "promote" {
+			deployment.audit("approval", "denied", choice.User, map[string]string{"gate": "canary"})
+			prompt(deployment.text(channelID, "canary.aborted", "user", choice.User.ID), nil)
+			return abort(fmt.Errorf("%w by %s", errAborted, choice.User.Username))
+		}
+		deployment.audit("approval", "approved", choice.User, map[string]string{"gate": "canary"})
+		prompt(deployment.text(channelID, "canary.promoted", "user", choice.User.ID), nil)
+	case <-soak:
+		if err := healthyTargets(ctx, deployment, canary); err != nil {
+			prompt(deployment.text(channelID, "canary.unhealthy"), nil)
+			return abort(err)
+		}
+		prompt(deployment.text(channelID, "canary.healthy"), nil)
+	case <-ctx.Done():
+		return abort(fmt.Errorf("%w: %w", errAborted, ctx.Err()))
+	}
+
+	if err := deployTargets(ctx, deployment, entry.Command, rest, output); err != nil {
+		return output.Bytes(), err
+	}
+
+	return output.Bytes(), healthyTargets(ctx, deployment, rest)
+}
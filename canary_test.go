@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func TestDeployCanaryRollbackAfterCancel(t *testing.T) {
+	data = &Config{}
+	testStore(t)
+	dir := t.TempDir()
+	canary, rest := filepath.Join(dir, "canary"), filepath.Join(dir, "rest")
+
+	deployment := &Deployment{
+		Environment: &Environment{Name: "production", Location: dir},
+		Entry:       &Entry{Command: "true", Rollback: "touch ${TARGET}", Targets: []string{canary, rest}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := deployCanary(ctx, deployment, func(string, []discordgo.MessageComponent) {})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("deployCanary() error = %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := os.Stat(canary); err != nil {
+		t.Errorf("rollback did not run on the canary target: %v", err)
+	}
+	if _, err := os.Stat(rest); err == nil {
+		t.Errorf("rollback ran on a target outside the canary")
+	}
+}
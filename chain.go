@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const chainApprovalWindow = 24 * time.Hour
+
+type Chain struct {
+	Environment string   `json:"environment"`
+	Mode        string   `json:"mode"`
+	Keys        []string `json:"keys"`
+
+	next *Environment
+}
+
+func (chain *Chain) compile(environment *Environment, environments map[string]*Environment) error {
+	if chain.next = environments[chain.Environment]; chain.next == nil || chain.next == environment {
+		return fmt.Errorf("next stage %q is not another environment", chain.Environment)
+	}
+
+	switch chain.Mode {
+	case "":
+		chain.Mode = "approve"
+	case "auto", "approve":
+	default:
+		return fmt.Errorf("unknown next stage mode %q, expected auto or approve", chain.Mode)
+	}
+
+	return nil
+}
+
+func chainCycle(environments map[string]*Environment) []string {
+	for name, environment := range environments {
+		stages := []string{name}
+		for next := environment.Next; next != nil; next = next.next.Next {
+			if slices.Contains(stages, next.Environment) {
+				return append(stages, next.Environment)
+			}
+			stages = append(stages, next.Environment)
+		}
+	}
+
+	return nil
+}
+
+func (deployment *Deployment) advance(session *discordgo.Session) {
+	chain := deployment.Environment.Next
+	if chain == nil || deployment.PullRequest != 0 || deployment.SHA == "" || (len(chain.Keys) > 0 && !slices.Contains(chain.Keys, deployment.Key)) {
+		return
+	}
+
+	source := &Record{ID: deployment.ID, Key: deployment.Key, Ref: deployment.Branch, SHA: deployment.SHA}
+	args := strings.Fields(deployment.Reason)
+
+	if chain.Mode == "auto" {
+		deployment.promoteNext(session, deployment.Environment.Channel, source, deployment.Author, args)
+		return
+	}
+
+	channelID := chain.next.Channel
+	id := newID()
+	decision, done := awaitDecision(id)
+	msg, err := session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: deployment.text(channelID, "chain.proposed", "to", chain.next.Name, "sha", fmt.Sprintf("%.7s", deployment.SHA)),
+		Components: decisionButtons(id,
+			discordgo.Button{Label: text(channelID, "chain.approve"), Style: discordgo.SuccessButton, CustomID: "approve"},
+			discordgo.Button{Label: text(channelID, "chain.skip"), Style: discordgo.SecondaryButton, CustomID: "skip"},
+		),
+	})
+	if err != nil {
+		done()
+		log.Printf("session.ChannelMessageSendComplex(): %v", err)
+		return
+	}
+
+	go func() {
+		defer done()
+
+		content := deployment.text(channelID, "chain.expired", "to", chain.next.Name)
+		var approver *discordgo.User
+		select {
+		case choice := <-decision:
+			if choice.Choice == "approve" {
+				content, approver = deployment.text(channelID, "chain.approved", "to", chain.next.Name, "user", choice.User.ID), choice.User
+			} else {
+				content = deployment.text(channelID, "chain.skipped", "to", chain.next.Name, "user", choice.User.ID)
+			}
+		case <-time.After(chainApprovalWindow):
+		}
+
+		components := []discordgo.MessageComponent{}
+		session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+
+		if approver != nil {
+			deployment.promoteNext(session, channelID, source, approver, args)
+		}
+	}()
+}
+
+func (deployment *Deployment) promoteNext(session *discordgo.Session, channelID string, source *Record, author *discordgo.User, args []string) {
+	to := deployment.Environment.Next.next
+	next, code, err := promotion(channelID, deployment.Environment, to, source, author, args)
+	if err != nil {
+		session.ChannelMessageSend(channelID, deployment.text(channelID, "chain.failed", "to", to.Name, "error", err.Error()))
+		return
+	}
+	next.wait = true
+
+	session.ChannelMessageSend(channelID, next.text(channelID, "promote.started", "from", deployment.Environment.Name, "sha", fmt.Sprintf("%.7s", source.SHA), "source", source.Ref, "channel", to.Channel))
+	log.Printf("Promoting %s@%.7s from %s to %s as the next stage. Username: %s (%s)", source.Key, source.SHA, deployment.Environment.Name, to.Name, author.Username, author.ID)
+	launchPromotion(session, next, code)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"deploy/engine"
+)
+
+const defaultStepTimeout = 30 * time.Minute
+
+var ciProviders = map[string]func(ctx context.Context, with map[string]string, output *bytes.Buffer) error{
+	"github":  runGithubWorkflow,
+	"jenkins": runJenkinsJob,
+}
+
+func stepTimeout(step *Step) time.Duration {
+	if timeout, err := time.ParseDuration(step.With["timeout"]); err == nil {
+		return timeout
+	}
+	return defaultStepTimeout
+}
+
+func pollInterval(with map[string]string) time.Duration {
+	if interval, err := time.ParseDuration(with["interval"]); err == nil && interval > 0 {
+		return interval
+	}
+	return 10 * time.Second
+}
+
+func ciPrefixed(with map[string]string, prefix string) map[string]string {
+	values := map[string]string{}
+	for key, value := range with {
+		if name, ok := strings.CutPrefix(key, prefix); ok {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+func runCI(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+	with := map[string]string{}
+	for key, value := range step.With {
+		with[key] = request.Expand(value)
+	}
+	if with["ref"] == "" {
+		with["ref"] = request.Ref
+	}
+
+	run, ok := ciProviders[with["provider"]]
+	if !ok {
+		return fmt.Errorf("unknown ci provider %q", with["provider"])
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout(step))
+	defer cancel()
+
+	return run(ctx, with, output)
+}
+
+func apiRequest(ctx context.Context, method, target string, body any, authorize func(*http.Request), result any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal(): %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	authorize(req)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http.Do(): %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, target, res.Status, strings.TrimSpace(string(message)))
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+			return nil, fmt.Errorf("json.Decode(): %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+func poll(ctx context.Context, interval time.Duration, check func() (bool, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if done, err := check(); done || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func runGithubWorkflow(ctx context.Context, with map[string]string, output *bytes.Buffer) error {
+	repository, workflow, ref := with["repository"], with["workflow"], with["ref"]
+	if repository == "" || workflow == "" || ref == "" {
+		return fmt.Errorf("github ci steps need repository, workflow and ref")
+	}
+
+	authorize := func(req *http.Request) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+		req.Header.Set("Authorization", "Bearer "+data.GithubToken)
+	}
+
+	base := fmt.Sprintf("https://api.github.com/repos/%s/actions/workflows/%s", repository, url.PathEscape(workflow))
+	dispatched := time.Now().Add(-5 * time.Second).UTC()
+	if _, err := apiRequest(ctx, http.MethodPost, base+"/dispatches", map[string]any{"ref": ref, "inputs": ciPrefixed(with, "input.")}, authorize, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(output, "Dispatched GitHub Actions workflow %s on %s@%s\n", workflow, repository, ref)
+
+	type workflowRun struct {
+		ID         int64     `json:"id"`
+		Status     string    `json:"status"`
+		Conclusion string    `json:"conclusion"`
+		HTMLURL    string    `json:"html_url"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	run := &workflowRun{}
+	query := url.Values{"event": {"workflow_dispatch"}, "branch": {ref}, "created": {">=" + dispatched.Format(time.RFC3339)}}
+	if err := poll(ctx, pollInterval(with), func() (bool, error) {
+		runs := struct {
+			WorkflowRuns []*workflowRun `json:"workflow_runs"`
+		}{}
+		if _, err := apiRequest(ctx, http.MethodGet, base+"/runs?"+query.Encode(), nil, authorize, &runs); err != nil {
+			return false, err
+		}
+		if len(runs.WorkflowRuns) == 0 {
+			return false, nil
+		}
+		run = runs.WorkflowRuns[0]
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("waiting for workflow run: %w", err)
+	}
+	fmt.Fprintf(output, "Workflow run %s\n", run.HTMLURL)
+
+	target := fmt.Sprintf("https://api.github.com/repos/%s/actions/runs/%d", repository, run.ID)
+	if err := poll(ctx, pollInterval(with), func() (bool, error) {
+		_, err := apiRequest(ctx, http.MethodGet, target, nil, authorize, run)
+		return run.Status == "completed", err
+	}); err != nil {
+		return fmt.Errorf("waiting for workflow run %d: %w", run.ID, err)
+	}
+
+	fmt.Fprintf(output, "Workflow run finished: %s\n", run.Conclusion)
+	if run.Conclusion != "success" {
+		return fmt.Errorf("workflow run %d finished with %s: %s", run.ID, run.Conclusion, run.HTMLURL)
+	}
+
+	return nil
+}
+
+func runJenkinsJob(ctx context.Context, with map[string]string, output *bytes.Buffer) error {
+	job := strings.TrimSuffix(with["url"], "/")
+	if job == "" {
+		return fmt.Errorf("jenkins ci steps need a job url")
+	}
+
+	username, token := with["user"], secret(with["token"], os.Getenv(with["token"]))
+	authorize := func(req *http.Request) {
+		if username != "" {
+			req.SetBasicAuth(username, token)
+		}
+	}
+
+	trigger := job + "/build"
+	if parameters := ciPrefixed(with, "param."); len(parameters) > 0 {
+		values := url.Values{}
+		for name, value := range parameters {
+			values.Set(name, value)
+		}
+		trigger = job + "/buildWithParameters?" + values.Encode()
+	}
+
+	res, err := apiRequest(ctx, http.MethodPost, trigger, nil, authorize, nil)
+	if err != nil {
+		return err
+	}
+
+	queue := res.Header.Get("Location")
+	if queue == "" {
+		return fmt.Errorf("jenkins did not return a queue item for %s", job)
+	}
+	fmt.Fprintf(output, "Queued Jenkins job %s\n", job)
+
+	build := struct {
+		Number   int    `json:"number"`
+		URL      string `json:"url"`
+		Building bool   `json:"building"`
+		Result   string `json:"result"`
+	}{}
+	if err := poll(ctx, pollInterval(with), func() (bool, error) {
+		item := struct {
+			Cancelled  bool `json:"cancelled"`
+			Executable *struct {
+				Number int    `json:"number"`
+				URL    string `json:"url"`
+			} `json:"executable"`
+		}{}
+		if _, err := apiRequest(ctx, http.MethodGet, strings.TrimSuffix(queue, "/")+"/api/json", nil, authorize, &item); err != nil {
+			return false, err
+		}
+		if item.Cancelled {
+			return false, fmt.Errorf("jenkins queue item for %s was cancelled", job)
+		}
+		if item.Executable == nil {
+			return false, nil
+		}
+		build.Number, build.URL = item.Executable.Number, item.Executable.URL
+		return true, nil
+	}); err != nil {
+		return fmt.Errorf("waiting for jenkins build: %w", err)
+	}
+	fmt.Fprintf(output, "Jenkins build #%d %s\n", build.Number, build.URL)
+
+	if err := poll(ctx, pollInterval(with), func() (bool, error) {
+		_, err := apiRequest(ctx, http.MethodGet, strings.TrimSuffix(build.URL, "/")+"/api/json", nil, authorize, &build)
+		return !build.Building && build.Result != "", err
+	}); err != nil {
+		return fmt.Errorf("waiting for jenkins build #%d: %w", build.Number, err)
+	}
+
+	fmt.Fprintf(output, "Jenkins build #%d finished: %s\n", build.Number, build.Result)
+	if build.Result != "SUCCESS" {
+		return fmt.Errorf("jenkins build #%d finished with %s: %s", build.Number, build.Result, build.URL)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func compare(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 2 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.usage"))
+		return
+	}
+
+	source, target := environmentByName(args[0]), environmentByName(args[1])
+	for i, environment := range []*Environment{source, target} {
+		if environment == nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.invalid_environment", "environment", args[i]))
+			return
+		}
+	}
+
+	from, to := deployedSHA(source), deployedSHA(target)
+	for _, environment := range []*Environment{source, target} {
+		if deployedSHA(environment) == "" {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.no_revision", "environment", environment.Name))
+			return
+		}
+	}
+
+	if from == to {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.same", "source", source.Name, "target", target.Name, "sha", fmt.Sprintf("%.7s", from)))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env, cleanup, err := gitCredentials(source)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.failed", "error", err.Error()))
+		return
+	}
+	defer cleanup()
+
+	dir, _ := environmentRepo(source)
+	if err := gitEnv(ctx, env, &bytes.Buffer{}, "-C", dir, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.failed", "error", err.Error()))
+		return
+	}
+
+	for _, sha := range []string{from, to} {
+		if _, err := gitLines(ctx, dir, 1, "cat-file", "-e", sha+"^{commit}"); err != nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.missing_commit", "sha", fmt.Sprintf("%.7s", sha), "environment", source.Name))
+			return
+		}
+	}
+
+	pending, err := gitLines(ctx, dir, 20, "log", "--no-merges", "--format=%h %an: %s", to+".."+from)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.failed", "error", err.Error()))
+		return
+	}
+
+	ahead, err := gitLines(ctx, dir, 20, "log", "--no-merges", "--format=%h %an: %s", from+".."+to)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.failed", "error", err.Error()))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "compare.summary", "source", source.Name, "from", fmt.Sprintf("%.7s", from), "target", target.Name, "to", fmt.Sprintf("%.7s", to), "pending", codeBlock(pending), "ahead", codeBlock(ahead)))
+}
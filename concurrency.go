@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var concurrencyGroups = map[string]int{}
+
+func parseConcurrencyGroups(value string) (map[string]int, error) {
+	groups := map[string]int{}
+	for pair := range strings.SplitSeq(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		name, limit, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if !ok || err != nil || n < 1 || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid concurrency group %q, expected name=limit", pair)
+		}
+		groups[strings.TrimSpace(name)] = n
+	}
+
+	return groups, nil
+}
+
+func (scheduler *Scheduler) groupBlocker(deployment *Deployment) *ConflictError {
+	group := deployment.Environment.Group
+	limit, ok := concurrencyGroups[group]
+	if !ok {
+		return nil
+	}
+
+	busy := []*Deployment{}
+	for _, running := range scheduler.running {
+		if running.Environment.Group == group && !slices.Contains(busy, running) {
+			busy = append(busy, running)
+		}
+	}
+
+	for _, queued := range scheduler.queue {
+		if queued == deployment {
+			break
+		}
+
+		if queued.Environment.Group == group {
+			busy = append(busy, queued)
+		}
+	}
+
+	if len(busy) < limit {
+		return nil
+	}
+	return &ConflictError{Location: "group:" + group, Deployment: busy[0]}
+}
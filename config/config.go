@@ -0,0 +1,28 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+func Load(target any, lookup func(name string) (string, bool)) error {
+	val := reflect.ValueOf(target).Elem()
+
+	for i := range val.NumField() {
+		field := val.Type().Field(i)
+		name := field.Tag.Get("env")
+		value, input := lookup(name)
+
+		if !input || strings.TrimSpace(value) == "" {
+			value = field.Tag.Get("default")
+		}
+
+		if strings.TrimSpace(value) == "" && field.Tag.Get("optional") != "true" {
+			return fmt.Errorf("missing environment variable: %s", name)
+		}
+		val.Field(i).SetString(value)
+	}
+
+	return nil
+}
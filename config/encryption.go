@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+const EncryptedFile = ".env.enc"
+
+var magic = []byte("DEPLOYENC1")
+
+func ParseKey(value string) ([]byte, error) {
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	return nil, errors.New("CONFIG_KEY must be 32 bytes encoded as base64 or hex")
+}
+
+func GenerateKey() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher(): %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM(): %w", err)
+	}
+
+	return gcm, nil
+}
+
+func Encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("rand.Read(): %w", err)
+	}
+
+	out := append(append([]byte{}, magic...), nonce...)
+	return gcm.Seal(out, nonce, plaintext, magic), nil
+}
+
+func Decrypt(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, ok := bytes.CutPrefix(ciphertext, magic)
+	if !ok || len(body) < gcm.NonceSize() {
+		return nil, errors.New("not an encrypted config file")
+	}
+
+	plaintext, err := gcm.Open(nil, body[:gcm.NonceSize()], body[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, fmt.Errorf("gcm.Open(): %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func EncryptFile(path string) error {
+	key, err := ParseKey(os.Getenv("CONFIG_KEY"))
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	ciphertext, err := Encrypt(key, plaintext)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(EncryptedFile, ciphertext, 0o600); err != nil {
+		return fmt.Errorf("os.WriteFile(): %w", err)
+	}
+
+	return nil
+}
+
+func LoadEnvFile() error {
+	value, ok := os.LookupEnv("CONFIG_KEY")
+	if _, err := os.Stat(EncryptedFile); !ok || err != nil {
+		if err := godotenv.Load(".env"); err != nil {
+			return fmt.Errorf("godotenv.Load(): %w", err)
+		}
+		return nil
+	}
+
+	key, err := ParseKey(value)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := os.ReadFile(EncryptedFile)
+	if err != nil {
+		return fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	plaintext, err := Decrypt(key, ciphertext)
+	if err != nil {
+		return err
+	}
+
+	values, err := godotenv.UnmarshalBytes(plaintext)
+	if err != nil {
+		return fmt.Errorf("godotenv.UnmarshalBytes(): %w", err)
+	}
+
+	for name, value := range values {
+		if _, exists := os.LookupEnv(name); !exists {
+			os.Setenv(name, value)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var messageRefPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`/commit/([0-9a-f]{7,40})\b`),
+	regexp.MustCompile(`/tree/([A-Za-z0-9_./-]+)`),
+	regexp.MustCompile(`\[[^\]:]+:([A-Za-z0-9_./-]+)\]`),
+	regexp.MustCompile("`([A-Za-z0-9_./-]+)`"),
+	regexp.MustCompile(`\b([0-9a-f]{7,40})\b`),
+}
+
+func messageText(message *discordgo.Message) []string {
+	texts := []string{message.Content}
+	for _, embed := range message.Embeds {
+		texts = append(texts, embed.Title, embed.URL, embed.Description)
+		for _, field := range embed.Fields {
+			texts = append(texts, field.Name, field.Value)
+		}
+	}
+	return texts
+}
+
+func messageRef(environment *Environment, message *discordgo.Message) string {
+	texts := messageText(message)
+	for _, pattern := range messageRefPatterns {
+		for _, text := range texts {
+			for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+				ref := strings.TrimSuffix(match[1], ".")
+				if commitPattern.MatchString(ref) && refAllowed(environment, "commit") || validBranch(environment, ref) && branchAllowed(environment, ref) {
+					return ref
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func deployThis(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	command := interaction.ApplicationCommandData()
+	message := command.Resolved.Messages[command.TargetID]
+	if message == nil {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "contextmenu.unreadable"))
+		return
+	}
+
+	ref := messageRef(environmentByChannel(interaction.ChannelID), message)
+	if ref == "" {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "contextmenu.no_ref"))
+		return
+	}
+
+	openPicker(session, interaction, ref)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const (
+	dashboardCookie  = "deploy_session"
+	dashboardSession = 12 * time.Hour
+	loginCookie      = "deploy_login"
+	loginWindow      = 10 * time.Minute
+	discordOAuthURL  = "https://discord.com/oauth2/authorize"
+	discordTokenURL  = "https://discord.com/api/oauth2/token"
+	discordUserURL   = "https://discord.com/api/users/@me"
+)
+
+//go:embed dashboard/*.html
+var dashboardFiles embed.FS
+
+var dashboardTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"elapsed": elapsed,
+	"short":   func(sha string) string { return sha[:min(len(sha), 7)] },
+	"time":    func(t time.Time) string { return t.Local().Format("2006-01-02 15:04") },
+}).ParseFS(dashboardFiles, "dashboard/*.html"))
+
+type Dashboard struct {
+	session *discordgo.Session
+
+	mu       sync.Mutex
+	users    map[string]*dashboardUser
+	attempts map[string]time.Time
+}
+
+type dashboardUser struct {
+	User    *discordgo.User
+	Expires time.Time
+}
+
+func (dashboard *Dashboard) redirectURL() string {
+	return strings.TrimSuffix(data.DashboardURL, "/") + "/callback"
+}
+
+func (dashboard *Dashboard) user(r *http.Request) *discordgo.User {
+	cookie, err := r.Cookie(dashboardCookie)
+	if err != nil {
+		return nil
+	}
+
+	dashboard.mu.Lock()
+	defer dashboard.mu.Unlock()
+
+	current, ok := dashboard.users[cookie.Value]
+	if !ok || time.Now().After(current.Expires) {
+		delete(dashboard.users, cookie.Value)
+		return nil
+	}
+	return current.User
+}
+
+func (dashboard *Dashboard) environments(user *discordgo.User) []*Environment {
+	members := map[string]*discordgo.Member{}
+	allowed := []*Environment{}
+	for _, environment := range Environments {
+		channel, err := dashboard.session.State.Channel(environment.Channel)
+		if err != nil {
+			if channel, err = dashboard.session.Channel(environment.Channel); err != nil {
+				continue
+			}
+		}
+
+		if !guildAllowed(channel.GuildID) {
+			continue
+		}
+
+		member, ok := members[channel.GuildID]
+		if !ok {
+			member, _ = guildMember(dashboard.session, channel.GuildID, user.ID)
+			members[channel.GuildID] = member
+		}
+
+		if member != nil && slices.Contains(member.Roles, environment.Role) {
+			allowed = append(allowed, environment)
+		}
+	}
+
+	slices.SortFunc(allowed, func(a, b *Environment) int { return strings.Compare(a.Name, b.Name) })
+	return allowed
+}
+
+func (dashboard *Dashboard) authorized(next func(http.ResponseWriter, *http.Request, *discordgo.User, []*Environment)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := dashboard.user(r)
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+
+		environments := dashboard.environments(user)
+		if len(environments) == 0 {
+			http.Error(w, "you do not have a role for any environment", http.StatusForbidden)
+			return
+		}
+
+		next(w, r, user, environments)
+	}
+}
+
+func (dashboard *Dashboard) login(w http.ResponseWriter, r *http.Request) {
+	state := newID()
+
+	dashboard.mu.Lock()
+	for attempt, expires := range dashboard.attempts {
+		if time.Now().After(expires) {
+			delete(dashboard.attempts, attempt)
+		}
+	}
+	dashboard.attempts[state] = time.Now().Add(loginWindow)
+	dashboard.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    state,
+		Path:     "/callback",
+		MaxAge:   int(loginWindow.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(data.DashboardURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := url.Values{
+		"client_id":     {data.DiscordClientID},
+		"redirect_uri":  {dashboard.redirectURL()},
+		"response_type": {"code"},
+		"scope":         {"identify"},
+		"state":         {state},
+	}
+	http.Redirect(w, r, discordOAuthURL+"?"+query.Encode(), http.StatusFound)
+}
+
+func (dashboard *Dashboard) exchange(ctx context.Context, code string) (*discordgo.User, error) {
+	form := url.Values{
+		"client_id":     {data.DiscordClientID},
+		"client_secret": {secret("DISCORD_CLIENT_SECRET", data.DiscordClientSecret)},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {dashboard.redirectURL()},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discordTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http.Do(): %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("token exchange returned %s", res.Status)
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("json.Decode(): %w", err)
+	}
+
+	user := &discordgo.User{}
+	if _, err := apiRequest(ctx, http.MethodGet, discordUserURL, nil, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	}, user); err != nil {
+		return nil, fmt.Errorf("users/@me: %w", err)
+	}
+
+	return user, nil
+}
+
+func (dashboard *Dashboard) callback(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Path: "/callback", MaxAge: -1})
+
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		http.Error(w, "login was not started from this browser, try again", http.StatusBadRequest)
+		return
+	}
+
+	dashboard.mu.Lock()
+	expires, ok := dashboard.attempts[state]
+	delete(dashboard.attempts, state)
+	dashboard.mu.Unlock()
+
+	if !ok || time.Now().After(expires) {
+		http.Error(w, "login expired, try again", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	user, err := dashboard.exchange(ctx, r.URL.Query().Get("code"))
+	if err != nil {
+		log.Printf("dashboard.exchange(): %v", err)
+		http.Error(w, "could not log in with Discord", http.StatusBadGateway)
+		return
+	}
+
+	id := newID() + newID() + newID()
+	dashboard.mu.Lock()
+	dashboard.users[id] = &dashboardUser{User: user, Expires: time.Now().Add(dashboardSession)}
+	dashboard.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     dashboardCookie,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(dashboardSession.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(data.DashboardURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (dashboard *Dashboard) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(dashboardCookie); err == nil {
+		dashboard.mu.Lock()
+		delete(dashboard.users, cookie.Value)
+		dashboard.mu.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: dashboardCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
+
+func allowedEnvironment(environments []*Environment, name string) bool {
+	return slices.ContainsFunc(environments, func(environment *Environment) bool {
+		return environment.Name == name || environment.Preview != nil && strings.HasPrefix(name, environment.Name+"-pr-")
+	})
+}
+
+func render(w http.ResponseWriter, name string, page map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplates.ExecuteTemplate(w, name, page); err != nil {
+		log.Printf("dashboardTemplates.ExecuteTemplate(): %v", err)
+	}
+}
+
+func (dashboard *Dashboard) overview(w http.ResponseWriter, r *http.Request, user *discordgo.User, environments []*Environment) {
+	type live struct {
+		*Deployment
+		Progress string
+	}
+
+	running, queued := []live{}, []Deployment{}
+	for _, deployment := range scheduler.Running() {
+		if allowedEnvironment(environments, deployment.Environment.Name) {
+			current := live{Deployment: deployment}
+			if deployment.Progress != nil {
+				current.Progress = strings.ReplaceAll(deployment.Progress.Render(), "`", "")
+			}
+			running = append(running, current)
+		}
+	}
+
+	for _, deployment := range scheduler.Queued() {
+		if allowedEnvironment(environments, deployment.Environment.Name) {
+			queued = append(queued, deployment)
+		}
+	}
+
+	render(w, "overview.html", map[string]any{"User": user, "Environments": environments, "Running": running, "Queued": queued, "Refresh": 5})
+}
+
+func (dashboard *Dashboard) history(w http.ResponseWriter, r *http.Request, user *discordgo.User, environments []*Environment) {
+	query := r.URL.Query()
+	filters := map[string]string{}
+	for _, name := range []string{"environment", "status", "key", "author", "incident"} {
+		filters[name] = strings.TrimSpace(query.Get(name))
+	}
+
+	if filters["environment"] != "" && !allowedEnvironment(environments, filters["environment"]) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	count := 100
+	if n, err := strconv.Atoi(query.Get("count")); err == nil && n > 0 && n <= 1000 {
+		count = n
+	}
+
+	records := []*Record{}
+	store.View(func(state *State) {
+		for _, record := range history.Recent(state.History, filters["environment"], -1) {
+			switch {
+			case !allowedEnvironment(environments, record.Environment):
+			case filters["status"] != "" && record.Status != filters["status"]:
+			case filters["key"] != "" && record.Key != filters["key"]:
+			case filters["author"] != "" && record.Author != filters["author"] && !strings.EqualFold(record.Username, filters["author"]):
+			case filters["incident"] != "" && record.Incident != filters["incident"]:
+			default:
+				records = append(records, record)
+			}
+
+			if len(records) == count {
+				break
+			}
+		}
+	})
+
+	render(w, "history.html", map[string]any{"User": user, "Environments": environments, "Records": records, "Filters": filters, "Statuses": []string{"success", "degraded", "failed"}})
+}
+
+func (dashboard *Dashboard) logs(w http.ResponseWriter, r *http.Request, user *discordgo.User, environments []*Environment) {
+	id := r.PathValue("id")
+
+	var record *Record
+	store.View(func(state *State) {
+		for _, candidate := range state.History {
+			if candidate.ID == id {
+				record = candidate
+			}
+		}
+	})
+
+	if record == nil || !allowedEnvironment(environments, record.Environment) {
+		http.NotFound(w, r)
+		return
+	}
+
+	output, err := deploymentLogs.Read(record.ID)
+	if err != nil && !errors.Is(err, history.ErrNoLog) {
+		log.Printf("deploymentLogs.Read(): %v", err)
+	}
+
+	render(w, "logs.html", map[string]any{"User": user, "Environments": environments, "Record": record, "Output": string(output)})
+}
+
+func serveDashboard(session *discordgo.Session, addr string) error {
+	if data.DashboardURL == "" || data.DiscordClientID == "" || data.DiscordClientSecret == "" {
+		return fmt.Errorf("refusing to listen on %s without DASHBOARD_URL, DISCORD_CLIENT_ID and DISCORD_CLIENT_SECRET", addr)
+	}
+
+	dashboard := &Dashboard{session: session, users: map[string]*dashboardUser{}, attempts: map[string]time.Time{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", dashboard.login)
+	mux.HandleFunc("GET /callback", dashboard.callback)
+	mux.HandleFunc("GET /logout", dashboard.logout)
+	mux.HandleFunc("GET /{$}", dashboard.authorized(dashboard.overview))
+	mux.HandleFunc("GET /history", dashboard.authorized(dashboard.history))
+	mux.HandleFunc("GET /logs/{id}", dashboard.authorized(dashboard.logs))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Dashboard listening on %s", listener.Addr())
+	go http.Serve(listener, mux)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestDashboardCallbackState(t *testing.T) {
+	data = &Config{DashboardURL: "https://deploy.example.com"}
+	dashboard := &Dashboard{users: map[string]*dashboardUser{}, attempts: map[string]time.Time{}}
+
+	login := httptest.NewRecorder()
+	dashboard.login(login, httptest.NewRequest(http.MethodGet, "/login", nil))
+
+	location, err := url.Parse(login.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("url.Parse(): %v", err)
+	}
+	state := location.Query().Get("state")
+
+	var cookie *http.Cookie
+	for _, candidate := range login.Result().Cookies() {
+		if candidate.Name == loginCookie {
+			cookie = candidate
+		}
+	}
+	if cookie == nil || cookie.Value != state || !cookie.HttpOnly || !cookie.Secure {
+		t.Fatalf("login cookie = %+v, want an HttpOnly, Secure cookie holding state %s", cookie, state)
+	}
+
+	tests := []struct {
+		name   string
+		cookie string
+	}{
+		{"MissingCookie", ""},
+		{"OtherBrowser", newID()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/callback?code=code&state="+state, nil)
+			if test.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: loginCookie, Value: test.cookie})
+			}
+
+			response := httptest.NewRecorder()
+			dashboard.callback(response, request)
+			if response.Code != http.StatusBadRequest {
+				t.Errorf("callback() = %d, want %d", response.Code, http.StatusBadRequest)
+			}
+			if _, ok := dashboard.attempts[state]; !ok {
+				t.Errorf("callback() consumed the login attempt of another browser")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+type DeploymentState struct {
+	ID          string    `json:"id"`
+	Environment string    `json:"environment"`
+	Key         string    `json:"key"`
+	Ref         string    `json:"ref"`
+	Requester   string    `json:"requester"`
+	Started     time.Time `json:"started"`
+}
+
+func deploymentState(deployment *Deployment) DeploymentState {
+	return DeploymentState{
+		ID:          deployment.ID,
+		Environment: deployment.Environment.Name,
+		Key:         deployment.Key,
+		Ref:         deployment.Branch,
+		Requester:   deployment.Author.Username,
+		Started:     deployment.Started,
+	}
+}
+
+func debugState(w http.ResponseWriter, r *http.Request) {
+	running, queued := []DeploymentState{}, []DeploymentState{}
+	for _, deployment := range scheduler.Running() {
+		running = append(running, deploymentState(deployment))
+	}
+
+	for _, deployment := range scheduler.Queued() {
+		queued = append(queued, deploymentState(&deployment))
+	}
+
+	decisionsMu.Lock()
+	pending := len(decisions)
+	decisionsMu.Unlock()
+
+	state := map[string]any{
+		"version":    versionString(),
+		"uptime":     uptime(),
+		"goroutines": runtime.NumGoroutine(),
+		"running":    running,
+		"queued":     queued,
+		"decisions":  pending,
+	}
+
+	store.View(func(s *State) {
+		state["locks"] = s.Locks
+		state["maintenance"] = s.Maintenance
+		state["slots"] = s.Slots
+		state["deployed"] = s.Deployed
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(state)
+}
+
+func debugAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if data.DebugToken == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+data.DebugToken)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token := requestToken(r); token != nil && r.URL.Path == "/debug/history/export" {
+			next.ServeHTTP(w, r.WithContext(withToken(r.Context(), token)))
+			return
+		}
+
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func serveDebug(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); data.DebugToken == "" && host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("refusing to expose %s on a non-loopback address without DEBUG_TOKEN", addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/state", debugState)
+	mux.HandleFunc("/debug/history/export", debugHistoryExport)
+	mux.HandleFunc("/metrics", debugMetrics)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Debug endpoint listening on %s", listener.Addr())
+	go http.Serve(listener, debugAuth(mux))
+	return nil
+}
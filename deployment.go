@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"deploy/engine"
+	"deploy/executor"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type Deployment struct {
+	ID          string
+	Environment *Environment
+	Key         string
+	Entry       *Entry
+	Branch      string
+	RefType     string
+	SHA         string
+	Author      *discordgo.User
+	Started     time.Time
+	Backup      string
+	Progress    *Progress
+	Reason      string
+	Ticket      string
+	LockedBy    string
+	PullRequest int
+	Flags       []string
+	Incident    string
+	Priority    string
+	Preempt     bool
+	Commits     string
+	Params      map[string]string
+	Promoted    string
+	Approved    string
+	Slot        string
+	Credential  string
+
+	jumped int
+	thread string
+	wait   bool
+	active *atomic.Int64
+	done   func(outcome string)
+	gitEnv []string
+	cancel context.CancelCauseFunc
+	trace  context.Context
+}
+
+func (deployment *Deployment) request() *engine.Request {
+	vars := map[string]string{"BACKUP": deployment.Backup}
+	maps.Copy(vars, deployment.Params)
+	if deployment.PullRequest != 0 {
+		maps.Copy(vars, previewVars(deployment.PullRequest))
+	}
+
+	return &engine.Request{
+		Entry:       deployment.Entry,
+		Environment: deployment.Environment.Name,
+		Location:    deployment.Environment.Location,
+		Ref:         deployment.Branch,
+		SHA:         deployment.SHA,
+		Previous:    deployedSHA(deployment.Environment),
+		Vars:        vars,
+		Executor:    deployment.executorOptions(),
+		Plugins:     data.PluginsDir,
+	}
+}
+
+func (deployment *Deployment) executorOptions() executor.Options {
+	options := executorOptions(deployment.Environment.Shell, append(secretEnv(deployment.Entry.Secrets), deployment.gitEnv...))
+	options.Activity = deployment.touch
+	return options
+}
+
+func (deployment *Deployment) expand(command string, vars ...string) string {
+	return deployment.request().Expand(command, vars...)
+}
+
+func (deployment *Deployment) execute(ctx context.Context, dir, command string, vars ...string) ([]byte, error) {
+	return deployment.request().Execute(ctx, dir, command, vars...)
+}
+
+func (deployment *Deployment) timeout() time.Duration {
+	timeout := 2 * time.Minute
+	if deployment.Entry.Strategy == "canary" {
+		timeout += 2*time.Minute + canaryWindow(deployment.Entry)
+	}
+
+	if balancer := deployment.Entry.Balancer; balancer != nil {
+		timeout += 2 * balancerTimeout(balancer) * time.Duration(max(len(deployment.Entry.Targets), 1))
+	}
+
+	if observation := deployment.Environment.Observe; observation != nil {
+		timeout += observation.window
+	}
+
+	for _, step := range deployment.Entry.Steps {
+		switch step.Type {
+		case "migrations":
+			timeout += confirmWindow
+		case "ci", "argocd", "nomad":
+			timeout += stepTimeout(step)
+		}
+	}
+
+	return timeout
+}
+
+func runDeployment(session *discordgo.Session, msg *discordgo.Message, deployment *Deployment) {
+	var result error
+	defer func() { deployment.finish(result) }()
+
+	outcome, rollback := "failed", false
+	defer func() {
+		deployment.tagForumPost(session, outcome)
+		if outcome != "cancelled" && outcome != "rejected" {
+			deployment.addQuickReactions(session, msg)
+		}
+		if deployment.done != nil {
+			deployment.done(outcome)
+		}
+		if outcome == "success" {
+			deployment.advance(session)
+		}
+		if rollback {
+			rollbackTo(session, deployment, deployment.Author, true)
+		}
+	}()
+
+	if err := deployment.confirmRisk(session, msg); err != nil {
+		result, outcome = err, "rejected"
+		content, components := deployment.text(msg.ChannelID, "deploy.risk_rejected", "cause", err.Error()), []discordgo.MessageComponent{}
+		session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+		return
+	}
+
+	queueing, span := tracer.Start(deployment.context(), "queue")
+	queue, cancelQueue := context.WithCancelCause(queueing)
+	defer cancelQueue(nil)
+	deployment.cancel = cancelQueue
+
+	queued := false
+	if err := scheduler.Acquire(queue, deployment, deployment.wait || deployment.Environment.OnConflict == "queue", func(conflict *ConflictError) {
+		queued = true
+		session.ChannelMessageEdit(msg.ChannelID, msg.ID, deployment.text(msg.ChannelID, "deploy.queued", "location", conflict.Location, "other", conflict.Deployment.ID, "other_key", conflict.Deployment.Key, "other_branch", conflict.Deployment.Branch, "other_requester", conflict.Deployment.Author.ID))
+	}); err != nil {
+		endSpan(span, err)
+		result = err
+
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			outcome = "rejected"
+			session.ChannelMessageEdit(msg.ChannelID, msg.ID, deployment.text(msg.ChannelID, "deploy.rejected", "location", conflict.Location, "other", conflict.Deployment.ID, "other_key", conflict.Deployment.Key, "other_branch", conflict.Deployment.Branch, "other_requester", conflict.Deployment.Author.ID))
+		} else if cause := context.Cause(queue); errors.Is(cause, errCancelled) {
+			outcome = "cancelled"
+			session.ChannelMessageEdit(msg.ChannelID, msg.ID, deployment.text(msg.ChannelID, "deploy.cancelled", "cause", cause.Error()))
+			log.Printf("Deployment %s %s", deployment.ID, cause.Error())
+		}
+		return
+	}
+	defer scheduler.Release(deployment)
+	span.End()
+	deployment.audit("execution", "started", nil, nil)
+
+	if queued {
+		session.ChannelMessageEdit(msg.ChannelID, msg.ID, deployment.text(msg.ChannelID, "deploy.ongoing"))
+	}
+
+	ctx, cancel := deployment.watchTimeout(session, msg.ChannelID)
+	defer cancel()
+
+	var (
+		entry      = deployment.Entry
+		command    = deployment.expand(entry.Command)
+		content    = deployment.text(msg.ChannelID, "deploy.success")
+		components = []discordgo.MessageComponent{}
+		output     []byte
+		err        error
+	)
+
+	edit := func(content string, components []discordgo.MessageComponent) {
+		if components == nil {
+			components = []discordgo.MessageComponent{}
+		}
+		session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+	}
+
+	var cleanup func()
+	if deployment.gitEnv, cleanup, err = gitCredentials(deployment.Environment); err != nil {
+		result = err
+		edit(deployment.text(msg.ChannelID, "deploy.credentials_failed", "error", err.Error()), nil)
+		log.Printf("gitCredentials(): %v", err)
+		return
+	}
+	defer cleanup()
+
+	if entry.Maintenance == "wrap" && !inMaintenance(deployment.Environment) {
+		if out, err := setMaintenance(ctx, deployment.Environment, true, deployment.Author.ID); err != nil {
+			result = err
+			edit(deployment.text(msg.ChannelID, "deploy.maintenance_failed", "error", err.Error()), nil)
+			log.Printf("setMaintenance(): %v\n%s", err, string(out))
+			return
+		}
+
+		defer func() {
+			if out, err := setMaintenance(context.Background(), deployment.Environment, false, deployment.Author.ID); err != nil {
+				session.ChannelMessageSend(msg.ChannelID, text(msg.ChannelID, "maintenance.disable_failed", "environment", deployment.Environment.Name, "error", err.Error()))
+				log.Printf("setMaintenance(): %v\n%s", err, string(out))
+			}
+		}()
+	}
+
+	switch entry.Strategy {
+	case "releases":
+		command = "release " + entry.Repository
+		output, err = deployRelease(ctx, deployment)
+	case "artifact":
+		command = "artifact " + entry.Repository + "@" + deployment.Branch
+		output, err = deployArtifact(ctx, deployment)
+	case "bluegreen":
+		var previous, target string
+		output, previous, target, err = deployBlueGreen(ctx, deployment)
+		command = "bluegreen " + deployment.Key + "@" + target
+		content = deployment.text(msg.ChannelID, "deploy.switched", "slot", target)
+		if err == nil && previous != "" {
+			components = blueGreenButtons(deployment, previous)
+		}
+	case "canary":
+		command = "canary " + strings.Join(entry.Targets, ",")
+		output, err = deployCanary(ctx, deployment, edit)
+	default:
+		if len(entry.Steps) == 0 {
+			var result engine.Result
+			result, err = engine.Run(ctx, *deployment.request())
+			output = result.Output
+			break
+		}
+
+		command = fmt.Sprintf("pipeline %s (%d steps)", deployment.Key, len(entry.Steps))
+		deployment.Progress = newProgress(entry.Steps)
+
+		track, stop := context.WithCancel(ctx)
+		tracked := make(chan struct{})
+		go func() {
+			defer close(tracked)
+			deployment.Progress.Track(track, func(status string) {
+				embeds := []*discordgo.MessageEmbed{{Description: status, Color: 0x3b82f6}}
+				session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Embeds: &embeds})
+			})
+		}()
+
+		output, err = runPipeline(ctx, deployment, edit)
+		stop()
+		<-tracked
+
+		color := 0x008000
+		if err != nil {
+			color = 0x800000
+		}
+		embeds := []*discordgo.MessageEmbed{{Description: deployment.Progress.Render(), Color: color}}
+		session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Embeds: &embeds})
+		if deployment.Backup != "" {
+			content += "\n" + deployment.text(msg.ChannelID, "deploy.backup", "backup", deployment.Backup)
+		}
+	}
+
+	if cause := context.Cause(ctx); err != nil && (errors.Is(cause, errTimeout) || errors.Is(cause, errStalled)) {
+		err = fmt.Errorf("%w: %v", cause, err)
+	}
+
+	if err != nil {
+		result = err
+		failure := deployment.text(msg.ChannelID, "deploy.failed", "error", err.Error())
+		if hint := gitHint(msg.ChannelID, err); hint != "" {
+			failure += "\n" + hint
+		} else if clean := sanitizeOutput(string(output)); clean != "" {
+			failure += "\n```\n" + tail(clean, 1500) + "\n```"
+		}
+		edit(failure, nil)
+		log.Printf("cmd.CombinedOutput(): %v\n%s", err, string(output))
+		deployment.notify("failed", err.Error(), output, append(deployment.changeFields(), deployment.archive(output)...)...)
+		if errors.Is(err, errVerification) {
+			deployment.notifyVerification(err)
+		}
+		recordDeployment(deployment, "failed", err)
+		storeLog(deployment, output)
+		deployment.pingIncident(session, "failed")
+		deployment.audit("result", "failed", nil, map[string]string{"error": err.Error()})
+		return
+	}
+
+	deployment.SHA = deployedRevision(ctx, deployment)
+
+	status, fields := "success", deployment.changeFields()
+	if len(deployment.Flags) > 0 {
+		fields = append(fields, Field{Name: "Feature Flags", Value: truncate(strings.Join(deployment.Flags, "\n"), 1000)})
+	}
+	if len(deployment.Environment.Smoke) > 0 {
+		results := runSmokeTests(ctx, deployment)
+		summary := truncate(smokeSummary(results), 1000)
+		fields = append(fields, Field{Name: "Smoke Tests", Value: summary})
+
+		if failures := smokeFailures(results); failures > 0 {
+			status = "degraded"
+			content = deployment.text(msg.ChannelID, "deploy.smoke_degraded", "failures", failures, "total", len(results))
+			if deployment.Environment.SmokePolicy != "degrade" {
+				status = "failed"
+				content = deployment.text(msg.ChannelID, "deploy.smoke_failed", "failures", failures, "total", len(results))
+				result = errors.New(content)
+			}
+		}
+		content += "\n" + summary
+	}
+
+	if observation := deployment.Environment.Observe; observation != nil && status != "failed" {
+		edit(content+"\n"+deployment.text(msg.ChannelID, "deploy.observing", "window", observation.window), nil)
+		if polls, err := deployment.observe(ctx); err != nil {
+			status, result = "failed", err
+			content = deployment.text(msg.ChannelID, "deploy.observe_failed", "error", err.Error())
+			fields = append(fields, Field{Name: "Observation", Value: truncate(err.Error(), 1000)})
+			var breach *GateError
+			if rollback = observation.Rollback || errors.As(err, &breach); rollback {
+				content += "\n" + deployment.text(msg.ChannelID, "deploy.observe_rollback")
+			}
+		} else {
+			fields = append(fields, Field{Name: "Observation", Value: fmt.Sprintf("Healthy for %s (%d poll(s))", observation.window, polls)})
+		}
+	}
+
+	if url := deployment.previewURL(); url != "" && status != "failed" {
+		trackPreview(deployment, url)
+		content += "\n" + deployment.text(msg.ChannelID, "deploy.preview", "url", url)
+		fields = append(fields, Field{Name: "Preview", Value: url})
+	}
+
+	reason, detail, summary := "", map[string]string(nil), "Deployment successful."
+	if result != nil {
+		reason, detail, summary = result.Error(), map[string]string{"error": result.Error()}, fmt.Sprintf("Deployment %s: %v.", status, result)
+	} else if status != "success" {
+		summary = fmt.Sprintf("Deployment %s.", status)
+	}
+
+	outcome = status
+	edit(content, components)
+	deployment.notify(status, reason, output, append(fields, deployment.archive(output)...)...)
+	recordDeployment(deployment, status, result)
+	storeLog(deployment, output)
+	deployment.pingIncident(session, status)
+	deployment.audit("result", status, nil, detail)
+	log.Printf("%s Username: %s (%s) - Environment: %s - Branch: %s - Executed: %s", summary, deployment.Author.Username, deployment.Author.ID, deployment.Environment.Name, deployment.Branch, command)
+}
@@ -0,0 +1,220 @@
+package dictionary
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"deploy/executor"
+)
+
+type Entry struct {
+	Command     string           `json:"command"`
+	Strategy    string           `json:"strategy"`
+	Repository  string           `json:"repository"`
+	Keep        int              `json:"keep"`
+	Build       string           `json:"build"`
+	Restart     string           `json:"restart"`
+	Asset       string           `json:"asset"`
+	Checksums   string           `json:"checksums"`
+	Signature   string           `json:"signature"`
+	Keyring     string           `json:"keyring"`
+	Slots       map[string]*Slot `json:"slots"`
+	Switch      string           `json:"switch"`
+	SwitchURL   string           `json:"switch_url"`
+	Targets     []string         `json:"targets"`
+	Canary      int              `json:"canary"`
+	Rollback    string           `json:"rollback"`
+	Health      string           `json:"health"`
+	Soak        string           `json:"soak"`
+	Maintenance string           `json:"maintenance"`
+	Steps       []*Step          `json:"steps"`
+	Secrets     []string         `json:"secrets"`
+	Limits      *executor.Limits `json:"limits"`
+	Shell       string           `json:"shell"`
+	TOTP        bool             `json:"totp"`
+	Risk        string           `json:"risk"`
+	Script      string           `json:"script"`
+	Balancer    *LoadBalancer    `json:"load_balancer"`
+	Params      []*Param         `json:"params"`
+	Needs       []string         `json:"needs"`
+	Keys        []string         `json:"keys"`
+}
+
+func (entry *Entry) Aggregate() bool {
+	return len(entry.Keys) > 0 || (entry.Command == "" && entry.Strategy == "" && len(entry.Steps) == 0 && len(entry.Needs) > 0)
+}
+
+func (entry *Entry) UnmarshalJSON(b []byte) error {
+	var command string
+	if err := json.Unmarshal(b, &command); err == nil {
+		entry.Command = command
+		return nil
+	}
+
+	type raw Entry
+	return json.Unmarshal(b, (*raw)(entry))
+}
+
+type Slot struct {
+	Location string `json:"location"`
+	Health   string `json:"health"`
+}
+
+type LoadBalancer struct {
+	Type        string `json:"type"`
+	TargetGroup string `json:"target_group"`
+	Port        int    `json:"port"`
+	Socket      string `json:"socket"`
+	Backend     string `json:"backend"`
+	Target      string `json:"target"`
+	Timeout     string `json:"timeout"`
+}
+
+type Step struct {
+	Name     string            `json:"name"`
+	When     string            `json:"when"`
+	Type     string            `json:"type"`
+	Run      string            `json:"run"`
+	Pending  string            `json:"pending"`
+	Backup   string            `json:"backup"`
+	Backups  string            `json:"backups"`
+	With     map[string]string `json:"with"`
+	Parallel []*Step           `json:"parallel"`
+}
+
+func (step *Step) Label(index int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+
+	return fmt.Sprintf("step %d", index+1)
+}
+
+var paramPattern = regexp.MustCompile(`^[A-Za-z0-9._/:-]*$`)
+
+type Param struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Choices  []string `json:"choices"`
+	Default  string   `json:"default"`
+	Required bool     `json:"required"`
+	Min      *int     `json:"min"`
+	Max      *int     `json:"max"`
+}
+
+func (param *Param) Variable() string {
+	return strings.ToUpper(param.Name)
+}
+
+func (param *Param) Validate(value string) (string, error) {
+	if value = strings.TrimSpace(value); value == "" {
+		value = param.Default
+	}
+
+	if value == "" {
+		if param.Required {
+			return "", fmt.Errorf("parameter %s is required", param.Name)
+		}
+		return "", nil
+	}
+
+	switch param.Type {
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s must be a whole number", param.Name)
+		}
+		if param.Min != nil && n < *param.Min || param.Max != nil && n > *param.Max {
+			return "", fmt.Errorf("parameter %s must be between %s and %s", param.Name, bound(param.Min, "-∞"), bound(param.Max, "∞"))
+		}
+	case "choice":
+		if !slices.Contains(param.Choices, value) {
+			return "", fmt.Errorf("parameter %s must be one of %s", param.Name, strings.Join(param.Choices, ", "))
+		}
+	default:
+		if !paramPattern.MatchString(value) {
+			return "", fmt.Errorf("parameter %s may only contain letters, digits and ._/:-", param.Name)
+		}
+	}
+
+	return value, nil
+}
+
+func (param *Param) Hint() string {
+	switch param.Type {
+	case "int":
+		return fmt.Sprintf("Whole number between %s and %s", bound(param.Min, "-∞"), bound(param.Max, "∞"))
+	case "choice":
+		return "One of " + strings.Join(param.Choices, ", ")
+	}
+	return "Letters, digits and ._/:-"
+}
+
+func bound(value *int, unbounded string) string {
+	if value == nil {
+		return unbounded
+	}
+	return strconv.Itoa(*value)
+}
+
+type Dictionary map[string]*Entry
+
+func (dictionary Dictionary) Order(key string) ([]string, error) {
+	order, visiting := []string{}, map[string]bool{}
+
+	var visit func(key string, path []string) error
+	visit = func(key string, path []string) error {
+		entry, ok := dictionary[key]
+		if !ok {
+			return fmt.Errorf("unknown key %s needed by %s", key, path[len(path)-1])
+		}
+
+		path = append(path, key)
+		if visiting[key] {
+			return fmt.Errorf("dependency cycle %s", strings.Join(path, " → "))
+		}
+		if slices.Contains(order, key) {
+			return nil
+		}
+
+		visiting[key] = true
+		for _, need := range slices.Concat(entry.Needs, entry.Keys) {
+			if err := visit(need, path); err != nil {
+				return err
+			}
+		}
+		visiting[key] = false
+
+		order = append(order, key)
+		return nil
+	}
+
+	if _, ok := dictionary[key]; !ok {
+		return nil, fmt.Errorf("unknown key %s", key)
+	}
+	if err := visit(key, nil); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func Load(path string) (Dictionary, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	dictionary := Dictionary{}
+	if err := json.Unmarshal(body, &dictionary); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+
+	return dictionary, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func deployedSHA(environment *Environment) (sha string) {
+	store.View(func(state *State) { sha = state.Deployed[environment.Name] })
+	return sha
+}
+
+func environmentRepo(environment *Environment) (dir, prefix string) {
+	mirror := filepath.Join(environment.Location, "repo")
+	if _, err := os.Stat(mirror); err == nil {
+		return mirror, ""
+	}
+
+	return environment.Location, "origin/"
+}
+
+func gitLines(ctx context.Context, dir string, limit int, args ...string) ([]string, error) {
+	output := &bytes.Buffer{}
+	if err := git(ctx, output, append([]string{"-C", dir}, args...)...); err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil, nil
+	}
+
+	if len(lines) > limit {
+		lines = append(lines[:limit], fmt.Sprintf("... and %d more", len(lines)-limit))
+	}
+
+	return lines, nil
+}
+
+func codeBlock(lines []string) string {
+	if len(lines) == 0 {
+		return "```\n(none)\n```"
+	}
+
+	return "```\n" + truncate(sanitizeOutput(strings.Join(lines, "\n")), 900) + "\n```"
+}
+
+func deployDiff(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+	branch := environment.Branch
+	if len(args) > 0 {
+		branch = args[0]
+	}
+
+	if !validBranch(environment, branch) || !branchAllowed(environment, branch) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "validate.invalid_branch", "branch", branch))
+		return
+	}
+
+	deployed := deployedSHA(environment)
+	if deployed == "" {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.no_revision", "environment", environment.Name))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	env, cleanup, err := gitCredentials(environment)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.failed", "error", err.Error()))
+		return
+	}
+	defer cleanup()
+
+	dir, prefix := environmentRepo(environment)
+	if err := gitEnv(ctx, env, &bytes.Buffer{}, "-C", dir, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.failed", "error", err.Error()))
+		return
+	}
+
+	head, err := gitLines(ctx, dir, 1, "rev-parse", prefix+branch)
+	if err != nil || len(head) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "validate.invalid_branch", "branch", branch))
+		return
+	}
+
+	if head[0] == deployed {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.up_to_date", "environment", environment.Name, "branch", branch, "sha", fmt.Sprintf("%.7s", deployed)))
+		return
+	}
+
+	commits, err := gitLines(ctx, dir, 20, "log", "--no-merges", "--format=%h %an: %s", deployed+".."+head[0])
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.failed", "error", err.Error()))
+		return
+	}
+
+	files, err := gitLines(ctx, dir, 20, "diff", "--stat=80", deployed, head[0])
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.failed", "error", err.Error()))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "diff.summary", "branch", branch, "environment", environment.Name, "from", fmt.Sprintf("%.7s", deployed), "to", fmt.Sprintf("%.7s", head[0]), "commits", codeBlock(commits), "files", codeBlock(files)))
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+
+	"deploy/executor"
+	"go.starlark.net/starlark"
+)
+
+var matchOperator = regexp.MustCompile(`([\w.\[\]"']+)\s*(=~|!~)\s*("[^"]*"|'[^']*')`)
+
+func rewriteCondition(condition string) string {
+	return matchOperator.ReplaceAllStringFunc(condition, func(expr string) string {
+		parts := matchOperator.FindStringSubmatch(expr)
+		if parts[2] == "!~" {
+			return fmt.Sprintf("not match(%s, %s)", parts[1], parts[3])
+		}
+		return fmt.Sprintf("match(%s, %s)", parts[1], parts[3])
+	})
+}
+
+func matchGlob(pattern, name string) bool {
+	patterns, names := strings.Split(pattern, "/"), strings.Split(name, "/")
+	var match func(p, n int) bool
+	match = func(p, n int) bool {
+		for ; p < len(patterns); p++ {
+			if patterns[p] == "**" {
+				for next := n; next <= len(names); next++ {
+					if match(p+1, next) {
+						return true
+					}
+				}
+				return false
+			}
+
+			if n >= len(names) {
+				return false
+			}
+			if matched, err := path.Match(patterns[p], names[n]); err != nil || !matched {
+				return false
+			}
+			n++
+		}
+		return n == len(names)
+	}
+
+	return match(0, 0)
+}
+
+func (request *Request) changedFiles(ctx context.Context) ([]string, error) {
+	output := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, "git", "-C", request.Location, "diff", "--name-only", request.Previous, "HEAD")
+	cmd.Stdout = output
+
+	release, err := executor.Prepare(cmd, request.Executor)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+
+	return strings.Fields(output.String()), nil
+}
+
+func (request *Request) builtins() starlark.StringDict {
+	return starlark.StringDict{
+		"match": starlark.NewBuiltin("match", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var value, pattern string
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &value, &pattern); err != nil {
+				return nil, err
+			}
+			return starlark.Bool(matchGlob(pattern, value)), nil
+		}),
+		"changed": starlark.NewBuiltin("changed", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if len(kwargs) > 0 || len(args) == 0 {
+				return nil, fmt.Errorf("%s: expected one or more path patterns", fn.Name())
+			}
+
+			if request.Previous == "" {
+				return starlark.True, nil
+			}
+
+			files, err := request.changedFiles(thread.Local("context").(context.Context))
+			if err != nil {
+				return starlark.True, nil
+			}
+
+			for _, arg := range args {
+				pattern, ok := starlark.AsString(arg)
+				if !ok {
+					return nil, fmt.Errorf("%s: got %s, want string", fn.Name(), arg.Type())
+				}
+
+				for _, file := range files {
+					if matchGlob(pattern, file) {
+						return starlark.True, nil
+					}
+				}
+			}
+
+			return starlark.False, nil
+		}),
+	}
+}
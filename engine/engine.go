@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"deploy/dictionary"
+	"deploy/executor"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.starlark.net/starlark"
+)
+
+var tracer = otel.Tracer("deploy")
+
+type StepFunc func(ctx context.Context, request *Request, step *dictionary.Step, output *bytes.Buffer) error
+
+type Request struct {
+	Entry       *dictionary.Entry
+	Environment string
+	Location    string
+	Ref         string
+	SHA         string
+	Previous    string
+	Vars        map[string]string
+	Executor    executor.Options
+	Steps       map[string]StepFunc
+	Skip        map[string]bool
+	Plugins     string
+	Progress    func(step int, name string)
+	Completed   func(step int, result StepResult)
+
+	globals starlark.StringDict
+}
+
+type StepResult struct {
+	Name     string
+	Type     string
+	Duration time.Duration
+	Skipped  bool
+	Steps    []StepResult
+	Err      error
+}
+
+type Result struct {
+	Output   []byte
+	Steps    []StepResult
+	Duration time.Duration
+}
+
+func (request *Request) Expand(command string, vars ...string) string {
+	for _, name := range slices.Sorted(maps.Keys(request.Vars)) {
+		vars = append(vars, "${"+name+"}", request.Vars[name])
+	}
+
+	return strings.NewReplacer(slices.Concat(vars, []string{"${LOCATION}", request.Location, "${BRANCH}", request.Ref, "${REF}", request.Ref, "${SHA}", request.SHA})...).Replace(command)
+}
+
+func (request *Request) Execute(ctx context.Context, dir, command string, vars ...string) ([]byte, error) {
+	options := request.Executor
+	options.Dir = dir
+	if request.Entry.Shell != "" {
+		options.Shell = request.Entry.Shell
+	}
+	if request.Entry.Limits != nil {
+		options.Limits = request.Entry.Limits
+	}
+
+	return executor.Run(ctx, request.Expand(command, vars...), options)
+}
+
+func Run(ctx context.Context, request Request) (Result, error) {
+	started := time.Now()
+	if request.Entry.Strategy != "" {
+		return Result{}, fmt.Errorf("strategy %s is not supported by the engine", request.Entry.Strategy)
+	}
+
+	var (
+		output = &bytes.Buffer{}
+		result = Result{}
+		err    error
+	)
+
+	if request.Entry.Script != "" {
+		err = request.compute(ctx, output)
+	}
+
+	if err == nil && len(request.Entry.Steps) == 0 {
+		var out []byte
+		out, err = request.Execute(ctx, "", request.Entry.Command)
+		output.Write(out)
+	} else if err == nil {
+		result.Steps, err = request.pipeline(ctx, output)
+	}
+
+	result.Output, result.Duration = output.Bytes(), time.Since(started)
+	return result, err
+}
+
+func (request *Request) pipeline(ctx context.Context, output *bytes.Buffer) ([]StepResult, error) {
+	results := []StepResult{}
+
+	for i, step := range request.Entry.Steps {
+		if request.Progress != nil {
+			request.Progress(i+1, step.Label(i))
+		}
+
+		result := request.run(ctx, step.Label(i), step, output, "==>", false)
+		results = append(results, result)
+		if request.Completed != nil {
+			request.Completed(i+1, result)
+		}
+		if result.Err != nil {
+			return results, fmt.Errorf("%s: %w", result.Name, result.Err)
+		}
+	}
+
+	return results, nil
+}
+
+func (request *Request) run(ctx context.Context, label string, step *dictionary.Step, output *bytes.Buffer, marker string, parallel bool) StepResult {
+	result := StepResult{Name: label, Type: step.Type}
+
+	if step.When != "" {
+		run, err := request.condition(ctx, step.When, output)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		if !run {
+			fmt.Fprintf(output, "%s %s (skipped)\n", marker, label)
+			result.Skipped = true
+			return result
+		}
+	}
+
+	if request.Skip[step.Type] {
+		fmt.Fprintf(output, "%s %s (skipped)\n", marker, label)
+		result.Skipped = true
+		return result
+	}
+
+	if len(step.Parallel) > 0 {
+		fmt.Fprintf(output, "%s %s (%d in parallel)\n", marker, label, len(step.Parallel))
+	} else {
+		fmt.Fprintf(output, "%s %s\n", marker, label)
+	}
+
+	stepCtx, span := tracer.Start(ctx, "step "+label, trace.WithAttributes(attribute.String("deploy.step.type", step.Type)))
+	started := time.Now()
+
+	switch handler := request.Steps[step.Type]; {
+	case len(step.Parallel) > 0:
+		result.Steps, result.Err = request.group(stepCtx, step, output)
+	case step.Type == "git" && parallel:
+		result.Err = errors.New("git steps cannot run in parallel")
+	case step.Type == "git":
+		result.Err = request.checkout(stepCtx, step, output)
+	case step.Type == "systemd":
+		result.Err = request.systemd(stepCtx, step, output)
+	case step.Type == "pm2":
+		result.Err = request.pm2(stepCtx, step, output)
+	case step.Type == "supervisor":
+		result.Err = request.supervisor(stepCtx, step, output)
+	case handler != nil && parallel:
+		result.Err = fmt.Errorf("%s steps cannot run in parallel", step.Type)
+	case handler != nil:
+		result.Err = handler(stepCtx, request, step, output)
+	case step.Type == "" || step.Type == "command":
+		var out []byte
+		out, result.Err = request.Execute(stepCtx, request.Location, step.Run)
+		output.Write(out)
+	default:
+		var path string
+		if path, result.Err = FindPlugin(request.Plugins, step.Type); result.Err == nil {
+			result.Err = request.runPlugin(stepCtx, path, label, step, output)
+		}
+	}
+
+	if result.Err != nil {
+		span.RecordError(result.Err)
+		span.SetStatus(codes.Error, result.Err.Error())
+	}
+	span.End()
+
+	result.Duration = time.Since(started)
+	return result
+}
+
+func (request *Request) group(ctx context.Context, step *dictionary.Step, output *bytes.Buffer) ([]StepResult, error) {
+	buffers := make([]*bytes.Buffer, len(step.Parallel))
+	results := make([]StepResult, len(step.Parallel))
+
+	var wg sync.WaitGroup
+	for i, child := range step.Parallel {
+		buffers[i] = &bytes.Buffer{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = request.run(ctx, child.Label(i), child, buffers[i], "-->", true)
+		}()
+	}
+	wg.Wait()
+
+	errs := []error{}
+	for i, result := range results {
+		output.Write(buffers[i].Bytes())
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
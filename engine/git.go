@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"deploy/dictionary"
+	"deploy/executor"
+)
+
+var (
+	ErrGitAuth       = errors.New("authentication failed")
+	ErrGitMissingRef = errors.New("ref not found")
+	ErrGitDirty      = errors.New("working tree has local changes")
+	ErrGitNetwork    = errors.New("remote unreachable")
+)
+
+var gitFailures = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrGitAuth, []string{"permission denied", "authentication failed", "could not read username", "could not read password", "host key verification failed", "invalid username or password", "403"}},
+	{ErrGitMissingRef, []string{"couldn't find remote ref", "did not match any file(s) known to git", "unknown revision", "not a valid object name", "invalid reference"}},
+	{ErrGitNetwork, []string{"could not resolve host", "connection timed out", "connection refused", "network is unreachable", "unable to access", "could not read from remote repository"}},
+}
+
+type GitError struct {
+	Op     string
+	Kind   error
+	Detail string
+}
+
+func (err *GitError) Error() string {
+	if err.Kind == nil {
+		return fmt.Sprintf("git %s: %s", err.Op, err.Detail)
+	}
+	return fmt.Sprintf("git %s: %s (%s)", err.Op, err.Kind, err.Detail)
+}
+
+func (err *GitError) Unwrap() error {
+	return err.Kind
+}
+
+func classifyGit(op string, stderr string, err error) error {
+	detail := strings.TrimSpace(stderr)
+	if lines := strings.Split(detail, "\n"); len(lines) > 0 {
+		detail = strings.TrimSpace(lines[len(lines)-1])
+	}
+	if detail == "" {
+		detail = err.Error()
+	}
+
+	lower := strings.ToLower(stderr)
+	for _, failure := range gitFailures {
+		for _, pattern := range failure.patterns {
+			if strings.Contains(lower, pattern) {
+				return &GitError{Op: op, Kind: failure.kind, Detail: detail}
+			}
+		}
+	}
+
+	return &GitError{Op: op, Detail: detail}
+}
+
+func (request *Request) exec(ctx context.Context, output *bytes.Buffer, name string, args ...string) (string, string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	release, err := executor.Prepare(cmd, request.Executor)
+	if err != nil {
+		return "", "", err
+	}
+	defer release()
+
+	err = cmd.Run()
+	if output != nil {
+		output.Write(stdout.Bytes())
+		output.Write(stderr.Bytes())
+	}
+
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	return strings.TrimSpace(stdout.String()), stderr.String(), err
+}
+
+func (request *Request) git(ctx context.Context, output *bytes.Buffer, args ...string) (string, error) {
+	stdout, stderr, err := request.exec(ctx, output, "git", append([]string{"-C", request.Location}, args...)...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		return "", classifyGit(args[0], stderr, err)
+	}
+
+	return stdout, nil
+}
+
+func (request *Request) checkout(ctx context.Context, step *dictionary.Step, output *bytes.Buffer) error {
+	remote := step.With["remote"]
+	if remote == "" {
+		remote = "origin"
+	}
+
+	ref := request.Ref
+	if step.With["ref"] != "" {
+		ref = request.Expand(step.With["ref"])
+	}
+
+	if step.With["clean"] == "true" {
+		if _, err := request.git(ctx, output, "reset", "--hard", "--quiet"); err != nil {
+			return err
+		}
+		if _, err := request.git(ctx, output, "clean", "-fd", "--quiet"); err != nil {
+			return err
+		}
+	} else if status, err := request.git(ctx, nil, "status", "--porcelain", "--untracked-files=no"); err != nil {
+		return err
+	} else if status != "" {
+		files := strings.Split(status, "\n")
+		detail := strings.Join(files[:min(len(files), 5)], ", ")
+		if len(files) > 5 {
+			detail += fmt.Sprintf(" and %d more", len(files)-5)
+		}
+		return &GitError{Op: "status", Kind: ErrGitDirty, Detail: detail}
+	}
+
+	if _, err := request.git(ctx, output, "fetch", "--prune", "--tags", "--force", remote); err != nil {
+		return err
+	}
+
+	if _, err := request.git(ctx, nil, "rev-parse", "--verify", "--quiet", remote+"/"+ref+"^{commit}"); err == nil {
+		start := remote + "/" + ref
+		if request.SHA != "" && step.With["ref"] == "" {
+			start = request.SHA
+		}
+
+		if _, err := request.git(ctx, output, "checkout", "--quiet", "--force", "-B", ref, start); err != nil {
+			return err
+		}
+	} else {
+		target := ref
+		if request.SHA != "" && step.With["ref"] == "" {
+			target = request.SHA
+		}
+
+		if _, err := request.git(ctx, nil, "rev-parse", "--verify", "--quiet", target+"^{commit}"); err != nil {
+			return &GitError{Op: "rev-parse", Kind: ErrGitMissingRef, Detail: ref}
+		}
+
+		if _, err := request.git(ctx, output, "checkout", "--quiet", "--force", "--detach", target); err != nil {
+			return err
+		}
+	}
+
+	head, err := request.git(ctx, nil, "log", "-1", "--format=%h %s")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "HEAD is now at %s\n", head)
+	return nil
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+
+	"deploy/dictionary"
+	"deploy/executor"
+)
+
+var pluginName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+type PluginInput struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Run      string            `json:"run,omitempty"`
+	With     map[string]string `json:"with,omitempty"`
+	Location string            `json:"location"`
+	Ref      string            `json:"ref"`
+	SHA      string            `json:"sha,omitempty"`
+}
+
+type PluginOutput struct {
+	Output string `json:"output"`
+	Error  string `json:"error"`
+}
+
+func FindPlugin(dir, name string) (string, error) {
+	if dir == "" || !pluginName.MatchString(name) {
+		return "", fmt.Errorf("unknown step type %s", name)
+	}
+
+	path, err := exec.LookPath(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("unknown step type %s: no plugin in %s", name, dir)
+	}
+
+	return path, nil
+}
+
+func (request *Request) runPlugin(ctx context.Context, path, name string, step *dictionary.Step, output *bytes.Buffer) error {
+	input := PluginInput{
+		Name:     name,
+		Type:     step.Type,
+		Run:      request.Expand(step.Run),
+		With:     map[string]string{},
+		Location: request.Location,
+		Ref:      request.Ref,
+		SHA:      request.SHA,
+	}
+	for key, value := range step.With {
+		input.With[key] = request.Expand(value)
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(): %w", err)
+	}
+
+	options := request.Executor
+	if request.Entry.Limits != nil {
+		options.Limits = request.Entry.Limits
+	}
+
+	stdout := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Dir = request.Location
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(body), stdout, output
+
+	release, err := executor.Prepare(cmd, options)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	runErr := cmd.Run()
+
+	result := PluginOutput{}
+	if stdout.Len() > 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+			output.Write(stdout.Bytes())
+			return fmt.Errorf("plugin %s returned invalid output: %w", step.Type, err)
+		}
+	}
+	output.WriteString(result.Output)
+
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+
+	return runErr
+}
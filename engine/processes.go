@@ -0,0 +1,198 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"deploy/dictionary"
+)
+
+type ProcessError struct {
+	Manager string
+	Name    string
+	State   string
+}
+
+func (err *ProcessError) Error() string {
+	return fmt.Sprintf("%s process %s is %s", err.Manager, err.Name, err.State)
+}
+
+type processCheck func(ctx context.Context) (done bool, err error)
+
+func waitProcesses(ctx context.Context, step *dictionary.Step, check processCheck) error {
+	timeout, err := time.ParseDuration(step.With["timeout"])
+	if err != nil {
+		timeout = time.Minute
+	}
+	settle, _ := time.ParseDuration(step.With["settle"])
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	wait := func() error {
+		for {
+			done, err := check(ctx)
+			if done || err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("timed out after %s", timeout)
+			case <-time.After(time.Second):
+			}
+		}
+	}
+
+	if err := wait(); err != nil || settle <= 0 {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %s", timeout)
+	case <-time.After(settle):
+	}
+	return wait()
+}
+
+func (request *Request) pm2(ctx context.Context, step *dictionary.Step, output *bytes.Buffer) error {
+	action := step.With["action"]
+	if action == "" {
+		action = "reload"
+	}
+	if action != "reload" && action != "restart" {
+		return fmt.Errorf("unknown pm2 action %q", action)
+	}
+
+	names := strings.Fields(request.Expand(step.With["process"]))
+	if len(names) == 0 {
+		return fmt.Errorf("pm2 steps need at least one process")
+	}
+
+	started := time.Now().UnixMilli()
+	restarts := map[int]int{}
+	for _, name := range names {
+		if _, stderr, err := request.exec(ctx, output, "pm2", action, name, "--update-env"); err != nil {
+			return fmt.Errorf("pm2 %s %s: %s", action, name, strings.TrimSpace(stderr+" "+err.Error()))
+		}
+	}
+
+	err := waitProcesses(ctx, step, func(ctx context.Context) (bool, error) {
+		stdout, stderr, err := request.exec(ctx, nil, "pm2", "jlist")
+		if err != nil {
+			return false, fmt.Errorf("pm2 jlist: %s", strings.TrimSpace(stderr+" "+err.Error()))
+		}
+
+		processes := []struct {
+			Name string `json:"name"`
+			ID   int    `json:"pm_id"`
+			Env  struct {
+				Status   string `json:"status"`
+				Uptime   int64  `json:"pm_uptime"`
+				Restarts int    `json:"restart_time"`
+			} `json:"pm2_env"`
+		}{}
+		if err := json.Unmarshal([]byte(stdout), &processes); err != nil {
+			return false, fmt.Errorf("pm2 jlist: %w", err)
+		}
+
+		for _, name := range names {
+			found := false
+			for _, process := range processes {
+				if process.Name != name {
+					continue
+				}
+				found = true
+
+				label := fmt.Sprintf("%s[%d]", name, process.ID)
+				switch status := process.Env.Status; {
+				case status == "errored" || status == "stopped":
+					return false, &ProcessError{Manager: "pm2", Name: label, State: status}
+				case status != "online" || process.Env.Uptime < started:
+					return false, nil
+				}
+
+				if previous, ok := restarts[process.ID]; ok && previous != process.Env.Restarts {
+					return false, &ProcessError{Manager: "pm2", Name: label, State: "restarting repeatedly"}
+				}
+				restarts[process.ID] = process.Env.Restarts
+			}
+
+			if !found {
+				return false, &ProcessError{Manager: "pm2", Name: name, State: "not found"}
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		request.exec(ctx, output, "pm2", "logs", names[0], "--nostream", "--lines", "20")
+		return err
+	}
+
+	fmt.Fprintf(output, "%s online\n", strings.Join(names, ", "))
+	return nil
+}
+
+func (request *Request) supervisor(ctx context.Context, step *dictionary.Step, output *bytes.Buffer) error {
+	names := strings.Fields(request.Expand(step.With["process"]))
+	if len(names) == 0 {
+		return fmt.Errorf("supervisor steps need at least one process")
+	}
+
+	base := []string{}
+	if step.With["config"] != "" {
+		base = append(base, "-c", request.Expand(step.With["config"]))
+	}
+
+	command := append([]string{}, base...)
+	switch action := step.With["action"]; action {
+	case "", "restart":
+		command = append(command, "restart")
+	case "reload":
+		command = append(command, "signal", "HUP")
+	default:
+		return fmt.Errorf("unknown supervisor action %q", action)
+	}
+
+	if stdout, stderr, err := request.exec(ctx, output, "supervisorctl", append(command, names...)...); err != nil || strings.Contains(stdout, "ERROR") {
+		return fmt.Errorf("supervisorctl %s: %s", strings.Join(command[len(base):], " "), strings.TrimSpace(stdout+" "+stderr))
+	}
+
+	var status string
+	err := waitProcesses(ctx, step, func(ctx context.Context) (bool, error) {
+		status, _, _ = request.exec(ctx, nil, "supervisorctl", append(append(append([]string{}, base...), "status"), names...)...)
+		if status == "" {
+			return false, fmt.Errorf("supervisorctl status returned nothing for %s", strings.Join(names, ", "))
+		}
+
+		running := true
+		for line := range strings.Lines(status) {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+
+			switch fields[1] {
+			case "RUNNING":
+			case "STARTING", "STOPPING":
+				running = false
+			default:
+				return false, &ProcessError{Manager: "supervisor", Name: fields[0], State: strings.ToLower(fields[1])}
+			}
+		}
+		return running, nil
+	})
+	if err != nil {
+		fmt.Fprintln(output, status)
+		request.exec(ctx, output, "supervisorctl", append(append(append([]string{}, base...), "tail"), names[0], "stderr")...)
+		return err
+	}
+
+	fmt.Fprintln(output, status)
+	return nil
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	scriptTimeout = 2 * time.Second
+	scriptSteps   = 1_000_000
+)
+
+var scriptOptions = &syntax.FileOptions{}
+
+func ScriptVariables(script string) ([]string, error) {
+	file, err := scriptOptions.Parse("script", script, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, stmt := range file.Stmts {
+		if assign, ok := stmt.(*syntax.AssignStmt); ok {
+			if ident, ok := assign.LHS.(*syntax.Ident); ok && !strings.HasPrefix(ident.Name, "_") {
+				names = append(names, ident.Name)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+func ParseCondition(condition string) error {
+	_, err := scriptOptions.ParseExpr("when", rewriteCondition(condition), 0)
+	return err
+}
+
+func (request *Request) thread(ctx context.Context, name string, output *bytes.Buffer) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			if output != nil {
+				fmt.Fprintf(output, "%s: %s\n", name, msg)
+			}
+		},
+	}
+	thread.SetMaxExecutionSteps(scriptSteps)
+
+	ctx, cancel := context.WithTimeout(ctx, scriptTimeout)
+	thread.SetLocal("context", ctx)
+	stop := context.AfterFunc(ctx, func() { thread.Cancel(fmt.Sprintf("%s exceeded %s", name, scriptTimeout)) })
+
+	return thread, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (request *Request) predeclared() starlark.StringDict {
+	vars := starlark.NewDict(len(request.Vars))
+	for name, value := range request.Vars {
+		vars.SetKey(starlark.String(name), starlark.String(value))
+	}
+	vars.Freeze()
+
+	predeclared := starlark.StringDict{
+		"environment": starlark.String(request.Environment),
+		"branch":      starlark.String(request.Ref),
+		"ref":         starlark.String(request.Ref),
+		"sha":         starlark.String(request.SHA),
+		"location":    starlark.String(request.Location),
+		"vars":        vars,
+	}
+	maps.Copy(predeclared, request.builtins())
+	maps.Copy(predeclared, request.globals)
+
+	return predeclared
+}
+
+func (request *Request) compute(ctx context.Context, output *bytes.Buffer) error {
+	thread, done := request.thread(ctx, "script", output)
+	defer done()
+
+	globals, err := starlark.ExecFileOptions(scriptOptions, thread, "script", request.Entry.Script, request.predeclared())
+	if err != nil {
+		return fmt.Errorf("script: %w", err)
+	}
+
+	if request.Vars == nil {
+		request.Vars = map[string]string{}
+	}
+
+	request.globals = starlark.StringDict{}
+	for name, value := range globals {
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		request.globals[name] = value
+
+		switch value := value.(type) {
+		case starlark.String:
+			request.Vars[name] = string(value)
+		case starlark.Int, starlark.Bool, starlark.Float:
+			request.Vars[name] = value.String()
+		}
+	}
+
+	return nil
+}
+
+func (request *Request) condition(ctx context.Context, condition string, output *bytes.Buffer) (bool, error) {
+	thread, done := request.thread(ctx, "when", output)
+	defer done()
+
+	value, err := starlark.EvalOptions(scriptOptions, thread, "when", rewriteCondition(condition), request.predeclared())
+	if err != nil {
+		return false, fmt.Errorf("when: %w", err)
+	}
+
+	return bool(value.Truth()), nil
+}
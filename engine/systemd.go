@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"deploy/dictionary"
+)
+
+var systemdActions = []string{"restart", "reload", "reload-or-restart", "try-restart", "start", "stop"}
+
+type UnitError struct {
+	Unit     string
+	State    string
+	SubState string
+	Result   string
+}
+
+func (err *UnitError) Error() string {
+	return fmt.Sprintf("unit %s is %s (%s, result %s)", err.Unit, err.State, err.SubState, err.Result)
+}
+
+type unitStatus map[string]string
+
+func (request *Request) unitStatus(ctx context.Context, unit string) (unitStatus, error) {
+	stdout, stderr, err := request.exec(ctx, nil, "systemctl", "show", "--property=ActiveState,SubState,Result,LoadState", "--", unit)
+	if err != nil {
+		return nil, fmt.Errorf("systemctl show %s: %s", unit, strings.TrimSpace(stderr+" "+err.Error()))
+	}
+
+	status := unitStatus{}
+	for line := range strings.Lines(stdout) {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			status[key] = value
+		}
+	}
+	return status, nil
+}
+
+func (request *Request) journal(ctx context.Context, unit string, lines int, output *bytes.Buffer) {
+	stdout, _, err := request.exec(ctx, nil, "journalctl", "--unit", unit, "--lines", strconv.Itoa(lines), "--no-pager", "--output", "short-iso")
+	if err != nil || stdout == "" {
+		return
+	}
+	fmt.Fprintf(output, "--- journalctl -u %s (last %d lines)\n%s\n", unit, lines, stdout)
+}
+
+func (request *Request) waitUnit(ctx context.Context, unit, want string) (unitStatus, error) {
+	for {
+		status, err := request.unitStatus(ctx, unit)
+		if err != nil {
+			return nil, err
+		}
+
+		failure := &UnitError{Unit: unit, State: status["ActiveState"], SubState: status["SubState"], Result: status["Result"]}
+		switch {
+		case status["ActiveState"] == want:
+			return status, nil
+		case status["LoadState"] == "not-found", status["ActiveState"] == "failed", want == "active" && status["ActiveState"] == "inactive":
+			return nil, failure
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for %s: %w", want, failure)
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (request *Request) systemd(ctx context.Context, step *dictionary.Step, output *bytes.Buffer) error {
+	action := step.With["action"]
+	if action == "" {
+		action = "restart"
+	}
+	if !slices.Contains(systemdActions, action) {
+		return fmt.Errorf("unknown systemd action %q", action)
+	}
+
+	units := strings.Fields(request.Expand(step.With["units"]))
+	if len(units) == 0 {
+		return fmt.Errorf("systemd steps need at least one unit")
+	}
+
+	timeout, err := time.ParseDuration(step.With["timeout"])
+	if err != nil {
+		timeout = time.Minute
+	}
+
+	lines, err := strconv.Atoi(step.With["journal"])
+	if err != nil {
+		lines = 20
+	}
+
+	want := "active"
+	if action == "stop" {
+		want = "inactive"
+	}
+
+	if _, stderr, err := request.exec(ctx, output, "systemctl", append([]string{action, "--"}, units...)...); err != nil {
+		for _, unit := range units {
+			request.journal(ctx, unit, lines, output)
+		}
+		return fmt.Errorf("systemctl %s: %s", action, strings.TrimSpace(stderr+" "+err.Error()))
+	}
+
+	settle, _ := time.ParseDuration(step.With["settle"])
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, unit := range units {
+		status, err := request.waitUnit(waitCtx, unit, want)
+		if err == nil && settle > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(settle):
+			}
+			status, err = request.waitUnit(waitCtx, unit, want)
+		}
+
+		if err != nil {
+			request.journal(ctx, unit, lines, output)
+			return err
+		}
+		fmt.Fprintf(output, "%s is %s (%s)\n", unit, status["ActiveState"], status["SubState"])
+	}
+
+	return nil
+}
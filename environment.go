@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type Maintenance struct {
+	On  string `json:"on"`
+	Off string `json:"off"`
+}
+
+type Environment struct {
+	Name        string          `json:"-"`
+	Branch      string          `json:"branch"`
+	Branches    []string        `json:"branches"`
+	Refs        []string        `json:"refs"`
+	OnConflict  string          `json:"on_conflict"`
+	Group       string          `json:"group"`
+	Location    string          `json:"location"`
+	Channel     string          `json:"channel"`
+	Channels    []string        `json:"channels"`
+	Forum       string          `json:"forum"`
+	Role        string          `json:"role"`
+	Maintenance *Maintenance    `json:"maintenance"`
+	Smoke       []*Check        `json:"smoke"`
+	SmokePolicy string          `json:"smoke_policy"`
+	Shell       string          `json:"shell"`
+	Reason      string          `json:"reason"`
+	Ticket      string          `json:"ticket"`
+	BranchRules *BranchRules    `json:"branch_rules"`
+	Git         *GitCredentials `json:"git"`
+	AutoDeploy  []*AutoDeploy   `json:"auto_deploy"`
+	Preview     *Preview        `json:"preview"`
+	Hooks       []*DeployHook   `json:"hooks"`
+	Observe     *Observation    `json:"observe"`
+	Next        *Chain          `json:"next"`
+
+	ticket *regexp.Regexp
+	host   *Environment
+}
+
+var (
+	Environments        = map[string]*Environment{}
+	environmentChannels = map[string]*Environment{}
+)
+
+func getEnvironments(path string) error {
+	body, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	environments := map[string]*Environment{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &environments); err != nil {
+			return fmt.Errorf("json.Unmarshal(): %w", err)
+		}
+	}
+
+	primary, ok := environments[data.Environment]
+	if !ok {
+		primary = &Environment{}
+		environments[data.Environment] = primary
+	}
+
+	for _, field := range []struct {
+		dst *string
+		src string
+	}{
+		{&primary.Branch, data.Branch},
+		{&primary.Location, data.DeploymentLocation},
+		{&primary.Channel, data.DeploymentChannel},
+		{&primary.Role, data.DeploymentRole},
+	} {
+		if *field.dst == "" {
+			*field.dst = field.src
+		}
+	}
+
+	for name, environment := range environments {
+		environment.Name = name
+		if environment.Branch == "" || environment.Location == "" || environment.Channel == "" || environment.Role == "" {
+			return fmt.Errorf("environment %s: branch, location, channel and role are required", name)
+		}
+
+		if environment.BranchRules != nil {
+			if err := environment.BranchRules.compile(); err != nil {
+				return fmt.Errorf("environment %s: invalid branch pattern: %w", name, err)
+			}
+		}
+
+		if environment.Observe != nil {
+			if err := environment.Observe.compile(environment); err != nil {
+				return fmt.Errorf("environment %s: %w", name, err)
+			}
+		}
+
+		if environment.Next != nil {
+			if err := environment.Next.compile(environment, environments); err != nil {
+				return fmt.Errorf("environment %s: %w", name, err)
+			}
+		}
+
+		if environment.Ticket != "" {
+			if environment.ticket, err = regexp.Compile(environment.Ticket); err != nil {
+				return fmt.Errorf("environment %s: invalid ticket pattern: %w", name, err)
+			}
+		}
+	}
+
+	if cycle := chainCycle(environments); cycle != nil {
+		return fmt.Errorf("next stages form a cycle: %s", strings.Join(cycle, " → "))
+	}
+
+	channels := map[string]*Environment{}
+	for _, name := range slices.Sorted(maps.Keys(environments)) {
+		for _, channel := range append([]string{environments[name].Channel}, environments[name].Channels...) {
+			if _, ok := channels[channel]; !ok {
+				channels[channel] = environments[name]
+			}
+		}
+	}
+
+	Environments, environmentChannels = environments, channels
+	return nil
+}
+
+func environmentByChannel(channelID string) *Environment {
+	if environment := environmentChannels[channelID]; environment != nil {
+		return environment
+	}
+	return forumEnvironment(channelID)
+}
+
+func environmentByName(name string) *Environment {
+	if environment := Environments[name]; environment != nil {
+		return environment
+	}
+	for key, environment := range Environments {
+		if strings.EqualFold(key, name) {
+			return environment
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"os/exec"
+)
+
+type Limits struct {
+	Memory string  `json:"memory"`
+	CPU    float64 `json:"cpu"`
+	Nice   int     `json:"nice"`
+}
+
+type Options struct {
+	Shell      string
+	Dir        string
+	Env        []string
+	Limits     *Limits
+	User       string
+	Path       string
+	PassEnv    []string
+	CgroupRoot string
+	Activity   func()
+}
+
+type activityWriter struct {
+	io.Writer
+	activity func()
+}
+
+func (writer *activityWriter) Write(p []byte) (int, error) {
+	writer.activity()
+	return writer.Writer.Write(p)
+}
+
+func (options Options) restricted() bool {
+	return options.User != "" || options.Path != "" || len(options.PassEnv) > 0
+}
+
+func Prepare(cmd *exec.Cmd, options Options) (func(), error) {
+	if options.Activity != nil && cmd.Stdout != nil {
+		stdout := &activityWriter{Writer: cmd.Stdout, activity: options.Activity}
+		if cmd.Stderr == cmd.Stdout {
+			cmd.Stderr = stdout
+		} else if cmd.Stderr != nil {
+			cmd.Stderr = &activityWriter{Writer: cmd.Stderr, activity: options.Activity}
+		}
+		cmd.Stdout = stdout
+	}
+
+	stop := configureProcess(cmd)
+	if err := restrictProcess(cmd, options); err != nil {
+		return nil, err
+	}
+
+	release, err := limitProcess(cmd, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		stop()
+		release()
+	}, nil
+}
+
+func Run(ctx context.Context, command string, options Options) ([]byte, error) {
+	cmd, err := Command(ctx, options.Shell, command)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &bytes.Buffer{}
+	cmd.Dir, cmd.Stdout, cmd.Stderr = options.Dir, output, output
+	release, err := Prepare(cmd, options)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	if options.Limits != nil && options.Limits.Nice != 0 {
+		if err := setPriority(cmd.Process.Pid, options.Limits.Nice); err != nil {
+			log.Printf("setPriority(): %v", err)
+		}
+	}
+
+	err = cmd.Wait()
+	return output.Bytes(), err
+}
@@ -0,0 +1,95 @@
+//go:build linux
+
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func limitProcess(cmd *exec.Cmd, options Options) (func(), error) {
+	limits := options.Limits
+	if limits == nil || limits.Memory == "" && limits.CPU == 0 {
+		return func() {}, nil
+	}
+
+	controllers := []string{}
+	if limits.Memory != "" {
+		controllers = append(controllers, "memory")
+	}
+	if limits.CPU > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if err := enableControllers(options.CgroupRoot, controllers); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(options.CgroupRoot, "deploy-"+cgroupID())
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return nil, fmt.Errorf("resource limits require a writable cgroup v2 hierarchy: %w", err)
+	}
+
+	files := map[string]string{}
+	if limits.Memory != "" {
+		files["memory.max"] = limits.Memory
+	}
+	if limits.CPU > 0 {
+		files["cpu.max"] = fmt.Sprintf("%d 100000", int(limits.CPU*100000))
+	}
+
+	for name, value := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+			os.Remove(dir)
+			return nil, fmt.Errorf("could not set %s: %w", name, err)
+		}
+	}
+
+	cgroup, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return nil, err
+	}
+
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cgroup.Fd())
+
+	return func() {
+		cgroup.Close()
+		os.Remove(dir)
+	}, nil
+}
+
+// enableControllers delegates the controllers to child cgroups. The write can
+// fail when they are already enabled by someone else, so only a controller
+// that is still missing afterwards is an error.
+func enableControllers(root string, controllers []string) error {
+	path := filepath.Join(root, "cgroup.subtree_control")
+	enable := "+" + strings.Join(controllers, " +")
+	writeErr := os.WriteFile(path, []byte(enable), 0644)
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("resource limits require a writable cgroup v2 hierarchy: %w", err)
+	}
+
+	enabled := strings.Fields(string(body))
+	for _, controller := range controllers {
+		if !slices.Contains(enabled, controller) {
+			return fmt.Errorf("resource limits are not enforced, could not enable the %s controller in %s: %v", controller, root, writeErr)
+		}
+	}
+
+	return nil
+}
+
+func cgroupID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func limitProcess(cmd *exec.Cmd, options Options) (func(), error) {
+	limits := options.Limits
+	if limits != nil && (limits.Memory != "" || limits.CPU != 0) {
+		return nil, errors.New("memory and cpu limits are only supported on linux")
+	}
+
+	return func() {}, nil
+}
@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+)
+
+const (
+	killGrace   = 10 * time.Second
+	defaultPath = "/usr/local/bin:/usr/bin:/bin"
+)
+
+func processEnv(account *user.User, options Options) []string {
+	if !options.restricted() {
+		if len(options.Env) == 0 {
+			return nil
+		}
+		return append(os.Environ(), options.Env...)
+	}
+
+	env := []string{}
+	for _, name := range options.PassEnv {
+		if value, ok := os.LookupEnv(strings.TrimSpace(name)); ok {
+			env = append(env, strings.TrimSpace(name)+"="+value)
+		}
+	}
+
+	path := options.Path
+	if path == "" {
+		path = defaultPath
+	}
+	env = append(env, "PATH="+path)
+
+	if account != nil {
+		env = append(env, "HOME="+account.HomeDir, "USER="+account.Username, "LOGNAME="+account.Username)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		env = append(env, "HOME="+home)
+	}
+
+	return append(env, options.Env...)
+}
+
+func restrictProcess(cmd *exec.Cmd, options Options) error {
+	var account *user.User
+	if options.User != "" {
+		var err error
+		if account, err = user.Lookup(options.User); err != nil {
+			return err
+		}
+
+		if err := runAs(cmd, account); err != nil {
+			return err
+		}
+	}
+
+	cmd.Env = processEnv(account, options)
+	return nil
+}
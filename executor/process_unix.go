@@ -0,0 +1,63 @@
+//go:build unix
+
+package executor
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+func configureProcess(cmd *exec.Cmd) func() {
+	var kill atomic.Pointer[time.Timer]
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		group := -cmd.Process.Pid
+		kill.Store(time.AfterFunc(killGrace, func() { syscall.Kill(group, syscall.SIGKILL) }))
+		return syscall.Kill(group, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = killGrace + time.Second
+
+	return func() {
+		if timer := kill.Load(); timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func runAs(cmd *exec.Cmd, account *user.User) error {
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if ids, err := account.GroupIds(); err == nil {
+		for _, id := range ids {
+			if group, err := strconv.ParseUint(id, 10, 32); err == nil {
+				credential.Groups = append(credential.Groups, uint32(group))
+			}
+		}
+	}
+
+	cmd.SysProcAttr.Credential = credential
+	return nil
+}
+
+func setPriority(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+func Reexec(executable string) error {
+	return syscall.Exec(executable, os.Args, os.Environ())
+}
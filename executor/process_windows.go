@@ -0,0 +1,38 @@
+//go:build windows
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func configureProcess(cmd *exec.Cmd) func() {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+	if strings.EqualFold(strings.TrimSuffix(filepath.Base(cmd.Path), filepath.Ext(cmd.Path)), "cmd") {
+		cmd.SysProcAttr.CmdLine = strings.Join(cmd.Args, " ")
+	}
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+	cmd.WaitDelay = killGrace
+
+	return func() {}
+}
+
+func runAs(cmd *exec.Cmd, account *user.User) error {
+	return errors.New("DEPLOY_USER is not supported on windows")
+}
+
+func setPriority(pid, nice int) error {
+	return errors.New("nice is not supported on windows")
+}
+
+func Reexec(executable string) error {
+	return errors.New("restarting in place is not supported on windows, set SELF_UPDATE_SERVICE")
+}
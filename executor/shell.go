@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+var Shells = map[string][]string{
+	"bash":       {"bash", "-c"},
+	"sh":         {"sh", "-c"},
+	"powershell": {"powershell", "-NoProfile", "-NonInteractive", "-Command"},
+	"pwsh":       {"pwsh", "-NoProfile", "-NonInteractive", "-Command"},
+	"cmd":        {"cmd", "/C"},
+}
+
+func DefaultShell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+
+	if _, err := exec.LookPath("bash"); err != nil {
+		return "sh"
+	}
+
+	return "bash"
+}
+
+func Command(ctx context.Context, shell, command string) (*exec.Cmd, error) {
+	if shell == "" {
+		shell = DefaultShell()
+	}
+
+	args, ok := Shells[shell]
+	if !ok {
+		return nil, fmt.Errorf("unknown shell %s", shell)
+	}
+
+	return exec.CommandContext(ctx, args[0], append(args[1:], command)...), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var exportTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"json": "application/json",
+}
+
+func parsePeriod(period string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(period, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid period %q", period)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period %q", period)
+	}
+	return d, nil
+}
+
+func exportRecords(environment, period, format string) ([]byte, error) {
+	var since time.Time
+	if period != "" {
+		d, err := parsePeriod(period)
+		if err != nil {
+			return nil, err
+		}
+		since = time.Now().Add(-d)
+	}
+
+	var records []*Record
+	store.View(func(state *State) {
+		records = history.Since(state.History, environment, since)
+	})
+
+	var buf bytes.Buffer
+	if err := history.Export(&buf, records, format); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func historyExport(session *discordgo.Session, message *discordgo.MessageCreate, environment *Environment, args []string) {
+	period, format := "", "csv"
+	for _, arg := range args {
+		if _, ok := exportTypes[strings.ToLower(arg)]; ok {
+			format = strings.ToLower(arg)
+			continue
+		}
+		period = arg
+	}
+
+	output, err := exportRecords(environment.Name, period, format)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "export.failed", "error", err))
+		return
+	}
+
+	name := fmt.Sprintf("%s-history-%s.%s", environment.Name, time.Now().UTC().Format("20060102T150405Z"), format)
+	if archiveEnabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		link, err := uploadArchive(ctx, archiveObject("exports/"+name), exportTypes[format], output)
+		if err == nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "export.uploaded", "environment", environment.Name, "name", name, "link", link))
+			return
+		}
+		log.Printf("uploadArchive(): %v", err)
+	}
+
+	session.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{
+		Content: text(message.ChannelID, "export.attached", "environment", environment.Name),
+		Files:   []*discordgo.File{{Name: name, ContentType: exportTypes[format], Reader: bytes.NewReader(output)}},
+	})
+}
+
+func debugHistoryExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	if _, ok := exportTypes[format]; !ok {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	environment := r.URL.Query().Get("environment")
+	if !scopeAllowed(r.Context(), "read", environment) {
+		http.Error(w, "token is not scoped to read this environment", http.StatusForbidden)
+		return
+	}
+
+	output, err := exportRecords(environment, r.URL.Query().Get("period"), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", exportTypes[format])
+	w.Header().Set("Content-Disposition", "attachment; filename=history."+format)
+	w.Write(output)
+}
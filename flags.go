@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"deploy/engine"
+)
+
+// flagProviders toggle a flag and report whether it was on beforehand.
+var flagProviders = map[string]func(ctx context.Context, with map[string]string, enabled bool, authorize func(*http.Request)) (bool, error){
+	"launchdarkly": toggleLaunchDarkly,
+	"unleash":      toggleUnleash,
+	"flagsmith":    toggleFlagsmith,
+}
+
+func runFlag(ctx context.Context, deployment *Deployment, request *engine.Request, step *Step, output *bytes.Buffer) error {
+	with := map[string]string{}
+	for key, value := range step.With {
+		with[key] = request.Expand(value)
+	}
+
+	toggle, ok := flagProviders[with["provider"]]
+	if !ok {
+		return fmt.Errorf("unknown flag provider %q", with["provider"])
+	}
+
+	if with["flag"] == "" || with["environment"] == "" {
+		return errors.New("flag steps need a flag and an environment")
+	}
+
+	var enabled bool
+	switch with["state"] {
+	case "on":
+		enabled = true
+	case "off":
+	default:
+		return fmt.Errorf("flag state must be on or off, got %q", with["state"])
+	}
+
+	token := secret(with["token"], os.Getenv(with["token"]))
+	if token == "" {
+		return fmt.Errorf("flag token %s is not set", with["token"])
+	}
+
+	authorize := func(req *http.Request) {
+		if with["provider"] == "flagsmith" {
+			req.Header.Set("Authorization", "Token "+token)
+			return
+		}
+		req.Header.Set("Authorization", token)
+	}
+
+	previous, err := toggle(ctx, with, enabled, authorize)
+	detail := map[string]string{"provider": with["provider"], "flag": with["flag"], "flag_environment": with["environment"], "new": with["state"]}
+	if err != nil {
+		detail["error"] = err.Error()
+		deployment.audit("flag", "failed", nil, detail)
+		return fmt.Errorf("%s %s: %w", with["provider"], with["flag"], err)
+	}
+	detail["old"] = flagState(previous)
+	deployment.audit("flag", "changed", nil, detail)
+
+	change := fmt.Sprintf("%s:%s=%s (%s)", with["provider"], with["flag"], with["state"], with["environment"])
+	deployment.Flags = append(deployment.Flags, change)
+	fmt.Fprintf(output, "Turned %s %s in %s on %s\n", with["state"], with["flag"], with["environment"], with["provider"])
+	return nil
+}
+
+func flagState(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+func toggleLaunchDarkly(ctx context.Context, with map[string]string, enabled bool, authorize func(*http.Request)) (bool, error) {
+	project := with["project"]
+	if project == "" {
+		project = "default"
+	}
+
+	target := fmt.Sprintf("https://app.launchdarkly.com/api/v2/flags/%s/%s", url.PathEscape(project), url.PathEscape(with["flag"]))
+	current := struct {
+		Environments map[string]struct {
+			On bool `json:"on"`
+		} `json:"environments"`
+	}{}
+	if _, err := apiRequest(ctx, http.MethodGet, target+"?env="+url.QueryEscape(with["environment"]), nil, authorize, &current); err != nil {
+		return false, err
+	}
+	previous := current.Environments[with["environment"]].On
+
+	kind := "turnFlagOff"
+	if enabled {
+		kind = "turnFlagOn"
+	}
+
+	body := map[string]any{"environmentKey": with["environment"], "comment": "deploy bot", "instructions": []map[string]string{{"kind": kind}}}
+	_, err := apiRequest(ctx, http.MethodPatch, target, body, func(req *http.Request) {
+		authorize(req)
+		req.Header.Set("Content-Type", "application/json; domain-model=launchdarkly.semanticpatch")
+	}, nil)
+	return previous, err
+}
+
+func toggleUnleash(ctx context.Context, with map[string]string, enabled bool, authorize func(*http.Request)) (bool, error) {
+	base := strings.TrimSuffix(with["url"], "/")
+	if base == "" {
+		return false, errors.New("unleash flags need a url")
+	}
+
+	project := with["project"]
+	if project == "" {
+		project = "default"
+	}
+
+	feature := fmt.Sprintf("%s/api/admin/projects/%s/features/%s", base, url.PathEscape(project), url.PathEscape(with["flag"]))
+	current := struct {
+		Environments []struct {
+			Name    string `json:"name"`
+			Enabled bool   `json:"enabled"`
+		} `json:"environments"`
+	}{}
+	if _, err := apiRequest(ctx, http.MethodGet, feature, nil, authorize, &current); err != nil {
+		return false, err
+	}
+
+	previous := false
+	for _, environment := range current.Environments {
+		if environment.Name == with["environment"] {
+			previous = environment.Enabled
+		}
+	}
+
+	state := "off"
+	if enabled {
+		state = "on"
+	}
+
+	target := fmt.Sprintf("%s/environments/%s/%s", feature, url.PathEscape(with["environment"]), state)
+	_, err := apiRequest(ctx, http.MethodPost, target, nil, authorize, nil)
+	return previous, err
+}
+
+func toggleFlagsmith(ctx context.Context, with map[string]string, enabled bool, authorize func(*http.Request)) (bool, error) {
+	base := strings.TrimSuffix(with["url"], "/")
+	if base == "" {
+		base = "https://api.flagsmith.com"
+	}
+
+	states := struct {
+		Results []struct {
+			ID      int  `json:"id"`
+			Enabled bool `json:"enabled"`
+		} `json:"results"`
+	}{}
+	target := fmt.Sprintf("%s/api/v1/environments/%s/featurestates/", base, url.PathEscape(with["environment"]))
+	if _, err := apiRequest(ctx, http.MethodGet, target+"?feature_name="+url.QueryEscape(with["flag"]), nil, authorize, &states); err != nil {
+		return false, err
+	}
+
+	if len(states.Results) == 0 {
+		return false, errors.New("feature not found")
+	}
+
+	_, err := apiRequest(ctx, http.MethodPatch, fmt.Sprintf("%s%d/", target, states.Results[0].ID), map[string]bool{"enabled": enabled}, authorize, nil)
+	return states.Results[0].Enabled, err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var forumThreads sync.Map
+
+func rememberForumThread(thread *discordgo.Channel) {
+	for _, environment := range Environments {
+		if environment.Forum != "" && environment.Forum == thread.ParentID && strings.HasPrefix(thread.Name, environment.Name+"/") {
+			forumThreads.Store(thread.ID, environment.Name)
+			return
+		}
+	}
+}
+
+func forumEnvironment(threadID string) *Environment {
+	name, ok := forumThreads.Load(threadID)
+	if !ok {
+		return nil
+	}
+	return Environments[name.(string)]
+}
+
+func threadDelete(session *discordgo.Session, thread *discordgo.ThreadDelete) {
+	forumThreads.Delete(thread.ID)
+}
+
+func forumTags(session *discordgo.Session, forum string, names ...string) []string {
+	channel, err := session.State.Channel(forum)
+	if err != nil {
+		if channel, err = session.Channel(forum); err != nil {
+			log.Printf("session.Channel(): %v", err)
+			return nil
+		}
+	}
+
+	tags := []string{}
+	for _, tag := range channel.AvailableTags {
+		if slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, tag.Name) }) {
+			tags = append(tags, tag.ID)
+		}
+	}
+	return tags
+}
+
+func startForumPost(session *discordgo.Session, channelID string, deployment *Deployment) (*discordgo.Message, error) {
+	forum := deployment.Environment.Forum
+	thread, err := session.ForumThreadStartComplex(forum, &discordgo.ThreadStart{
+		Name:        truncate(fmt.Sprintf("%s/%s/%s", deployment.Environment.Name, deployment.Key, deployment.Branch), 100),
+		AppliedTags: forumTags(session, forum, deployment.Environment.Name, "running"),
+	}, &discordgo.MessageSend{Content: deployment.text(channelID, "deploy.ongoing")})
+	if err != nil {
+		return nil, err
+	}
+
+	deployment.thread = thread.ID
+	rememberChannel(thread.ID, thread.GuildID)
+	forumThreads.Store(thread.ID, deployment.Environment.Name)
+	if channelID != forum {
+		session.ChannelMessageSend(channelID, deployment.text(channelID, "deploy.started_thread", "thread", thread.ID))
+	}
+
+	return &discordgo.Message{ID: thread.ID, ChannelID: thread.ID}, nil
+}
+
+func (deployment *Deployment) tagForumPost(session *discordgo.Session, status string) {
+	if deployment.thread == "" {
+		return
+	}
+
+	tags := forumTags(session, deployment.Environment.Forum, deployment.Environment.Name, status)
+	if _, err := session.ChannelEdit(deployment.thread, &discordgo.ChannelEdit{AppliedTags: &tags}); err != nil {
+		log.Printf("session.ChannelEdit(): %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func TestForumThreadEnvironment(t *testing.T) {
+	production := &Environment{Name: "production", Channel: "channel", Forum: "forum"}
+	Environments = map[string]*Environment{"production": production, "staging": {Name: "staging", Channel: "staging", Forum: "forum"}}
+	environmentChannels = map[string]*Environment{"channel": production}
+
+	rememberForumThread(&discordgo.Channel{ID: "thread", ParentID: "forum", Name: "production/api/main"})
+	rememberForumThread(&discordgo.Channel{ID: "other", ParentID: "elsewhere", Name: "production/api/main"})
+
+	tests := []struct {
+		channel string
+		want    *Environment
+	}{
+		{"channel", production},
+		{"thread", production},
+		{"other", nil},
+		{"unknown", nil},
+	}
+
+	for _, test := range tests {
+		if got := environmentByChannel(test.channel); got != test.want {
+			t.Errorf("environmentByChannel(%s) = %v, want %v", test.channel, got, test.want)
+		}
+	}
+
+	threadDelete(nil, &discordgo.ThreadDelete{Channel: &discordgo.Channel{ID: "thread"}})
+	if got := environmentByChannel("thread"); got != nil {
+		t.Errorf("environmentByChannel(thread) = %v after the thread was deleted", got)
+	}
+}
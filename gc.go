@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type Retention struct {
+	History  time.Duration
+	Releases int
+	Logs     int64
+	Interval time.Duration
+}
+
+type Pruned struct {
+	Records  int
+	Logs     int
+	Freed    int64
+	Releases int
+}
+
+var retention Retention
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+
+	return time.ParseDuration(value)
+}
+
+func parseSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	for _, unit := range sizeUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			return int64(n * float64(unit.bytes)), err
+		}
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func formatSize(bytes int64) string {
+	for _, unit := range sizeUnits[:4] {
+		if bytes >= unit.bytes {
+			return fmt.Sprintf("%.1f %s", float64(bytes)/float64(unit.bytes), unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%d B", bytes)
+}
+
+func parseRetention(history, releases, logs, interval string) (Retention, error) {
+	var policy Retention
+	var err error
+	if policy.History, err = parseAge(history); err != nil || policy.History < 0 {
+		return policy, fmt.Errorf("invalid RETENTION_HISTORY %q, expected a duration such as 90d or 0 to keep everything", history)
+	}
+
+	if policy.Releases, err = strconv.Atoi(releases); err != nil || policy.Releases < 0 {
+		return policy, fmt.Errorf("invalid RETENTION_RELEASES %q, expected a number or 0 to keep everything", releases)
+	}
+
+	if policy.Logs, err = parseSize(logs); err != nil || policy.Logs < 0 {
+		return policy, fmt.Errorf("invalid RETENTION_LOGS %q, expected a size such as 5GB or 0 to keep everything", logs)
+	}
+
+	if interval != "" {
+		if policy.Interval, err = time.ParseDuration(interval); err != nil || policy.Interval < time.Minute {
+			return policy, fmt.Errorf("invalid RETENTION_INTERVAL %q, expected a duration of at least 1m", interval)
+		}
+	}
+
+	return policy, nil
+}
+
+func collectGarbage() (*Pruned, error) {
+	pruned := &Pruned{}
+
+	if retention.History > 0 {
+		cutoff, expired := time.Now().Add(-retention.History), []string{}
+		err := store.Update(func(state *State) {
+			latest := map[string]bool{}
+			kept := []*Record{}
+			for _, record := range slices.Backward(state.History) {
+				last := record.Status == "success" && !latest[record.Environment+"/"+record.Key]
+				if last {
+					latest[record.Environment+"/"+record.Key] = true
+				}
+
+				if record.Started.Before(cutoff) && !last {
+					expired = append(expired, record.ID)
+					continue
+				}
+				kept = append(kept, record)
+			}
+
+			slices.Reverse(kept)
+			state.History = kept
+		})
+		if err != nil {
+			return pruned, err
+		}
+
+		pruned.Records = len(expired)
+		for _, id := range expired {
+			freed, err := deploymentLogs.Remove(id)
+			if err != nil {
+				log.Printf("deploymentLogs.Remove(): %v", err)
+				continue
+			}
+			if freed > 0 {
+				pruned.Logs, pruned.Freed = pruned.Logs+1, pruned.Freed+freed
+			}
+		}
+	}
+
+	if retention.Logs > 0 {
+		removed, freed, err := deploymentLogs.Prune(retention.Logs)
+		pruned.Logs, pruned.Freed = pruned.Logs+removed, pruned.Freed+freed
+		if err != nil {
+			return pruned, err
+		}
+	}
+
+	if retention.Releases > 0 {
+		for _, name := range slices.Sorted(maps.Keys(Environments)) {
+			environment := Environments[name]
+			if _, err := os.Stat(releasesDir(environment)); err != nil {
+				continue
+			}
+
+			count, err := pruneReleases(environment, retention.Releases)
+			pruned.Releases += count
+			if err != nil {
+				return pruned, err
+			}
+		}
+	}
+
+	return pruned, nil
+}
+
+func scheduleGC(interval time.Duration) {
+	for range time.Tick(interval) {
+		pruned, err := collectGarbage()
+		if err != nil {
+			log.Printf("collectGarbage(): %v", err)
+			continue
+		}
+		log.Printf("Garbage collected %d record(s), %d log(s) (%s) and %d release(s)", pruned.Records, pruned.Logs, formatSize(pruned.Freed), pruned.Releases)
+	}
+}
+
+func adminGC(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	pruned, err := collectGarbage()
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "gc.failed", "error", err.Error()))
+		return
+	}
+
+	log.Printf("Garbage collected %d record(s), %d log(s) (%s) and %d release(s). Username: %s (%s)", pruned.Records, pruned.Logs, formatSize(pruned.Freed), pruned.Releases, message.Author.Username, message.Author.ID)
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "gc.finished", "records", pruned.Records, "logs", pruned.Logs, "freed", formatSize(pruned.Freed), "releases", pruned.Releases))
+}
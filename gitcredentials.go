@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"deploy/engine"
+)
+
+const credentialHelper = `!f() { test "$1" = get && printf 'username=%s\npassword=%s\n' "$DEPLOY_GIT_USERNAME" "$DEPLOY_GIT_TOKEN"; }; f`
+
+type GitCredentials struct {
+	SSHKey       string `json:"ssh_key"`
+	SSHKeySecret string `json:"ssh_key_secret"`
+	KnownHosts   string `json:"known_hosts"`
+	Username     string `json:"username"`
+	Token        string `json:"token"`
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func writeDeployKey(material string) (string, error) {
+	file, err := os.CreateTemp("", "deploy-key-*")
+	if err != nil {
+		return "", fmt.Errorf("os.CreateTemp(): %w", err)
+	}
+	defer file.Close()
+
+	if !strings.HasSuffix(material, "\n") {
+		material += "\n"
+	}
+
+	if _, err := file.WriteString(material); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("file.WriteString(): %w", err)
+	}
+
+	if data.DeployUser != "" {
+		account, err := user.Lookup(data.DeployUser)
+		if err != nil {
+			os.Remove(file.Name())
+			return "", err
+		}
+
+		uid, _ := strconv.Atoi(account.Uid)
+		gid, _ := strconv.Atoi(account.Gid)
+		if err := file.Chown(uid, gid); err != nil {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("file.Chown(): %w", err)
+		}
+	}
+
+	return file.Name(), nil
+}
+
+func gitCredentials(environment *Environment) ([]string, func(), error) {
+	credentials := environment.Git
+	if credentials == nil {
+		return nil, func() {}, nil
+	}
+
+	env, cleanup := []string{}, func() {}
+
+	key := credentials.SSHKey
+	if credentials.SSHKeySecret != "" {
+		material := secret(credentials.SSHKeySecret, os.Getenv(credentials.SSHKeySecret))
+		if material == "" {
+			return nil, nil, fmt.Errorf("deploy key secret %s is empty", credentials.SSHKeySecret)
+		}
+
+		path, err := writeDeployKey(material)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, cleanup = path, func() { os.Remove(path) }
+	}
+
+	if key != "" {
+		command := "ssh -i " + shellQuote(key) + " -o IdentitiesOnly=yes -o BatchMode=yes"
+		if credentials.KnownHosts != "" {
+			command += " -o StrictHostKeyChecking=yes -o UserKnownHostsFile=" + shellQuote(credentials.KnownHosts)
+		}
+		env = append(env, "GIT_SSH_COMMAND="+command)
+	}
+
+	if credentials.Token != "" {
+		token := secret(credentials.Token, os.Getenv(credentials.Token))
+		if token == "" {
+			cleanup()
+			return nil, nil, fmt.Errorf("git token secret %s is empty", credentials.Token)
+		}
+
+		username := credentials.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+
+		env = append(env,
+			"GIT_TERMINAL_PROMPT=0",
+			"GIT_CONFIG_COUNT=2",
+			"GIT_CONFIG_KEY_0=credential.helper",
+			"GIT_CONFIG_VALUE_0=",
+			"GIT_CONFIG_KEY_1=credential.helper",
+			"GIT_CONFIG_VALUE_1="+credentialHelper,
+			"DEPLOY_GIT_USERNAME="+username,
+			"DEPLOY_GIT_TOKEN="+token,
+		)
+	}
+
+	return env, cleanup, nil
+}
+
+func gitHint(channelID string, err error) string {
+	switch {
+	case errors.Is(err, engine.ErrGitAuth):
+		return text(channelID, "git.hint_auth")
+	case errors.Is(err, engine.ErrGitMissingRef):
+		return text(channelID, "git.hint_missing_ref")
+	case errors.Is(err, engine.ErrGitDirty):
+		return text(channelID, "git.hint_dirty")
+	case errors.Is(err, engine.ErrGitNetwork):
+		return text(channelID, "git.hint_network")
+	}
+	return ""
+}
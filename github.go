@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type GithubAsset struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Size   int64  `json:"size"`
+	Digest string `json:"digest"`
+}
+
+type GithubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []GithubAsset `json:"assets"`
+}
+
+func (release *GithubRelease) Asset(name string) (*GithubAsset, bool) {
+	for i := range release.Assets {
+		if release.Assets[i].Name == name {
+			return &release.Assets[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func githubRequest(ctx context.Context, url, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if data.GithubToken != "" {
+		req.Header.Set("Authorization", "Bearer "+data.GithubToken)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http.Do(): %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("github: %s returned %s", url, res.Status)
+	}
+
+	return res, nil
+}
+
+func getGithubRelease(ctx context.Context, repository, tag string) (*GithubRelease, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/tags/%s", repository, tag)
+	if tag == "latest" {
+		url = fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repository)
+	}
+
+	res, err := githubRequest(ctx, url, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	release := &GithubRelease{}
+	if err := json.NewDecoder(res.Body).Decode(release); err != nil {
+		return nil, fmt.Errorf("json.Decode(): %w", err)
+	}
+
+	return release, nil
+}
+
+func downloadGithubAsset(ctx context.Context, asset *GithubAsset, path string) (string, error) {
+	res, err := githubRequest(ctx, asset.URL, "application/octet-stream")
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("os.Create(): %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), res.Body); err != nil {
+		return "", fmt.Errorf("io.Copy(): %w", err)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if digest, ok := strings.CutPrefix(asset.Digest, "sha256:"); ok && digest != sum {
+		return "", fmt.Errorf("%w: digest mismatch for %s: expected %s, got %s", errVerification, asset.Name, digest, sum)
+	}
+
+	return sum, nil
+}
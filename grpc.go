@@ -0,0 +1,271 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/api"
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const logChunkSize = 32 << 10
+
+type deployServer struct {
+	api.UnimplementedDeployServiceServer
+
+	session *discordgo.Session
+}
+
+func grpcAuthorized(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if data.GRPCToken != "" && subtle.ConstantTimeCompare([]byte(value), []byte("Bearer "+data.GRPCToken)) == 1 {
+			return ctx, nil
+		}
+
+		if raw, ok := strings.CutPrefix(value, "Bearer "); ok {
+			if token := authenticateToken(raw); token != nil {
+				return withToken(ctx, token), nil
+			}
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+}
+
+type grpcStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (stream *grpcStream) Context() context.Context {
+	return stream.ctx
+}
+
+func (server *deployServer) RunDeployment(ctx context.Context, req *api.RunDeploymentRequest) (*api.RunDeploymentResponse, error) {
+	environment, ok := Environments[req.Environment]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown environment %q", req.Environment)
+	}
+
+	if !scopeAllowed(ctx, "deploy", environment.Name) {
+		return nil, status.Errorf(codes.PermissionDenied, "token is not scoped to deploy to %q", environment.Name)
+	}
+
+	if entry, ok := Commands[req.Key]; ok && entry.TOTP {
+		return nil, status.Errorf(codes.FailedPrecondition, "key %q requires a TOTP code and can only be deployed from Discord", req.Key)
+	}
+
+	key := ""
+	if req.IdempotencyKey != "" {
+		caller := "shared"
+		if token := tokenFrom(ctx); token != nil {
+			caller = token.ID
+		}
+
+		key = "grpc:" + caller + ":" + req.IdempotencyKey
+		existing, err := reserveIdempotency(key)
+		if err != nil {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		if existing != nil {
+			return &api.RunDeploymentResponse{Id: existing.Deployment, Status: deploymentStatus(existing.Deployment), Duplicate: true}, nil
+		}
+	}
+
+	args := []string{req.Ref, req.Key}
+	if req.Incident != "" {
+		args = append(args, "--incident="+req.Incident)
+	}
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		args = append(args, reason)
+	}
+
+	credential := ""
+	if token := tokenFrom(ctx); token != nil {
+		credential = "token:" + token.ID
+	}
+
+	deployment, _, err := newAPIDeployment(environment, server.session.State.User, credential, args)
+	if key != "" {
+		completeIdempotency(key, deployment)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	log.Printf("Deploying %s@%s to %s via gRPC (%s)", deployment.Key, deployment.Branch, environment.Name, cmp.Or(credential, "unauthenticated"))
+	startDeployment(server.session, environment.Channel, deployment)
+	return &api.RunDeploymentResponse{Id: deployment.ID, RefType: deployment.RefType, Sha: deployment.SHA, Status: "started"}, nil
+}
+
+func activeDeployment(id string) (*Deployment, bool) {
+	running := scheduler.Running()
+	if i := slices.IndexFunc(running, func(deployment *Deployment) bool { return deployment.ID == id }); i >= 0 {
+		return running[i], true
+	}
+
+	queued := scheduler.Queued()
+	if i := slices.IndexFunc(queued, func(deployment Deployment) bool { return deployment.ID == id }); i >= 0 {
+		return &queued[i], true
+	}
+
+	return nil, false
+}
+
+func (server *deployServer) StreamLogs(req *api.StreamLogsRequest, stream grpc.ServerStreamingServer[api.LogChunk]) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		deployment, ok := activeDeployment(req.Id)
+		if !ok {
+			break
+		}
+
+		if !scopeAllowed(stream.Context(), "read", deployment.Environment.Name) {
+			return status.Errorf(codes.NotFound, "no deployment %q", req.Id)
+		}
+
+		if deployment.Progress != nil {
+			if current := strings.ReplaceAll(deployment.Progress.Render(), "`", ""); current != last {
+				if err := stream.Send(&api.LogChunk{Progress: current}); err != nil {
+					return err
+				}
+				last = current
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+	}
+
+	var record *Record
+	store.View(func(state *State) {
+		for _, candidate := range slices.Backward(state.History) {
+			if candidate.ID == req.Id {
+				record = candidate
+				return
+			}
+		}
+	})
+
+	if record == nil || !scopeAllowed(stream.Context(), "read", record.Environment) {
+		return status.Errorf(codes.NotFound, "no deployment %q", req.Id)
+	}
+
+	output, err := deploymentLogs.Read(record.ID)
+	if err != nil && !errors.Is(err, history.ErrNoLog) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for start := 0; start < len(output); start += logChunkSize {
+		if err := stream.Send(&api.LogChunk{Output: output[start:min(start+logChunkSize, len(output))]}); err != nil {
+			return err
+		}
+	}
+
+	return stream.Send(&api.LogChunk{Status: record.Status})
+}
+
+func (server *deployServer) ListHistory(ctx context.Context, req *api.ListHistoryRequest) (*api.ListHistoryResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	if !scopeAllowed(ctx, "read", req.Environment) && req.Environment == "" {
+		return nil, status.Error(codes.PermissionDenied, "token is not scoped to read every environment, set one")
+	} else if !scopeAllowed(ctx, "read", req.Environment) {
+		return nil, status.Errorf(codes.PermissionDenied, "token is not scoped to read %q", req.Environment)
+	}
+
+	var since time.Time
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+
+	response := &api.ListHistoryResponse{}
+	store.View(func(state *State) {
+		for _, record := range history.Recent(history.Since(state.History, req.Environment, since), "", -1) {
+			switch {
+			case req.Status != "" && record.Status != req.Status:
+			case req.Key != "" && record.Key != req.Key:
+			case req.Incident != "" && record.Incident != req.Incident:
+			default:
+				response.Deployments = append(response.Deployments, &api.Deployment{
+					Id:          record.ID,
+					Environment: record.Environment,
+					Key:         record.Key,
+					Ref:         record.Ref,
+					RefType:     record.RefType,
+					Sha:         record.SHA,
+					Author:      record.Author,
+					Username:    record.Username,
+					Status:      record.Status,
+					Error:       record.Error,
+					Reason:      record.Reason,
+					Ticket:      record.Ticket,
+					LockedBy:    record.LockedBy,
+					Flags:       record.Flags,
+					Incident:    record.Incident,
+					Started:     timestamppb.New(record.Started),
+					Duration:    durationpb.New(record.Duration),
+				})
+			}
+
+			if len(response.Deployments) == limit {
+				return
+			}
+		}
+	})
+
+	return response, nil
+}
+
+func serveGRPC(session *discordgo.Session, addr string) error {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := grpcAuthorized(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := grpcAuthorized(stream.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &grpcStream{ServerStream: stream, ctx: ctx})
+		}),
+	)
+	api.RegisterDeployServiceServer(server, &deployServer{session: session})
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("gRPC endpoint listening on %s", listener.Addr())
+	go server.Serve(listener)
+	return nil
+}
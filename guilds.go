@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var allowedGuilds map[string]bool
+
+func parseAllowedGuilds(value string) map[string]bool {
+	guilds := map[string]bool{}
+	for guild := range strings.SplitSeq(value, ",") {
+		if guild = strings.TrimSpace(guild); guild != "" {
+			guilds[guild] = true
+		}
+	}
+	return guilds
+}
+
+func guildAllowed(guildID string) bool {
+	return len(allowedGuilds) == 0 || allowedGuilds[guildID]
+}
+
+func guildCreate(session *discordgo.Session, guild *discordgo.GuildCreate) {
+	if guildAllowed(guild.ID) {
+		for _, channel := range slices.Concat(guild.Channels, guild.Threads) {
+			rememberChannel(channel.ID, guild.ID)
+		}
+		for _, thread := range guild.Threads {
+			rememberForumThread(thread)
+		}
+		return
+	}
+
+	log.Printf("Leaving guild %s (%s), it is not in ALLOWED_GUILDS", guild.Name, guild.ID)
+	if err := session.GuildLeave(guild.ID); err != nil {
+		log.Printf("session.GuildLeave(): %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func checkHealth(ctx context.Context, url string, attempts int, interval time.Duration) (err error) {
+	ctx, span := tracer.Start(ctx, "health "+url)
+	defer func() { endSpan(span, err) }()
+
+	for attempt := range attempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		if err = probe(ctx, url, 0); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("health check %s: %w", url, err)
+}
+
+func probe(ctx context.Context, url string, status int) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http.Do(): %w", err)
+	}
+	res.Body.Close()
+
+	if (status == 0 && (res.StatusCode < 200 || res.StatusCode > 299)) || (status != 0 && res.StatusCode != status) {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return nil
+}
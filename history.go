@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type (
+	Record = history.Record
+	Note   = history.Note
+)
+
+func recordDeployment(deployment *Deployment, status string, err error) *Record {
+	record := &Record{
+		ID:          deployment.ID,
+		Environment: deployment.Environment.Name,
+		Key:         deployment.Key,
+		Ref:         deployment.Branch,
+		RefType:     deployment.RefType,
+		SHA:         deployment.SHA,
+		Author:      deployment.Author.ID,
+		Username:    deployment.Author.Username,
+		Status:      status,
+		Reason:      deployment.Reason,
+		Ticket:      deployment.Ticket,
+		LockedBy:    deployment.LockedBy,
+		Flags:       deployment.Flags,
+		Incident:    deployment.Incident,
+		Slot:        deployment.Slot,
+		Started:     deployment.Started.UTC(),
+		Duration:    time.Since(deployment.Started).Round(time.Second),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	store.Update(func(state *State) {
+		state.History = append(state.History, record)
+		if status != "failed" && record.SHA != "" {
+			state.Deployed[record.Environment] = record.SHA
+		}
+	})
+	return record
+}
+
+func deploymentHistory(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+	if len(args) > 0 && strings.EqualFold(args[0], "export") {
+		historyExport(session, message, environment, args[1:])
+		return
+	}
+
+	incidents, incident := false, ""
+	if len(args) > 0 && (args[0] == "--incident" || strings.HasPrefix(args[0], "--incident=")) {
+		incidents, incident = true, strings.TrimPrefix(strings.TrimPrefix(args[0], "--incident"), "=")
+		if args = args[1:]; incident == "" && len(args) > 0 && incidentPattern.MatchString(args[0]) {
+			if _, err := strconv.Atoi(args[0]); err != nil {
+				incident, args = args[0], args[1:]
+			}
+		}
+	}
+
+	count := 10
+	if len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil && n > 0 && n <= 50 {
+			count = n
+		}
+	}
+
+	lines := []string{}
+	store.View(func(state *State) {
+		records := state.History
+		if incidents {
+			records = history.Incidents(records, incident)
+		}
+
+		for _, record := range history.Recent(records, environment.Name, count) {
+			lines = append(lines, fmt.Sprintf("%s %s %-8s %-12s %-20s %.7s %s %s %s", record.ID, record.Started.Format("2006-01-02 15:04"), record.Status, record.Key, record.Ref, record.SHA, record.Username, record.Ticket, record.Incident))
+			for _, note := range record.Notes {
+				lines = append(lines, fmt.Sprintf("    ↳ %s: %s", note.Username, truncate(strings.ReplaceAll(note.Text, "`", "'"), 120)))
+			}
+		}
+	})
+
+	if len(lines) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "history.none"))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, "```\n"+strings.Join(lines, "\n")+"\n```")
+}
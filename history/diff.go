@@ -0,0 +1,139 @@
+package history
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var normalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`), "<time>"},
+	{regexp.MustCompile(`\b\d+(\.\d+)?(ns|µs|us|ms|s|m|h)\b`), "<duration>"},
+	{regexp.MustCompile(`\b[0-9a-f]{7,64}\b`), "<hash>"},
+	{regexp.MustCompile(`/tmp/[^\s'"]+`), "<tmp>"},
+}
+
+var (
+	warningPattern = regexp.MustCompile(`(?i)\b(warn|warning|deprecated|deprecation)\b`)
+	versionPattern = regexp.MustCompile(`([A-Za-z0-9_./@-]*[A-Za-z][A-Za-z0-9_./-]*)(?:@|==| v| \(v?)(\d+\.\d+[0-9A-Za-z.+-]*)`)
+)
+
+type Change struct {
+	Step  string
+	Added bool
+	Line  string
+}
+
+type Version struct {
+	Name string
+	From string
+	To   string
+}
+
+type LogDiff struct {
+	Changes  []Change
+	Warnings []string
+	Versions []Version
+}
+
+func Normalize(output []byte) []byte {
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	for i, line := range lines {
+		for _, normalizer := range normalizers {
+			line = normalizer.pattern.ReplaceAllString(line, normalizer.replacement)
+		}
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+type logLine struct {
+	step string
+	text string
+}
+
+func logLines(output []byte) []logLine {
+	lines, step := []logLine{}, ""
+	for line := range strings.Lines(string(output)) {
+		line = strings.TrimRight(line, "\n")
+		if strings.HasPrefix(line, "==> ") {
+			step = strings.TrimPrefix(line, "==> ")
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, logLine{step: step, text: line})
+		}
+	}
+
+	return lines
+}
+
+func unmatched(lines, other []logLine) []logLine {
+	counts := map[logLine]int{}
+	for _, line := range other {
+		counts[line]++
+	}
+
+	rest := []logLine{}
+	for _, line := range lines {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		rest = append(rest, line)
+	}
+
+	return rest
+}
+
+func versions(lines []logLine) map[string]string {
+	found := map[string]string{}
+	for _, line := range lines {
+		for _, match := range versionPattern.FindAllStringSubmatch(line.text, -1) {
+			found[match[1]] = match[2]
+		}
+	}
+
+	return found
+}
+
+func Diff(before, after []byte) *LogDiff {
+	a, b := logLines(before), logLines(after)
+	removed, added := unmatched(a, b), unmatched(b, a)
+
+	steps := []string{}
+	for _, line := range slices.Concat(b, a) {
+		if !slices.Contains(steps, line.step) {
+			steps = append(steps, line.step)
+		}
+	}
+
+	diff := &LogDiff{}
+	for _, line := range removed {
+		diff.Changes = append(diff.Changes, Change{Step: line.step, Line: line.text})
+	}
+	for _, line := range added {
+		diff.Changes = append(diff.Changes, Change{Step: line.step, Added: true, Line: line.text})
+		if warningPattern.MatchString(line.text) {
+			diff.Warnings = append(diff.Warnings, line.text)
+		}
+	}
+	slices.SortStableFunc(diff.Changes, func(x, y Change) int { return slices.Index(steps, x.Step) - slices.Index(steps, y.Step) })
+
+	old := versions(removed)
+	for _, line := range added {
+		for _, match := range versionPattern.FindAllStringSubmatch(line.text, -1) {
+			if from, ok := old[match[1]]; ok && from != match[2] {
+				diff.Versions = append(diff.Versions, Version{Name: match[1], From: from, To: match[2]})
+				delete(old, match[1])
+			}
+		}
+	}
+
+	return diff
+}
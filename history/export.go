@@ -0,0 +1,75 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var columns = []string{"id", "environment", "key", "ref", "ref_type", "sha", "author", "username", "status", "error", "reason", "ticket", "locked_by", "flags", "incident", "started", "duration", "notes"}
+
+func Since(records []*Record, environment string, since time.Time) []*Record {
+	matched := []*Record{}
+	for _, record := range records {
+		if environment != "" && record.Environment != environment {
+			continue
+		}
+
+		if record.Started.Before(since) {
+			continue
+		}
+		matched = append(matched, record)
+	}
+
+	return matched
+}
+
+func Export(w io.Writer, records []*Record, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write(columns)
+		for _, record := range records {
+			writer.Write([]string{
+				record.ID,
+				record.Environment,
+				record.Key,
+				record.Ref,
+				record.RefType,
+				record.SHA,
+				record.Author,
+				record.Username,
+				record.Status,
+				record.Error,
+				record.Reason,
+				record.Ticket,
+				record.LockedBy,
+				strings.Join(record.Flags, ";"),
+				record.Incident,
+				record.Started.UTC().Format(time.RFC3339),
+				fmt.Sprintf("%d", int64(record.Duration.Seconds())),
+				notes(record),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+func notes(record *Record) string {
+	texts := []string{}
+	for _, note := range record.Notes {
+		texts = append(texts, note.Username+": "+note.Text)
+	}
+
+	return strings.Join(texts, ";")
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"slices"
+	"time"
+)
+
+type Record struct {
+	ID          string        `json:"id"`
+	Environment string        `json:"environment"`
+	Key         string        `json:"key"`
+	Ref         string        `json:"ref"`
+	RefType     string        `json:"ref_type"`
+	SHA         string        `json:"sha"`
+	Author      string        `json:"author"`
+	Username    string        `json:"username"`
+	Status      string        `json:"status"`
+	Error       string        `json:"error,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+	Ticket      string        `json:"ticket,omitempty"`
+	LockedBy    string        `json:"locked_by,omitempty"`
+	Flags       []string      `json:"flags,omitempty"`
+	Incident    string        `json:"incident,omitempty"`
+	Slot        string        `json:"slot,omitempty"`
+	Started     time.Time     `json:"started"`
+	Duration    time.Duration `json:"duration"`
+	Notes       []*Note       `json:"notes,omitempty"`
+}
+
+type Note struct {
+	Author   string    `json:"author"`
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	Created  time.Time `json:"created"`
+}
+
+func Recent(records []*Record, environment string, count int) []*Record {
+	recent := []*Record{}
+	for _, record := range slices.Backward(records) {
+		if environment != "" && record.Environment != environment {
+			continue
+		}
+
+		if recent = append(recent, record); len(recent) == count {
+			break
+		}
+	}
+
+	return recent
+}
+
+func Incidents(records []*Record, incident string) []*Record {
+	return slices.DeleteFunc(slices.Clone(records), func(record *Record) bool {
+		return record.Incident == "" || incident != "" && record.Incident != incident
+	})
+}
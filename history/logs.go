@@ -0,0 +1,182 @@
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrNoLog = errors.New("no log stored for this deployment")
+	logID    = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+type Logs struct {
+	Dir string
+}
+
+type Match struct {
+	ID   string
+	Line string
+}
+
+func (logs *Logs) path(id string) (string, error) {
+	if !logID.MatchString(id) {
+		return "", fmt.Errorf("invalid deployment id %q", id)
+	}
+
+	return filepath.Join(logs.Dir, id+".log"), nil
+}
+
+func (logs *Logs) Write(id string, output []byte) error {
+	path, err := logs.path(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(logs.Dir, 0o750); err != nil {
+		return fmt.Errorf("os.MkdirAll(): %w", err)
+	}
+
+	if err := os.WriteFile(path, output, 0o640); err != nil {
+		return fmt.Errorf("os.WriteFile(): %w", err)
+	}
+
+	if err := os.WriteFile(strings.TrimSuffix(path, ".log")+".norm", Normalize(output), 0o640); err != nil {
+		return fmt.Errorf("os.WriteFile(): %w", err)
+	}
+
+	return nil
+}
+
+func (logs *Logs) Normalized(id string) ([]byte, error) {
+	path, err := logs.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := os.ReadFile(strings.TrimSuffix(path, ".log") + ".norm")
+	if os.IsNotExist(err) {
+		if output, err = logs.Read(id); err != nil {
+			return nil, err
+		}
+		return Normalize(output), nil
+	}
+
+	return output, err
+}
+
+func (logs *Logs) Read(id string) ([]byte, error) {
+	path, err := logs.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoLog
+	}
+
+	return output, err
+}
+
+func (logs *Logs) Search(ids []string, text string) (*Match, error) {
+	needle := strings.ToLower(text)
+
+	for _, id := range ids {
+		path, err := logs.path(id)
+		if err != nil {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if strings.Contains(strings.ToLower(scanner.Text()), needle) {
+				file.Close()
+				return &Match{ID: id, Line: scanner.Text()}, nil
+			}
+		}
+		file.Close()
+	}
+
+	return nil, nil
+}
+
+func (logs *Logs) Remove(id string) (int64, error) {
+	path, err := logs.path(id)
+	if err != nil {
+		return 0, err
+	}
+
+	freed := int64(0)
+	for _, file := range []string{path, strings.TrimSuffix(path, ".log") + ".norm"} {
+		info, err := os.Stat(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return freed, err
+		}
+
+		if err := os.Remove(file); err != nil {
+			return freed, fmt.Errorf("os.Remove(): %w", err)
+		}
+		freed += info.Size()
+	}
+
+	return freed, nil
+}
+
+func (logs *Logs) Prune(limit int64) (removed int, freed int64, err error) {
+	entries, err := os.ReadDir(logs.Dir)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("os.ReadDir(): %w", err)
+	}
+
+	type stored struct {
+		id   string
+		info os.FileInfo
+	}
+
+	files, total := []stored{}, int64(0)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		total += info.Size()
+		if id, ok := strings.CutSuffix(entry.Name(), ".log"); ok && logID.MatchString(id) {
+			files = append(files, stored{id: id, info: info})
+		}
+	}
+	slices.SortFunc(files, func(a, b stored) int { return a.info.ModTime().Compare(b.info.ModTime()) })
+
+	for _, file := range files {
+		if total <= limit {
+			break
+		}
+
+		size, err := logs.Remove(file.id)
+		if err != nil {
+			return removed, freed, err
+		}
+		removed, freed, total = removed+1, freed+size, total-size
+	}
+
+	return removed, freed, nil
+}
@@ -0,0 +1,110 @@
+package history
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+func Durations(records []*Record, environment, key string, count int) []time.Duration {
+	durations := []time.Duration{}
+	for _, record := range slices.Backward(records) {
+		if record.Environment != environment || record.Key != key || record.Status != "success" || record.Duration <= 0 {
+			continue
+		}
+
+		if durations = append(durations, record.Duration); len(durations) == count {
+			break
+		}
+	}
+
+	return durations
+}
+
+func Median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := slices.Sorted(slices.Values(durations))
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+
+	return (sorted[middle-1] + sorted[middle]) / 2
+}
+
+type Reliability struct {
+	Environment string        `json:"environment"`
+	Key         string        `json:"key"`
+	Deployments int           `json:"deployments"`
+	Successes   int           `json:"successes"`
+	Failures    int           `json:"failures"`
+	Recoveries  int           `json:"recoveries"`
+	MTTR        time.Duration `json:"mttr"`
+	Streak      int           `json:"streak"`
+	Frequency   float64       `json:"frequency"`
+}
+
+func (reliability *Reliability) SuccessRate() float64 {
+	if reliability.Deployments == 0 {
+		return 0
+	}
+
+	return float64(reliability.Successes) / float64(reliability.Deployments)
+}
+
+func Reliabilities(records []*Record, environment string, since time.Time) []*Reliability {
+	groups := map[[2]string]*Reliability{}
+	failed := map[[2]string]time.Time{}
+	restored := map[[2]string]time.Duration{}
+
+	for _, record := range records {
+		if environment != "" && record.Environment != environment || record.Started.Before(since) {
+			continue
+		}
+
+		group := [2]string{record.Environment, record.Key}
+		reliability := groups[group]
+		if reliability == nil {
+			reliability = &Reliability{Environment: record.Environment, Key: record.Key}
+			groups[group] = reliability
+		}
+
+		reliability.Deployments++
+		switch record.Status {
+		case "success":
+			reliability.Successes++
+			reliability.Streak++
+			if started, ok := failed[group]; ok {
+				reliability.Recoveries++
+				restored[group] += record.Started.Add(record.Duration).Sub(started)
+				delete(failed, group)
+			}
+		case "failed":
+			reliability.Failures++
+			reliability.Streak = 0
+			if _, ok := failed[group]; !ok {
+				failed[group] = record.Started
+			}
+		default:
+			reliability.Streak = 0
+		}
+	}
+
+	days := max(time.Since(since).Hours()/24, 1)
+	reliabilities := []*Reliability{}
+	for group, reliability := range groups {
+		if reliability.Recoveries > 0 {
+			reliability.MTTR = restored[group] / time.Duration(reliability.Recoveries)
+		}
+		reliability.Frequency = float64(reliability.Deployments) / days
+		reliabilities = append(reliabilities, reliability)
+	}
+
+	slices.SortFunc(reliabilities, func(a, b *Reliability) int {
+		return cmp.Or(cmp.Compare(a.Environment, b.Environment), cmp.Compare(a.Key, b.Key))
+	})
+	return reliabilities
+}
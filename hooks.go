@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const hookTolerance = 5 * time.Minute
+
+type DeployHook struct {
+	Name     string `json:"name"`
+	Key      string `json:"key"`
+	Ref      string `json:"ref"`
+	Secret   string `json:"secret"`
+	AllowRef bool   `json:"allow_ref"`
+}
+
+func findHook(id string) (*Environment, *DeployHook) {
+	name, hook, ok := strings.Cut(id, "/")
+	environment := Environments[name]
+	if !ok || environment == nil {
+		return nil, nil
+	}
+
+	i := slices.IndexFunc(environment.Hooks, func(candidate *DeployHook) bool { return candidate.Name == hook })
+	if i < 0 {
+		return nil, nil
+	}
+	return environment, environment.Hooks[i]
+}
+
+func freshTimestamp(header string, tolerance time.Duration) bool {
+	seconds, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	return age < tolerance && age > -tolerance
+}
+
+func deployHookHandler(session *discordgo.Session) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+		if err != nil {
+			http.Error(w, "could not read body", http.StatusBadRequest)
+			return
+		}
+
+		id, timestamp := r.URL.Query().Get("hook"), r.Header.Get("X-Deploy-Timestamp")
+		environment, hook := findHook(id)
+		if hook == nil || !validHMAC(secret(hook.Secret, os.Getenv(hook.Secret)), r.Header.Get("X-Deploy-Signature"), slices.Concat([]byte(timestamp+"."), body)) {
+			http.Error(w, "invalid hook or signature", http.StatusUnauthorized)
+			return
+		}
+
+		if !freshTimestamp(timestamp, hookTolerance) {
+			http.Error(w, "X-Deploy-Timestamp is missing or older than "+hookTolerance.String(), http.StatusUnauthorized)
+			return
+		}
+
+		if entry, ok := Commands[hook.Key]; ok && entry.TOTP {
+			http.Error(w, "key requires a TOTP code", http.StatusForbidden)
+			return
+		}
+
+		payload := struct {
+			Ref    string `json:"ref"`
+			Reason string `json:"reason"`
+		}{}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			if err := json.Unmarshal(body, &payload); err != nil {
+				http.Error(w, "body must be empty or JSON", http.StatusBadRequest)
+				return
+			}
+		}
+
+		ref := hook.Ref
+		if ref == "" {
+			ref = environment.Branch
+		}
+		if payload.Ref != "" && payload.Ref != ref {
+			if !hook.AllowRef {
+				http.Error(w, "this hook does not accept a ref", http.StatusForbidden)
+				return
+			}
+			ref = payload.Ref
+		}
+
+		reason := "Hook " + id
+		if payload.Reason = strings.TrimSpace(payload.Reason); payload.Reason != "" {
+			reason += ": " + payload.Reason
+		}
+
+		key := ""
+		if header := r.Header.Get("Idempotency-Key"); header != "" {
+			key = "hook:" + id + ":" + header
+			existing, err := reserveIdempotency(key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			if existing != nil {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]any{"id": existing.Deployment, "status": deploymentStatus(existing.Deployment), "duplicate": true})
+				return
+			}
+		}
+
+		deployment, _, err := newAPIDeployment(environment, session.State.User, "hook:"+id, []string{ref, hook.Key, reason})
+		if key != "" {
+			completeIdempotency(key, deployment)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		log.Printf("Deploying %s@%s to %s via hook %s", deployment.Key, deployment.Branch, environment.Name, id)
+		session.ChannelMessageSend(environment.Channel, deployment.text(environment.Channel, "hooks.deploying", "hook", hook.Name))
+		startDeployment(session, environment.Channel, deployment)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"id": deployment.ID, "ref": deployment.Branch, "sha": deployment.SHA, "status": "started"})
+	}
+}
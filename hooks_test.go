@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFreshTimestamp(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"Now", strconv.FormatInt(now.Unix(), 10), true},
+		{"Recent", strconv.FormatInt(now.Add(-4*time.Minute).Unix(), 10), true},
+		{"ClockSkew", strconv.FormatInt(now.Add(4*time.Minute).Unix(), 10), true},
+		{"Stale", strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10), false},
+		{"Future", strconv.FormatInt(now.Add(6*time.Minute).Unix(), 10), false},
+		{"Missing", "", false},
+		{"Milliseconds", strconv.FormatInt(now.UnixMilli(), 10), false},
+		{"NotANumber", now.Format(time.RFC3339), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := freshTimestamp(test.header, hookTolerance); got != test.want {
+				t.Errorf("freshTimestamp(%q) = %v, want %v", test.header, got, test.want)
+			}
+		})
+	}
+}
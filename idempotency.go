@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+const idempotencyWindow = 24 * time.Hour
+
+var errIdempotencyPending = errors.New("a request with this idempotency key is still being processed")
+
+type IdempotentRequest struct {
+	Deployment string    `json:"deployment"`
+	Created    time.Time `json:"created"`
+}
+
+func reserveIdempotency(key string) (*IdempotentRequest, error) {
+	var existing *IdempotentRequest
+	store.Update(func(state *State) {
+		for _, candidate := range slices.Collect(maps.Keys(state.Idempotency)) {
+			if time.Since(state.Idempotency[candidate].Created) > idempotencyWindow {
+				delete(state.Idempotency, candidate)
+			}
+		}
+
+		if existing = state.Idempotency[key]; existing == nil {
+			state.Idempotency[key] = &IdempotentRequest{Created: time.Now().UTC()}
+		}
+	})
+
+	if existing != nil && existing.Deployment == "" {
+		return nil, errIdempotencyPending
+	}
+	return existing, nil
+}
+
+func completeIdempotency(key string, deployment *Deployment) {
+	store.Update(func(state *State) {
+		if deployment == nil {
+			delete(state.Idempotency, key)
+			return
+		}
+
+		if reserved := state.Idempotency[key]; reserved != nil {
+			reserved.Deployment = deployment.ID
+		}
+	})
+}
+
+func deploymentStatus(id string) string {
+	if deployment, ok := activeDeployment(id); ok {
+		if slices.ContainsFunc(scheduler.Running(), func(running *Deployment) bool { return running == deployment }) {
+			return "running"
+		}
+		return "queued"
+	}
+
+	status := "started"
+	store.View(func(state *State) {
+		for _, record := range slices.Backward(state.History) {
+			if record.ID == id {
+				status = record.Status
+				return
+			}
+		}
+	})
+	return status
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"regexp"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var incidentPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.#-]{0,63}$`)
+
+func incidentArgs(channelID string, args []string) ([]string, string, error) {
+	rest, value, found := flagArg(args, "incident")
+	switch {
+	case !found:
+		return args, "", nil
+	case value == "":
+		return nil, "", textError(channelID, "incident.missing")
+	case !incidentPattern.MatchString(value):
+		return nil, "", textError(channelID, "incident.invalid", "incident", value)
+	}
+
+	return rest, value, nil
+}
+
+func (deployment *Deployment) pingIncident(session *discordgo.Session, status string) {
+	if deployment.Incident == "" || data.IncidentChannel == "" {
+		return
+	}
+
+	session.ChannelMessageSend(data.IncidentChannel, deployment.text(data.IncidentChannel, "incident.finished", "incident", deployment.Incident, "status", status))
+}
@@ -1,23 +1,25 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
-	"reflect"
-	"regexp"
 	"slices"
 	"strings"
+	"syscall"
 	"time"
 
+	"deploy/config"
+	"deploy/dictionary"
+	"deploy/executor"
+	"deploy/locale"
 	"github.com/jacobbernoulli/discordgo"
-	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Config struct {
@@ -28,152 +30,465 @@ type Config struct {
 	DeploymentChannel    string `env:"DEPLOYMENT_CHANNEL"`
 	DeploymentRole       string `env:"DEPLOYMENT_ROLE"`
 	DeploymentLogWebhook string `env:"DEPLOYMENT_LOG_WEBHOOK"`
+	GithubToken          string `env:"GITHUB_TOKEN" optional:"true"`
+	StateFile            string `env:"STATE_FILE" default:"state.json"`
+	StateBackup          string `env:"STATE_BACKUP" optional:"true"`
+	StateBackupInterval  string `env:"STATE_BACKUP_INTERVAL" optional:"true"`
+	StateBackupKeep      string `env:"STATE_BACKUP_KEEP" default:"14"`
+	RetentionHistory     string `env:"RETENTION_HISTORY" default:"90d"`
+	RetentionReleases    string `env:"RETENTION_RELEASES" default:"20"`
+	RetentionLogs        string `env:"RETENTION_LOGS" default:"5GB"`
+	RetentionInterval    string `env:"RETENTION_INTERVAL" default:"24h"`
+	EnvironmentsFile     string `env:"ENVIRONMENTS_FILE" default:"environments.json"`
+	NotificationsFile    string `env:"NOTIFICATIONS_FILE" default:"notifications.json"`
+	PresetsFile          string `env:"PRESETS_FILE" default:"presets.json"`
+	SecretsRefresh       string `env:"SECRETS_REFRESH" default:"5m"`
+	DeployUser           string `env:"DEPLOY_USER" optional:"true"`
+	DeployPath           string `env:"DEPLOY_PATH" optional:"true"`
+	DeployEnv            string `env:"DEPLOY_ENV" optional:"true"`
+	DeployShell          string `env:"DEPLOY_SHELL" optional:"true"`
+	CgroupRoot           string `env:"CGROUP_ROOT" default:"/sys/fs/cgroup/deploy"`
+	PluginsDir           string `env:"PLUGINS_DIR" default:"plugins"`
+	LogsDir              string `env:"LOGS_DIR" default:"logs"`
+	LogsBucket           string `env:"LOGS_S3_BUCKET" optional:"true"`
+	LogsEndpoint         string `env:"LOGS_S3_ENDPOINT" optional:"true"`
+	LogsPrefix           string `env:"LOGS_S3_PREFIX" default:"deploy-logs/"`
+	LogsExpiry           string `env:"LOGS_S3_EXPIRY" default:"168h"`
+	AdminRole            string `env:"ADMIN_ROLE" optional:"true"`
+	AllowedGuilds        string `env:"ALLOWED_GUILDS" optional:"true"`
+	DebugAddr            string `env:"DEBUG_ADDR" optional:"true"`
+	DebugToken           string `env:"DEBUG_TOKEN" optional:"true"`
+	WebhookAddr          string `env:"WEBHOOK_ADDR" optional:"true"`
+	WebhookSecret        string `env:"WEBHOOK_SECRET" optional:"true"`
+	DashboardAddr        string `env:"DASHBOARD_ADDR" optional:"true"`
+	DashboardURL         string `env:"DASHBOARD_URL" optional:"true"`
+	DiscordClientID      string `env:"DISCORD_CLIENT_ID" optional:"true"`
+	DiscordClientSecret  string `env:"DISCORD_CLIENT_SECRET" optional:"true"`
+	GRPCAddr             string `env:"GRPC_ADDR" optional:"true"`
+	GRPCToken            string `env:"GRPC_TOKEN" optional:"true"`
+	SMTPHost             string `env:"SMTP_HOST" optional:"true"`
+	SMTPPort             string `env:"SMTP_PORT" default:"587"`
+	SMTPUsername         string `env:"SMTP_USERNAME" optional:"true"`
+	SMTPPassword         string `env:"SMTP_PASSWORD" optional:"true"`
+	SMTPFrom             string `env:"SMTP_FROM" optional:"true"`
+	IncidentChannel      string `env:"INCIDENT_CHANNEL" optional:"true"`
+	ConcurrencyGroups    string `env:"CONCURRENCY_GROUPS" optional:"true"`
+	AnomalyThreshold     string `env:"ANOMALY_THRESHOLD" optional:"true"`
+	StallTimeout         string `env:"STALL_TIMEOUT" optional:"true"`
+	Locale               string `env:"LOCALE" optional:"true"`
+	QuickReactions       string `env:"QUICK_REACTIONS" optional:"true"`
+	Intents              string `env:"INTENTS" default:"default"`
+	MemberCacheTTL       string `env:"MEMBER_CACHE_TTL" optional:"true"`
+	PrometheusURL        string `env:"PROMETHEUS_URL" optional:"true"`
+	PrometheusToken      string `env:"PROMETHEUS_TOKEN" optional:"true"`
+	DatadogAPIKey        string `env:"DATADOG_API_KEY" optional:"true"`
+	DatadogAppKey        string `env:"DATADOG_APP_KEY" optional:"true"`
+	DatadogSite          string `env:"DATADOG_SITE" default:"datadoghq.com"`
+	AuditSyslog          string `env:"AUDIT_SYSLOG" optional:"true"`
+	AuditURL             string `env:"AUDIT_URL" optional:"true"`
+	AuditToken           string `env:"AUDIT_TOKEN" optional:"true"`
+	SelfUpdateRepository string `env:"SELF_UPDATE_REPOSITORY" optional:"true"`
+	SelfUpdateAsset      string `env:"SELF_UPDATE_ASSET" default:"deploy-${OS}-${ARCH}"`
+	SelfUpdateChecksums  string `env:"SELF_UPDATE_CHECKSUMS" default:"SHA256SUMS"`
+	SelfUpdateSignature  string `env:"SELF_UPDATE_SIGNATURE" optional:"true"`
+	SelfUpdateKeyring    string `env:"SELF_UPDATE_KEYRING" optional:"true"`
+	SelfUpdateService    string `env:"SELF_UPDATE_SERVICE" optional:"true"`
 }
 
-type COMMANDS_DICTIONARY map[string]string
+type (
+	COMMANDS_DICTIONARY = dictionary.Dictionary
+	Entry               = dictionary.Entry
+	Step                = dictionary.Step
+	Slot                = dictionary.Slot
+	LoadBalancer        = dictionary.LoadBalancer
+	Param               = dictionary.Param
+	Limits              = executor.Limits
+)
 
 var (
-	data     *Config
-	Commands COMMANDS_DICTIONARY
+	data            *Config
+	Commands        COMMANDS_DICTIONARY
+	secretsProvider SecretsProvider
 )
 
-func sendDiscordWebhookMessage(status, branch string, author string) {
-	success := status == "success"
-	color := 0x008000
-	description := "Deployment Successful!"
-	if !success {
-		color = 0x800000
-		description = "Deployment Failed!"
-	}
-
-	payload := map[string]any{
-		"embeds": []map[string]any{
-			{
-				"title":       "Deployment Status",
-				"description": description,
-				"color":       color,
-				"fields": []map[string]any{
-					{
-						"name":   "Environment",
-						"value":  data.Environment,
-						"inline": true,
-					},
-					{
-						"name":   "Branch",
-						"value":  branch,
-						"inline": true,
-					},
-				},
-				"thumbnail": map[string]any{
-					"url": "https://r2.fivemanage.com/3i2fhQIkHIaRFDy1YIvi8/images/image.png",
-				},
-				"footer": map[string]any{
-					"text": "User ID: " + author,
-				},
-				"timestamp": time.Now().Format(time.RFC3339),
-			},
-		},
-	}
-
-	body, _ := json.Marshal(payload)
-	http.Post(data.DeploymentLogWebhook, "application/json", bytes.NewBuffer(body))
+var handlers = map[string]func(*discordgo.Session, *discordgo.MessageCreate, []string){
+	"deploy":      deploy,
+	"releases":    releases,
+	"rollback":    rollback,
+	"maintenance": maintenance,
+	"history":     deploymentHistory,
+	"lock":        lock,
+	"unlock":      unlock,
+	"queue":       queue,
+	"totp":        totp,
+	"logs":        logs,
+	"compare":     compare,
+	"preview":     previews,
+	"token":       tokens,
+	"stats":       stats,
+	"locale":      setLocale,
+	"promote":     promote,
+	"admin":       admin,
+	"note":        note,
+	"status":      showStatus,
+}
+
+var deploySubcommands = map[string]func(*discordgo.Session, *discordgo.MessageCreate, []string){
+	"diff":        deployDiff,
+	"version":     deployVersion,
+	"self-update": selfUpdate,
+	"test":        deployShadow,
 }
 
 func getConfig() (*Config, error) {
-	if err := godotenv.Load(".env"); err != nil {
-		return nil, fmt.Errorf("godotenv.Load(): %w", err)
+	if err := config.LoadEnvFile(); err != nil {
+		return nil, fmt.Errorf("config.LoadEnvFile(): %w", err)
+	}
+
+	provider, err := newSecretsProvider()
+	if err != nil {
+		return nil, fmt.Errorf("newSecretsProvider(): %w", err)
 	}
 
-	config := &Config{}
-	val := reflect.ValueOf(config).Elem()
+	if provider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := loadSecrets(ctx, provider); err != nil {
+			return nil, fmt.Errorf("loadSecrets(): %w", err)
+		}
+		secretsProvider = provider
+	}
 
-	for i := range val.NumField() {
-		str := val.Type().Field(i).Tag.Get("env")
-		value, input := os.LookupEnv(str)
-		if !input || strings.TrimSpace(value) == "" {
-			return nil, fmt.Errorf("missing environment variable: %s", str)
+	result := &Config{}
+	if err := config.Load(result, func(name string) (string, bool) {
+		value, ok := os.LookupEnv(name)
+		if value = secret(name, value); value != "" {
+			ok = true
 		}
-		val.Field(i).SetString(value)
+		return value, ok
+	}); err != nil {
+		return nil, err
 	}
 
-	return config, nil
+	return result, nil
 }
 
-func getDictionary(Dictionary any) error {
-	data, err := os.ReadFile("dictionary.json")
-	if err != nil {
-		return fmt.Errorf("os.ReadFile(): %w", err)
+func executorOptions(shell string, env []string) executor.Options {
+	if shell == "" {
+		shell = data.DeployShell
 	}
 
-	if err := json.Unmarshal(data, Dictionary); err != nil {
-		return fmt.Errorf("json.Unmarshal(): %w", err)
+	options := executor.Options{Shell: shell, Env: env, User: data.DeployUser, Path: data.DeployPath, CgroupRoot: data.CgroupRoot}
+	if data.DeployEnv != "" {
+		options.PassEnv = strings.Split(data.DeployEnv, ",")
 	}
 
-	return nil
+	return options
 }
 
-func deploy(session *discordgo.Session, message *discordgo.MessageCreate) {
-	member, err := session.GuildMember(message.GuildID, message.Author.ID)
-	if err != nil || !strings.HasPrefix(message.Content, "!") || message.Author.Bot || message.ChannelID != data.DeploymentChannel || !slices.Contains(member.Roles, data.DeploymentRole) {
+func messageCreate(session *discordgo.Session, message *discordgo.MessageCreate) {
+	if !guildAllowed(message.GuildID) {
 		return
 	}
+	rememberChannel(message.ChannelID, message.GuildID)
 
-	args := strings.Fields(strings.TrimPrefix(message.Content, "!"))
-	if len(args) < 3 {
-		session.ChannelMessageSend(message.ChannelID, "Missing fields - !deploy <branch> <key>")
+	if message.Author == nil || message.Author.Bot || !strings.HasPrefix(message.Content, "!") {
 		return
 	}
 
-	command, branch, key := strings.ToLower(args[0]), strings.ToLower(args[1]), args[2]
-	if command != "deploy" {
+	environment := environmentByChannel(message.ChannelID)
+	if environment == nil {
 		return
 	}
 
-	if _, ok := Commands[key]; !ok {
-		session.ChannelMessageSend(message.ChannelID, fmt.Sprintf("Invalid key name `(%s)` specified.", key))
+	args := strings.Fields(message.Content[1:])
+	if len(args) == 0 {
 		return
 	}
 
-	if !regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(branch) || branch != data.Branch {
-		session.ChannelMessageSend(message.ChannelID, fmt.Sprintf("Invalid branch `(%s)` specified.", branch))
-		sendDiscordWebhookMessage("failed", branch, message.Author.ID)
+	handler, ok := handlers[strings.ToLower(args[0])]
+	if !ok {
 		return
 	}
 
-	msg, err := session.ChannelMessageSend(message.ChannelID, "Deploying ongoing...")
-	if err != nil {
+	member, err := guildMember(session, message.GuildID, message.Author.ID)
+	if err != nil || !slices.Contains(member.Roles, environment.Role) {
 		return
 	}
 
-	go func() {
-		command := strings.ReplaceAll(strings.ReplaceAll(Commands[key], "${LOCATION}", data.DeploymentLocation), "${BRANCH}", branch)
+	message.Member = member
+	handler(session, message, args[1:])
+}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-		defer cancel()
+func deploy(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) > 0 {
+		if subcommand, ok := deploySubcommands[strings.ToLower(args[0])]; ok {
+			subcommand(session, message, args[1:])
+			return
+		}
 
-		output, err := exec.CommandContext(ctx, "bash", "-c", command).CombinedOutput()
-		if err != nil {
-			session.ChannelMessageEdit(message.ChannelID, msg.ID, fmt.Sprintf("Deployment failed: `%s`", err.Error()))
-			log.Printf("cmd.CombinedOutput(): %v\n%s", err, string(output))
+		if strings.EqualFold(args[0], "preset") {
+			deployPreset(session, message, args[1:])
+			return
+		}
+	}
+
+	requestDeployment(session, message.ChannelID, environmentByChannel(message.ChannelID), message.Author, args)
+}
+
+func requestDeployment(session *discordgo.Session, channelID string, environment *Environment, author *discordgo.User, args []string) {
+	if len(args) > 1 {
+		if entry, ok := Commands[args[1]]; ok && entry.Aggregate() {
+			go deployPlan(session, channelID, environment, author, args)
 			return
 		}
+	}
+
+	deployment, code, err := newDeployment(environment, author, args)
+	if err != nil {
+		session.ChannelMessageSend(channelID, err.Error())
+		return
+	}
 
-		session.ChannelMessageEdit(message.ChannelID, msg.ID, "Deployment successful, wait at least 10s if you need to restart.")
-		sendDiscordWebhookMessage("success", branch, message.Author.ID)
-		log.Printf("Deployment successful. Username: %s (%s) - Branch: %s - Executed: %s", message.Author.Username, message.Author.ID, branch, command)
+	if !deployment.Entry.TOTP {
+		startDeployment(session, channelID, deployment)
+		return
+	}
+
+	go func() {
+		if !confirmTOTP(session, channelID, deployment, code) {
+			deployment.finish(errTOTP)
+			return
+		}
+		startDeployment(session, channelID, deployment)
 	}()
 }
 
+func newDeployment(environment *Environment, author *discordgo.User, args []string) (*Deployment, string, error) {
+	return newAPIDeployment(environment, author, "", args)
+}
+
+// newAPIDeployment is newDeployment for requests authenticated by an API
+// token or hook rather than a Discord user, recording the credential used.
+func newAPIDeployment(environment *Environment, author *discordgo.User, credential string, args []string) (*Deployment, string, error) {
+	ctx, span := tracer.Start(context.Background(), "deployment", trace.WithAttributes(attribute.String("deploy.environment", environment.Name), attribute.String("deploy.requester", author.ID)))
+
+	validation, validate := tracer.Start(ctx, "validate")
+	deployment, code, err := validateDeployment(validation, environment, author, args)
+	endSpan(validate, err)
+	if err != nil {
+		endSpan(span, err)
+		auditRejected(environment, author, credential, args, err)
+		return nil, "", err
+	}
+	deployment.Credential = credential
+	deployment.audit("attempt", "accepted", nil, map[string]string{"reason": deployment.Reason, "ticket": deployment.Ticket, "incident": deployment.Incident})
+
+	span.SetAttributes(attribute.String("deploy.id", deployment.ID), attribute.String("deploy.key", deployment.Key), attribute.String("deploy.ref", deployment.Branch), attribute.String("deploy.ref_type", deployment.RefType))
+	deployment.trace = ctx
+	return deployment, code, nil
+}
+
+func validateDeployment(ctx context.Context, environment *Environment, author *discordgo.User, args []string) (*Deployment, string, error) {
+	if len(args) < 2 {
+		return nil, "", textError(environment.Channel, "validate.missing")
+	}
+
+	branch, key := args[0], args[1]
+
+	entry, ok := Commands[key]
+	if !ok {
+		return nil, "", textError(environment.Channel, "validate.invalid_key", "key", key)
+	}
+
+	if entry.Strategy == "artifact" {
+		if !tagPattern.MatchString(branch) {
+			notifyDeployment("failed", environment.Name, branch, author.ID, "", nil)
+			return nil, "", textError(environment.Channel, "validate.invalid_tag", "tag", branch)
+		}
+	} else if !validBranch(environment, branch) {
+		notifyDeployment("failed", environment.Name, branch, author.ID, "", nil)
+		return nil, "", textError(environment.Channel, "validate.invalid_branch", "branch", branch)
+	}
+
+	if entry.Maintenance == "require" && !inMaintenance(environment) {
+		return nil, "", textError(environment.Channel, "validate.requires_maintenance", "key", key, "environment", environment.Name)
+	}
+
+	if current := lockOf(environment); current != nil && current.Author != author.ID {
+		return nil, "", errors.New(current.describe(environment) + " - !unlock")
+	}
+
+	rest, code := totpArgs(args[2:])
+	rest, incident, err := incidentArgs(environment.Channel, rest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rest, priority, preempt, err := priorityArgs(environment.Channel, rest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rest, params, err := paramArgs(environment.Channel, entry, rest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	reason, ticket, err := changeReason(environment, rest)
+	if err != nil {
+		return nil, "", err
+	}
+	if entry.Risk == "high" && reason == "" {
+		return nil, "", textError(environment.Channel, "validate.requires_reason", "key", key)
+	}
+
+	deployment := &Deployment{
+		ID:          newID(),
+		Environment: environment,
+		Key:         key,
+		Entry:       entry,
+		Branch:      branch,
+		Author:      author,
+		Started:     time.Now(),
+		Reason:      reason,
+		Ticket:      ticket,
+		Incident:    incident,
+		Priority:    priority,
+		Preempt:     preempt,
+		Params:      params,
+	}
+
+	if current := lockOf(environment); current != nil {
+		deployment.LockedBy = current.Author
+	}
+
+	if entry.Strategy == "artifact" {
+		deployment.RefType = "tag"
+		return deployment, code, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	env, cleanup, err := gitCredentials(environment)
+	if err != nil {
+		log.Printf("gitCredentials(): %v", err)
+		return nil, "", textError(environment.Channel, "validate.credentials", "environment", environment.Name)
+	}
+	defer cleanup()
+
+	if err := resolveRef(ctx, deployment, env); errors.Is(err, errMissingRef) {
+		notifyDeployment("failed", environment.Name, branch, author.ID, "", nil)
+		return nil, "", textError(environment.Channel, "validate.invalid_branch", "branch", branch)
+	} else if err != nil {
+		log.Printf("resolveRef(): %v", err)
+		return nil, "", textError(environment.Channel, "validate.unresolved", "branch", branch)
+	}
+
+	return deployment, code, nil
+}
+
+func startDeployment(session *discordgo.Session, channelID string, deployment *Deployment) error {
+	var msg *discordgo.Message
+	var err error
+	if deployment.Environment.Forum != "" {
+		msg, err = startForumPost(session, channelID, deployment)
+	} else {
+		msg, err = session.ChannelMessageSend(channelID, deployment.text(channelID, "deploy.ongoing"))
+	}
+	if err != nil {
+		return err
+	}
+
+	go runDeployment(session, msg, deployment)
+	return nil
+}
+
 func main() {
-	config, err := getConfig()
+	encrypt := flag.String("encrypt-config", "", "encrypt the given env file into "+config.EncryptedFile+" using CONFIG_KEY")
+	generate := flag.Bool("generate-key", false, "print a new random CONFIG_KEY")
+	validate := flag.Bool("validate", false, "validate the configuration and exit")
+	restore := flag.String("restore-state", "", "replace STATE_FILE with the given backup file or s3://bucket/key while the bot is stopped, keeping the old state as .pre-restore")
+	flag.Parse()
+
+	if *generate {
+		fmt.Println(config.GenerateKey())
+		return
+	}
+
+	if *encrypt != "" {
+		if err := config.EncryptFile(*encrypt); err != nil {
+			log.Fatalf("config.EncryptFile(): %v", err)
+		}
+		log.Printf("Encrypted %s into %s, the plaintext file can now be removed.", *encrypt, config.EncryptedFile)
+		return
+	}
+
+	cfg, err := getConfig()
 	if err != nil {
 		log.Fatalf("getConfig(): %v", err)
 	}
 
-	data = config
+	data = cfg
+	deploymentLogs.Dir = data.LogsDir
+	allowedGuilds = parseAllowedGuilds(data.AllowedGuilds)
+
+	if *restore != "" {
+		if err := restoreState(*restore, data.StateFile); err != nil {
+			log.Fatalf("restoreState(): %v", err)
+		}
+		log.Printf("Restored %s from %s, start the bot to use it.", data.StateFile, *restore)
+		return
+	}
+
+	if concurrencyGroups, err = parseConcurrencyGroups(data.ConcurrencyGroups); err != nil {
+		log.Fatalf("parseConcurrencyGroups(): %v", err)
+	}
+
+	if anomalyThreshold, err = parseAnomalyThreshold(data.AnomalyThreshold); err != nil {
+		log.Fatalf("parseAnomalyThreshold(): %v", err)
+	}
+
+	if stallTimeout, err = parseStallTimeout(data.StallTimeout); err != nil {
+		log.Fatalf("parseStallTimeout(): %v", err)
+	}
+
+	if memberCacheTTL, err = parseMemberCacheTTL(data.MemberCacheTTL); err != nil {
+		log.Fatalf("parseMemberCacheTTL(): %v", err)
+	}
+
+	if backupInterval, backupKeep, err = parseStateBackup(data.StateBackupInterval, data.StateBackupKeep); err != nil {
+		log.Fatalf("parseStateBackup(): %v", err)
+	}
+
+	if retention, err = parseRetention(data.RetentionHistory, data.RetentionReleases, data.RetentionLogs, data.RetentionInterval); err != nil {
+		log.Fatalf("parseRetention(): %v", err)
+	}
+
+	if quickReactions, err = parseQuickReactions(data.QuickReactions); err != nil {
+		log.Fatalf("parseQuickReactions(): %v", err)
+	}
+
+	if messages, err = locale.Load(); err != nil {
+		log.Fatalf("locale.Load(): %v", err)
+	}
+
+	if err := messages.LoadOverrides("messages.json"); err != nil {
+		log.Fatalf("messages.LoadOverrides(): %v", err)
+	}
+
+	if Commands, err = dictionary.Load("dictionary.json"); err != nil {
+		log.Fatalf("dictionary.Load(): %v", err)
+	}
 
-	if err := getDictionary(&Commands); err != nil {
-		log.Fatalf("getDictionary(): %v", err)
+	if err := getEnvironments(data.EnvironmentsFile); err != nil {
+		log.Fatalf("getEnvironments(): %v", err)
+	}
+
+	if err := getPresets(data.PresetsFile); err != nil {
+		log.Fatalf("getPresets(): %v", err)
 	}
 
 	session, err := discordgo.New("Bot " + data.Token)
@@ -181,19 +496,110 @@ func main() {
 		log.Fatalf("discordgo.New(): %v", err)
 	}
 
-	session.AddHandler(deploy)
-	session.Identify.Intents = discordgo.IntentGuilds | discordgo.IntentGuildModeration | discordgo.IntentGuildMembers | discordgo.IntentGuildMessages | discordgo.IntentMessageContent
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("setupTracing(): %v", err)
+	}
+
+	if err := loadNotifiers(session, data.NotificationsFile); err != nil {
+		log.Fatalf("loadNotifiers(): %v", err)
+	}
+
+	if err := loadAudit(); err != nil {
+		log.Fatalf("loadAudit(): %v", err)
+	}
+
+	if problems := preflight(session); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Printf("preflight: %s", problem)
+		}
+		log.Fatalf("preflight(): %d problem(s) found", len(problems))
+	} else if *validate {
+		log.Println("Configuration is valid.")
+		return
+	}
+
+	if secretsProvider != nil {
+		interval, err := time.ParseDuration(data.SecretsRefresh)
+		if err != nil {
+			log.Fatalf("time.ParseDuration(): %v", err)
+		}
+		go refreshSecrets(secretsProvider, interval)
+	}
+
+	store, err = openStore(data.StateFile)
+	if err != nil {
+		log.Fatalf("openStore(): %v", err)
+	}
+
+	if backupInterval > 0 && data.StateBackup != "" {
+		go scheduleBackups(backupInterval)
+	}
+
+	if retention.Interval > 0 {
+		go scheduleGC(retention.Interval)
+	}
+
+	if data.DebugAddr != "" {
+		if err := serveDebug(data.DebugAddr); err != nil {
+			log.Fatalf("serveDebug(): %v", err)
+		}
+	}
+
+	session.AddHandler(messageCreate)
+	session.AddHandler(interactionCreate)
+	session.AddHandler(messageReactionAdd)
+	session.AddHandler(disconnected)
+	session.AddHandler(guildCreate)
+	session.AddHandler(threadDelete)
+	session.AddHandler(guildMemberUpdate)
+	session.AddHandler(guildMemberRemove)
+	session.AddHandler(connected)
+	if session.Identify.Intents, err = parseIntents(data.Intents); err != nil {
+		log.Fatalf("parseIntents(): %v", err)
+	}
+	if session.Identify.Intents&discordgo.IntentMessageContent == 0 {
+		log.Printf("MESSAGE_CONTENT intent disabled, only slash commands, context menus and reactions will work")
+	}
 
 	if err := session.Open(); err != nil {
 		log.Fatalf("session.Open(): %v", err)
 	}
 
 	log.Printf("%s#%s is ready!", session.State.User.Username, session.State.User.Discriminator)
+	go updatePresence(session)
+	if data.WebhookAddr != "" {
+		if err := serveWebhooks(session, data.WebhookAddr); err != nil {
+			log.Fatalf("serveWebhooks(): %v", err)
+		}
+		go expirePreviews(session)
+	}
+	if data.DashboardAddr != "" {
+		if err := serveDashboard(session, data.DashboardAddr); err != nil {
+			log.Fatalf("serveDashboard(): %v", err)
+		}
+	}
+	if data.GRPCAddr != "" {
+		if err := serveGRPC(session, data.GRPCAddr); err != nil {
+			log.Fatalf("serveGRPC(): %v", err)
+		}
+	}
+	registerCommands(session)
+	announce(session, fmt.Sprintf("Deploy bot `%s` started.", versionString()), 0x008000)
 
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 
+	announce(session, fmt.Sprintf("Deploy bot `%s` is shutting down after %s.", versionString(), uptime()), 0x800000)
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Printf("shutdownTracing(): %v", err)
+	}
+
+	flush, cancelFlush := context.WithTimeout(context.Background(), 15*time.Second)
+	notifications.Flush(flush)
+	cancelFlush()
+	auditLog.Wait()
 	log.Println("Shutdown complete.")
 	if err := session.Close(); err != nil {
 		log.Fatalf("session.Close(): %v", err)
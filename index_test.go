@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func BenchmarkMessageCreate(b *testing.B) {
+	environmentChannels = map[string]*Environment{"channel": {Name: "production", Channel: "channel", Role: "deployers"}}
+	cacheMember("guild", &discordgo.Member{User: &discordgo.User{ID: "user"}})
+
+	session := &discordgo.Session{State: discordgo.NewState()}
+	author := &discordgo.User{ID: "user", Username: "user"}
+
+	benchmarks := []struct {
+		name    string
+		channel string
+		content string
+	}{
+		{"Chatter", "channel", "looks good to me, shipping after lunch"},
+		{"OtherChannel", "elsewhere", "!deploy api main"},
+		{"UnknownCommand", "channel", "!lunch"},
+		{"Command", "channel", "!deploy api main"},
+		{"UppercaseCommand", "channel", "!DEPLOY api main"},
+	}
+
+	for _, benchmark := range benchmarks {
+		message := &discordgo.MessageCreate{Message: &discordgo.Message{GuildID: "guild", ChannelID: benchmark.channel, Author: author, Content: benchmark.content}}
+		b.Run(benchmark.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				messageCreate(session, message)
+			}
+		})
+	}
+}
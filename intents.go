@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var intentNames = map[string]discordgo.Intent{
+	"guilds":                  discordgo.IntentGuilds,
+	"guild_members":           discordgo.IntentGuildMembers,
+	"guild_moderation":        discordgo.IntentGuildModeration,
+	"guild_messages":          discordgo.IntentGuildMessages,
+	"guild_message_reactions": discordgo.IntentGuildMessageReactions,
+	"message_content":         discordgo.IntentMessageContent,
+}
+
+var intentPresets = map[string]discordgo.Intent{
+	"default": discordgo.IntentGuilds | discordgo.IntentGuildModeration | discordgo.IntentGuildMembers | discordgo.IntentGuildMessages | discordgo.IntentGuildMessageReactions | discordgo.IntentMessageContent,
+	"minimal": discordgo.IntentGuilds | discordgo.IntentGuildMessageReactions,
+}
+
+func parseIntents(value string) (discordgo.Intent, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		value = "default"
+	}
+
+	if intents, ok := intentPresets[value]; ok {
+		return intents, nil
+	}
+
+	intents := discordgo.IntentGuilds
+	for name := range strings.SplitSeq(value, ",") {
+		intent, ok := intentNames[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown intent %q, expected default, minimal or a list of guilds, guild_members, guild_moderation, guild_messages, guild_message_reactions, message_content", name)
+		}
+		intents |= intent
+	}
+
+	return intents, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type Decision struct {
+	Choice string
+	User   *discordgo.User
+}
+
+var (
+	decisionsMu sync.Mutex
+	decisions   = map[string]chan Decision{}
+	guards      = map[string]func(Decision) string{}
+)
+
+var componentHandlers = map[string]func(*discordgo.Session, *discordgo.InteractionCreate, []string){
+	"bluegreen": blueGreenRollback,
+	"decision":  decide,
+	"picker":    pick,
+	"queue":     cancelQueued,
+	"totp":      totpButton,
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func awaitDecision(id string) (<-chan Decision, func()) {
+	decision := make(chan Decision, 1)
+
+	decisionsMu.Lock()
+	decisions[id] = decision
+	decisionsMu.Unlock()
+
+	return decision, func() {
+		decisionsMu.Lock()
+		delete(decisions, id)
+		delete(guards, id)
+		decisionsMu.Unlock()
+	}
+}
+
+func guardDecision(id string, guard func(Decision) string) {
+	decisionsMu.Lock()
+	guards[id] = guard
+	decisionsMu.Unlock()
+}
+
+var modalHandlers = map[string]func(*discordgo.Session, *discordgo.InteractionCreate, []string){
+	"decision": submitDecision,
+	"params":   submitParams,
+}
+
+func submitDecision(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 1 {
+		return
+	}
+
+	for _, row := range interaction.ModalSubmitData().Components {
+		if row, ok := row.(*discordgo.ActionsRow); ok {
+			for _, component := range row.Components {
+				if input, ok := component.(*discordgo.TextInput); ok {
+					decide(session, interaction, []string{args[0], strings.TrimSpace(input.Value)})
+					return
+				}
+			}
+		}
+	}
+}
+
+func decide(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 {
+		return
+	}
+
+	choice := Decision{Choice: args[1], User: interaction.Member.User}
+
+	decisionsMu.Lock()
+	decision, ok := decisions[args[0]]
+	reason := ""
+	if guard := guards[args[0]]; ok && guard != nil {
+		reason = guard(choice)
+	}
+	if ok && reason == "" {
+		delete(decisions, args[0])
+		delete(guards, args[0])
+	}
+	decisionsMu.Unlock()
+
+	if !ok {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "interaction.inactive"))
+		return
+	}
+
+	if reason != "" {
+		respondEphemeral(session, interaction, reason)
+		return
+	}
+
+	decision <- choice
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+}
+
+func decisionButtons(id string, choices ...discordgo.Button) []discordgo.MessageComponent {
+	for i := range choices {
+		choices[i].CustomID = "decision:" + id + ":" + choices[i].CustomID
+	}
+
+	return buttons(choices...)
+}
+
+func interactionCreate(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	environment := environmentByChannel(interaction.ChannelID)
+	if interaction.Member == nil || !guildAllowed(interaction.GuildID) {
+		return
+	}
+	rememberChannel(interaction.ChannelID, interaction.GuildID)
+	cacheMember(interaction.GuildID, interaction.Member)
+
+	if interaction.Type == discordgo.InteractionApplicationCommand {
+		handler, ok := slashHandlers[interaction.ApplicationCommandData().Name]
+		switch {
+		case !ok:
+		case environment == nil:
+			respondEphemeral(session, interaction, text(interaction.ChannelID, "interaction.not_deployment_channel"))
+		case !slices.Contains(interaction.Member.Roles, environment.Role):
+			respondEphemeral(session, interaction, text(interaction.ChannelID, "interaction.forbidden"))
+		default:
+			handler(session, interaction)
+		}
+		return
+	}
+
+	if environment == nil {
+		return
+	}
+
+	var id []string
+	var handler func(*discordgo.Session, *discordgo.InteractionCreate, []string)
+	switch interaction.Type {
+	case discordgo.InteractionMessageComponent:
+		id = strings.Split(interaction.MessageComponentData().CustomID, ":")
+		handler = componentHandlers[id[0]]
+	case discordgo.InteractionModalSubmit:
+		id = strings.Split(interaction.ModalSubmitData().CustomID, ":")
+		handler = modalHandlers[id[0]]
+	}
+
+	if handler == nil {
+		return
+	}
+
+	if !slices.Contains(interaction.Member.Roles, environment.Role) {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	handler(session, interaction, id[1:])
+}
+
+func respondEphemeral(session *discordgo.Session, interaction *discordgo.InteractionCreate, content string) {
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func respondUpdate(session *discordgo.Session, interaction *discordgo.InteractionCreate, content string) error {
+	return session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func buttons(buttons ...discordgo.Button) []discordgo.MessageComponent {
+	row := discordgo.ActionsRow{}
+	for _, button := range buttons {
+		row.Components = append(row.Components, button)
+	}
+
+	return []discordgo.MessageComponent{row}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const reconnectGrace = 30 * time.Second
+
+var started = time.Now()
+
+var gateway struct {
+	mu        sync.Mutex
+	lost      time.Time
+	announced bool
+	timer     *time.Timer
+}
+
+func uptime() string {
+	return time.Since(started).Round(time.Second).String()
+}
+
+func announce(session *discordgo.Session, content string, color int) {
+	channels := map[string]bool{}
+	for _, environment := range Environments {
+		channels[environment.Channel] = true
+	}
+
+	for _, channel := range slices.Sorted(maps.Keys(channels)) {
+		session.ChannelMessageSend(channel, content)
+	}
+
+	publish(&Event{
+		Type:        "bot.status",
+		Title:       "Bot Status",
+		Description: content,
+		Color:       color,
+		Fields: []Field{
+			{Name: "Version", Value: versionString(), Inline: true},
+			{Name: "Uptime", Value: uptime(), Inline: true},
+		},
+	})
+}
+
+func disconnected(session *discordgo.Session, _ *discordgo.Disconnect) {
+	gateway.mu.Lock()
+	defer gateway.mu.Unlock()
+
+	if !gateway.lost.IsZero() {
+		return
+	}
+
+	log.Println("Lost the gateway connection, reconnecting...")
+	gateway.lost = time.Now()
+	gateway.timer = time.AfterFunc(reconnectGrace, func() {
+		gateway.mu.Lock()
+		if gateway.lost.IsZero() {
+			gateway.mu.Unlock()
+			return
+		}
+		gateway.announced = true
+		gateway.mu.Unlock()
+
+		announce(session, fmt.Sprintf("Deploy bot lost its gateway connection %s ago and is trying to reconnect.", reconnectGrace), 0xcc8400)
+	})
+}
+
+func connected(session *discordgo.Session, _ *discordgo.Connect) {
+	gateway.mu.Lock()
+	if gateway.lost.IsZero() {
+		gateway.mu.Unlock()
+		return
+	}
+
+	downtime := time.Since(gateway.lost).Round(time.Second)
+	announced := gateway.announced
+	gateway.timer.Stop()
+	gateway.lost, gateway.announced = time.Time{}, false
+	gateway.mu.Unlock()
+
+	log.Printf("Gateway connection restored after %s.", downtime)
+	if announced {
+		announce(session, fmt.Sprintf("Deploy bot reconnected after %s offline.", downtime), 0x008000)
+	}
+}
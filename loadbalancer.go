@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"deploy/engine"
+)
+
+type balancer interface {
+	Drain(ctx context.Context, target string) error
+	Register(ctx context.Context, target string) error
+}
+
+func newBalancer(config *LoadBalancer) (balancer, error) {
+	switch config.Type {
+	case "alb":
+		return &albBalancer{config: config}, nil
+	case "haproxy":
+		return &haproxyBalancer{config: config}, nil
+	}
+
+	return nil, fmt.Errorf("unknown load balancer type %q", config.Type)
+}
+
+func balancerTimeout(config *LoadBalancer) time.Duration {
+	if timeout, err := time.ParseDuration(config.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return 5 * time.Minute
+}
+
+func balancerTarget(deployment *Deployment, target string) string {
+	if deployment.Entry.Balancer.Target == "" {
+		return target
+	}
+	return deployment.expand(deployment.Entry.Balancer.Target, "${TARGET}", target)
+}
+
+func (deployment *Deployment) deployBehindBalancer(ctx context.Context, command, target string, output *bytes.Buffer) error {
+	lb, err := newBalancer(deployment.Entry.Balancer)
+	if err != nil {
+		return err
+	}
+	id := balancerTarget(deployment, target)
+
+	fmt.Fprintf(output, "Draining %s from the load balancer\n", id)
+	if err := lb.Drain(ctx, id); err != nil {
+		return fmt.Errorf("drain: %w", err)
+	}
+
+	out, err := deployment.execute(ctx, "", command, "${TARGET}", target)
+	output.Write(out)
+	if err == nil {
+		err = healthyTargets(ctx, deployment, []string{target})
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(output, "Registering %s with the load balancer\n", id)
+	if err := lb.Register(ctx, id); err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+
+	return nil
+}
+
+func runBalancerStep(ctx context.Context, deployment *Deployment, request *engine.Request, step *Step, output *bytes.Buffer) error {
+	if deployment.Entry.Balancer == nil {
+		return fmt.Errorf("%s steps need a load_balancer on the dictionary entry", step.Type)
+	}
+
+	lb, err := newBalancer(deployment.Entry.Balancer)
+	if err != nil {
+		return err
+	}
+
+	target := balancerTarget(deployment, request.Expand(step.With["target"]))
+
+	if step.Type == "drain" {
+		fmt.Fprintf(output, "Draining %s from the load balancer\n", target)
+		return lb.Drain(ctx, target)
+	}
+
+	fmt.Fprintf(output, "Registering %s with the load balancer\n", target)
+	return lb.Register(ctx, target)
+}
+
+type albBalancer struct {
+	config *LoadBalancer
+}
+
+func (lb *albBalancer) call(ctx context.Context, action, target string, result any) error {
+	form := url.Values{"Action": {action}, "Version": {"2015-12-01"}, "TargetGroupArn": {lb.config.TargetGroup}, "Targets.member.1.Id": {target}}
+	if lb.config.Port > 0 {
+		form.Set("Targets.member.1.Port", strconv.Itoa(lb.config.Port))
+	}
+	body := []byte(form.Encode())
+
+	credentials := awsCredentials()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://elasticloadbalancing."+credentials.Region+".amazonaws.com/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signAWSRequest(req, body, credentials, "elasticloadbalancing", time.Now())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http.Do(): %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", action, res.Status, strings.TrimSpace(string(message)))
+	}
+
+	if result != nil {
+		if err := xml.NewDecoder(res.Body).Decode(result); err != nil {
+			return fmt.Errorf("xml.Decode(): %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (lb *albBalancer) state(ctx context.Context, target string) (string, error) {
+	result := struct {
+		States []string `xml:"DescribeTargetHealthResult>TargetHealthDescriptions>member>TargetHealth>State"`
+	}{}
+	if err := lb.call(ctx, "DescribeTargetHealth", target, &result); err != nil {
+		return "", err
+	}
+	if len(result.States) == 0 {
+		return "unused", nil
+	}
+	return result.States[0], nil
+}
+
+func (lb *albBalancer) wait(ctx context.Context, target string, states ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, balancerTimeout(lb.config))
+	defer cancel()
+
+	var state string
+	err := poll(ctx, 5*time.Second, func() (bool, error) {
+		var err error
+		state, err = lb.state(ctx, target)
+		for _, want := range states {
+			if state == want {
+				return true, err
+			}
+		}
+		return false, err
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("target %s still %s after %s", target, state, balancerTimeout(lb.config))
+	}
+	return err
+}
+
+func (lb *albBalancer) Drain(ctx context.Context, target string) error {
+	if err := lb.call(ctx, "DeregisterTargets", target, nil); err != nil {
+		return err
+	}
+	return lb.wait(ctx, target, "unused")
+}
+
+func (lb *albBalancer) Register(ctx context.Context, target string) error {
+	if err := lb.call(ctx, "RegisterTargets", target, nil); err != nil {
+		return err
+	}
+	return lb.wait(ctx, target, "healthy")
+}
+
+type haproxyBalancer struct {
+	config *LoadBalancer
+}
+
+func (lb *haproxyBalancer) command(ctx context.Context, command string) (string, error) {
+	network := "tcp"
+	if strings.HasPrefix(lb.config.Socket, "/") {
+		network = "unix"
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, lb.config.Socket)
+	if err != nil {
+		return "", fmt.Errorf("net.Dial(): %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := io.WriteString(conn, command+"\n"); err != nil {
+		return "", err
+	}
+
+	response, err := io.ReadAll(bufio.NewReader(conn))
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSpace(string(response))
+	if text != "" && !strings.HasPrefix(text, "#") {
+		return "", fmt.Errorf("haproxy %q: %s", command, text)
+	}
+	return text, nil
+}
+
+func (lb *haproxyBalancer) sessions(ctx context.Context, target string) (int, error) {
+	stats, err := lb.command(ctx, "show stat")
+	if err != nil {
+		return 0, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(stats, "# ")))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		return 0, fmt.Errorf("could not parse haproxy stats: %v", err)
+	}
+
+	column := -1
+	for i, name := range records[0] {
+		if name == "scur" {
+			column = i
+		}
+	}
+
+	for _, record := range records[1:] {
+		if len(record) > column && column >= 0 && record[0] == lb.config.Backend && record[1] == target {
+			return strconv.Atoi(record[column])
+		}
+	}
+
+	return 0, fmt.Errorf("server %s/%s not found", lb.config.Backend, target)
+}
+
+func (lb *haproxyBalancer) Drain(ctx context.Context, target string) error {
+	server := lb.config.Backend + "/" + target
+	if _, err := lb.command(ctx, "set server "+server+" state drain"); err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, balancerTimeout(lb.config))
+	defer cancel()
+
+	if err := poll(waitCtx, 2*time.Second, func() (bool, error) {
+		current, err := lb.sessions(waitCtx, target)
+		return current == 0, err
+	}); err != nil {
+		return fmt.Errorf("waiting for %s to drain: %w", server, err)
+	}
+
+	_, err := lb.command(ctx, "set server "+server+" state maint")
+	return err
+}
+
+func (lb *haproxyBalancer) Register(ctx context.Context, target string) error {
+	_, err := lb.command(ctx, "set server "+lb.config.Backend+"/"+target+" state ready")
+	return err
+}
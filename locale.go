@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"deploy/locale"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var (
+	messages      locale.Catalog
+	channelGuilds sync.Map
+)
+
+func rememberChannel(channelID, guildID string) {
+	if channelID == "" || guildID == "" {
+		return
+	}
+
+	if current, ok := channelGuilds.Load(channelID); !ok || current != guildID {
+		channelGuilds.Store(channelID, guildID)
+	}
+}
+
+func localeOf(channelID string) string {
+	lang := strings.ToLower(data.Locale)
+	if guild, ok := channelGuilds.Load(channelID); ok {
+		store.View(func(state *State) {
+			if configured := state.Locales[guild.(string)]; configured != "" {
+				lang = configured
+			}
+		})
+	}
+
+	if !locale.Valid(lang) {
+		return locale.Default
+	}
+	return lang
+}
+
+func text(channelID, key string, vars ...any) string {
+	values := map[string]any{}
+	for i := 0; i+1 < len(vars); i += 2 {
+		values[fmt.Sprint(vars[i])] = vars[i+1]
+	}
+
+	return messages.Text(localeOf(channelID), key, values)
+}
+
+func (deployment *Deployment) text(channelID, key string, vars ...any) string {
+	return text(channelID, key, append([]any{"id", deployment.ID, "environment", deployment.Environment.Name, "key", deployment.Key, "branch", deployment.Branch, "requester", deployment.Author.ID}, vars...)...)
+}
+
+func textError(channelID, key string, vars ...any) error {
+	return errors.New(text(channelID, key, vars...))
+}
+
+func setLocale(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	available := strings.Join(locale.Supported, ", ")
+	if len(args) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "locale.current", "locale", localeOf(message.ChannelID), "available", available))
+		return
+	}
+
+	if data.AdminRole == "" || !slices.Contains(message.Member.Roles, data.AdminRole) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	lang := strings.ToLower(args[0])
+	if !locale.Valid(lang) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "locale.invalid", "locale", args[0], "available", available))
+		return
+	}
+
+	store.Update(func(state *State) { state.Locales[message.GuildID] = lang })
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "locale.set", "locale", lang))
+}
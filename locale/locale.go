@@ -0,0 +1,130 @@
+package locale
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const Default = "en"
+
+//go:embed *.json
+var files embed.FS
+
+var Supported = []string{"de", "en", "fr", "pt"}
+
+type Catalog map[string]map[string]*template.Template
+
+func Load() (Catalog, error) {
+	catalog := Catalog{}
+	for _, lang := range Supported {
+		body, err := files.ReadFile(lang + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("files.ReadFile(): %w", err)
+		}
+
+		messages := map[string]string{}
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return nil, fmt.Errorf("%s: json.Unmarshal(): %w", lang, err)
+		}
+
+		for key, text := range messages {
+			if err := catalog.Set(lang, key, text); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, lang := range Supported {
+		for key := range catalog[lang] {
+			if catalog[Default][key] == nil {
+				return nil, fmt.Errorf("%s: message %s is missing from %s", lang, key, Default)
+			}
+		}
+	}
+
+	return catalog, nil
+}
+
+func (catalog Catalog) Set(lang, key, text string) error {
+	tmpl, err := template.New(key).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return fmt.Errorf("%s: message %s: %w", lang, key, err)
+	}
+
+	if catalog[lang] == nil {
+		catalog[lang] = map[string]*template.Template{}
+	}
+	catalog[lang][key] = tmpl
+	return nil
+}
+
+func (catalog Catalog) LoadOverrides(file string) error {
+	body, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	overrides := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &overrides); err != nil {
+		return fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+
+	for key, raw := range overrides {
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			for _, lang := range Supported {
+				if err := catalog.override(lang, key, text); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		localized := map[string]string{}
+		if err := json.Unmarshal(raw, &localized); err != nil || !Valid(key) {
+			return fmt.Errorf("%s: expected a message text or an object of %s messages", key, strings.Join(Supported, ", "))
+		}
+		for name, text := range localized {
+			if err := catalog.override(key, name, text); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (catalog Catalog) override(lang, key, text string) error {
+	if catalog[Default][key] == nil {
+		return fmt.Errorf("unknown message %s", key)
+	}
+	return catalog.Set(lang, key, text)
+}
+
+func Valid(lang string) bool {
+	return slices.Contains(Supported, strings.ToLower(lang))
+}
+
+func (catalog Catalog) Text(lang, key string, vars map[string]any) string {
+	tmpl := catalog[lang][key]
+	if tmpl == nil {
+		tmpl = catalog[Default][key]
+	}
+	if tmpl == nil {
+		return key
+	}
+
+	out := &bytes.Buffer{}
+	if err := tmpl.Execute(out, vars); err != nil {
+		return key
+	}
+	return out.String()
+}
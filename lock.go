@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const defaultLockDuration = time.Hour
+
+type Lock struct {
+	Author   string    `json:"author"`
+	Username string    `json:"username"`
+	Reason   string    `json:"reason"`
+	Since    time.Time `json:"since"`
+	Expires  time.Time `json:"expires"`
+}
+
+func (lock *Lock) describe(environment *Environment) string {
+	status := text(environment.Channel, "lock.status", "environment", environment.Name, "author", lock.Author, "since", lock.Since.Unix(), "expires", lock.Expires.Unix())
+	if lock.Reason != "" {
+		status += ": " + lock.Reason
+	}
+	return status
+}
+
+func lockOf(environment *Environment) (lock *Lock) {
+	store.View(func(state *State) { lock = state.Locks[environment.Name] })
+	if lock == nil || time.Now().Before(lock.Expires) {
+		return lock
+	}
+
+	store.Update(func(state *State) {
+		if state.Locks[environment.Name] == lock {
+			delete(state.Locks, environment.Name)
+		}
+	})
+	auditLock("expired", environment, lock, nil)
+	log.Printf("Lock expired. Username: %s (%s) - Environment: %s", lock.Username, lock.Author, environment.Name)
+	return nil
+}
+
+func notifyLock(event, title string, environment *Environment, author, reason string, color int) {
+	fields := []Field{{Name: "Environment", Value: environment.Name, Inline: true}}
+	if reason != "" {
+		fields = append(fields, Field{Name: "Reason", Value: reason})
+	}
+
+	publish(&Event{Type: event, Environment: environment.Name, Title: title, Color: color, Fields: fields, Author: author})
+}
+
+func lock(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+	current := lockOf(environment)
+
+	if len(args) == 0 || strings.ToLower(args[0]) == "status" {
+		if current == nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "lock.not_locked", "environment", environment.Name))
+			return
+		}
+		session.ChannelMessageSend(message.ChannelID, current.describe(environment)+".")
+		return
+	}
+
+	if current != nil && current.Author != message.Author.ID {
+		session.ChannelMessageSend(message.ChannelID, current.describe(environment)+".")
+		return
+	}
+
+	duration := defaultLockDuration
+	if d, err := time.ParseDuration(args[0]); err == nil && d > 0 {
+		duration, args = d, args[1:]
+	}
+
+	now := time.Now().UTC()
+	next := &Lock{
+		Author:   message.Author.ID,
+		Username: message.Author.Username,
+		Reason:   strings.Join(args, " "),
+		Since:    now,
+		Expires:  now.Add(duration),
+	}
+
+	if err := store.Update(func(state *State) { state.Locks[environment.Name] = next }); err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "lock.failed", "error", err.Error()))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, next.describe(environment)+".")
+	auditLock("acquired", environment, next, message.Author)
+	notifyLock("lock.acquired", "Environment Locked", environment, message.Author.ID, next.Reason, 0xcc8400)
+	log.Printf("Lock acquired. Username: %s (%s) - Environment: %s - Expires: %s", message.Author.Username, message.Author.ID, environment.Name, next.Expires.Format(time.RFC3339))
+}
+
+func unlock(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+	current := lockOf(environment)
+	if current == nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "lock.not_locked", "environment", environment.Name))
+		return
+	}
+
+	admin := data.AdminRole != "" && message.Member != nil && slices.Contains(message.Member.Roles, data.AdminRole)
+	if current.Author != message.Author.ID && !admin {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "lock.forbidden", "environment", environment.Name, "author", current.Author))
+		auditLock("denied", environment, current, message.Author)
+		return
+	}
+
+	if err := store.Update(func(state *State) { delete(state.Locks, environment.Name) }); err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "lock.unlock_failed", "error", err.Error()))
+		return
+	}
+
+	reason := ""
+	if current.Author != message.Author.ID {
+		reason = fmt.Sprintf("Lock held by %s (%s) released by an admin", current.Username, current.Author)
+	}
+
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "lock.unlocked", "environment", environment.Name))
+	auditLock("released", environment, current, message.Author)
+	notifyLock("lock.released", "Environment Unlocked", environment, message.Author.ID, reason, 0x008000)
+	log.Printf("Lock released. Username: %s (%s) - Environment: %s - Holder: %s (%s)", message.Author.Username, message.Author.ID, environment.Name, current.Username, current.Author)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var deploymentLogs = &history.Logs{Dir: "logs"}
+
+func storeLog(deployment *Deployment, output []byte) {
+	if err := deploymentLogs.Write(deployment.ID, []byte(cleanOutput(string(output)))); err != nil {
+		log.Printf("deploymentLogs.Write(): %v", err)
+	}
+}
+
+func findRecord(environment *Environment, id string) (found *Record) {
+	store.View(func(state *State) {
+		for _, record := range slices.Backward(state.History) {
+			if record.ID == id && record.Environment == environment.Name {
+				found = record
+				return
+			}
+		}
+	})
+	return found
+}
+
+func logs(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+	if len(args) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.usage"))
+		return
+	}
+
+	if strings.EqualFold(args[0], "search") {
+		searchLogs(session, message, environment, strings.Join(args[1:], " "))
+		return
+	}
+
+	if strings.EqualFold(args[0], "diff") {
+		diffLogs(session, message, environment, args[1:])
+		return
+	}
+
+	record := findRecord(environment, args[0])
+	if record == nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.not_found", "id", args[0], "environment", environment.Name))
+		return
+	}
+
+	output, err := deploymentLogs.Read(record.ID)
+	if err != nil {
+		if !errors.Is(err, history.ErrNoLog) {
+			log.Printf("deploymentLogs.Read(): %v", err)
+		}
+
+		if archiveEnabled() {
+			if link, err := presignArchive(archiveURL(record.Environment, record.ID)); err == nil {
+				session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.archived", "id", record.ID, "link", link))
+				return
+			}
+		}
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.missing", "id", record.ID))
+		return
+	}
+
+	session.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{
+		Content: text(message.ChannelID, "logs.summary", "id", record.ID, "key", record.Key, "ref", record.Ref, "status", record.Status, "author", record.Author, "started", record.Started.Unix()),
+		Files:   []*discordgo.File{{Name: record.ID + ".log", ContentType: "text/plain", Reader: bytes.NewReader(output)}},
+	})
+}
+
+func searchLogs(session *discordgo.Session, message *discordgo.MessageCreate, environment *Environment, query string) {
+	if strings.TrimSpace(query) == "" {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.search_usage"))
+		return
+	}
+
+	ids, records := []string{}, map[string]*Record{}
+	store.View(func(state *State) {
+		for _, record := range history.Recent(state.History, environment.Name, -1) {
+			ids = append(ids, record.ID)
+			records[record.ID] = record
+		}
+	})
+
+	match, err := deploymentLogs.Search(ids, query)
+	if err != nil {
+		log.Printf("deploymentLogs.Search(): %v", err)
+	}
+
+	if match == nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.search_none", "environment", environment.Name, "query", sanitizeOutput(query)))
+		return
+	}
+
+	record := records[match.ID]
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.search_match", "id", record.ID, "key", record.Key, "ref", record.Ref, "status", record.Status, "started", record.Started.Unix(), "line", truncate(sanitizeOutput(match.Line), 500)))
+}
+
+func diffLogs(session *discordgo.Session, message *discordgo.MessageCreate, environment *Environment, args []string) {
+	if len(args) < 2 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.diff_usage"))
+		return
+	}
+
+	records, outputs := make([]*Record, 2), make([][]byte, 2)
+	for i, id := range args[:2] {
+		if records[i] = findRecord(environment, id); records[i] == nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.not_found", "id", id, "environment", environment.Name))
+			return
+		}
+
+		var err error
+		if outputs[i], err = deploymentLogs.Normalized(records[i].ID); err != nil {
+			if !errors.Is(err, history.ErrNoLog) {
+				log.Printf("deploymentLogs.Normalized(): %v", err)
+			}
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.missing", "id", records[i].ID))
+			return
+		}
+	}
+
+	diff := history.Diff(outputs[0], outputs[1])
+	before, after := records[0], records[1]
+	header := text(message.ChannelID, "logs.diff_header", "before", before.ID, "before_key", before.Key, "before_ref", before.Ref, "before_status", before.Status, "after", after.ID, "after_key", after.Key, "after_ref", after.Ref, "after_status", after.Status)
+	if len(diff.Changes) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "logs.diff_identical", "header", header))
+		return
+	}
+
+	lines, step, added := []string{}, "", 0
+	for i, change := range diff.Changes {
+		if i == 0 || change.Step != step {
+			step = change.Step
+			lines = append(lines, "@@ "+step+" @@")
+		}
+
+		prefix := "- "
+		if change.Added {
+			prefix, added = "+ ", added+1
+		}
+		lines = append(lines, prefix+change.Line)
+	}
+
+	content := []string{text(message.ChannelID, "logs.diff_summary", "header", header, "added", added, "removed", len(diff.Changes)-added)}
+	if len(diff.Warnings) > 0 {
+		content = append(content, fmt.Sprintf("%s\n```\n%s\n```", text(message.ChannelID, "logs.diff_warnings"), truncate(sanitizeOutput(strings.Join(diff.Warnings, "\n")), 500)))
+	}
+	if len(diff.Versions) > 0 {
+		versions := []string{}
+		for _, version := range diff.Versions {
+			versions = append(versions, fmt.Sprintf("`%s` %s → %s", version.Name, version.From, version.To))
+		}
+		content = append(content, text(message.ChannelID, "logs.diff_versions")+"\n"+truncate(strings.Join(versions, "\n"), 500))
+	}
+
+	full := strings.Join(lines, "\n")
+	content = append(content, fmt.Sprintf("```diff\n%s\n```", truncate(sanitizeOutput(full), 700)))
+	session.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{
+		Content: strings.Join(content, "\n"),
+		Files:   []*discordgo.File{{Name: before.ID + "-" + after.ID + ".diff", ContentType: "text/plain", Reader: strings.NewReader(full)}},
+	})
+}
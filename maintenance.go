@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/executor"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type MaintenanceState struct {
+	Enabled bool      `json:"enabled"`
+	Author  string    `json:"author"`
+	Since   time.Time `json:"since"`
+}
+
+func inMaintenance(environment *Environment) (enabled bool) {
+	store.View(func(state *State) {
+		if maintenance, ok := state.Maintenance[environment.Name]; ok {
+			enabled = maintenance.Enabled
+		}
+	})
+
+	return enabled
+}
+
+func setMaintenance(ctx context.Context, environment *Environment, enabled bool, author string) ([]byte, error) {
+	if environment.Maintenance == nil {
+		return nil, fmt.Errorf("no maintenance commands configured for %s", environment.Name)
+	}
+
+	command := environment.Maintenance.Off
+	if enabled {
+		command = environment.Maintenance.On
+	}
+
+	output, err := executor.Run(ctx, strings.ReplaceAll(command, "${LOCATION}", environment.Location), executorOptions(environment.Shell, nil))
+	if err != nil {
+		return output, err
+	}
+
+	return output, store.Update(func(state *State) {
+		state.Maintenance[environment.Name] = &MaintenanceState{Enabled: enabled, Author: author, Since: time.Now().UTC()}
+	})
+}
+
+func maintenance(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 1 || (args[0] != "on" && args[0] != "off") {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.usage"))
+		return
+	}
+
+	name, environment := "", environmentByChannel(message.ChannelID)
+	if len(args) > 1 {
+		name, environment = args[1], Environments[args[1]]
+	}
+
+	if environment == nil || !slices.Contains(message.Member.Roles, environment.Role) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.invalid_environment", "environment", name))
+		return
+	}
+
+	enabled := args[0] == "on"
+	if inMaintenance(environment) == enabled {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.already", "state", args[0], "environment", environment.Name))
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		output, err := setMaintenance(ctx, environment, enabled, message.Author.ID)
+		if err != nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.toggle_failed", "error", err.Error()))
+			log.Printf("setMaintenance(): %v\n%s", err, string(output))
+			return
+		}
+
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.toggled", "state", args[0], "environment", environment.Name))
+		log.Printf("Maintenance %s. Username: %s (%s) - Environment: %s", args[0], message.Author.Username, message.Author.ID, environment.Name)
+	}()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type cachedMember struct {
+	member  *discordgo.Member
+	fetched time.Time
+}
+
+var (
+	memberCacheTTL = 5 * time.Minute
+	memberCacheMu  sync.Mutex
+	memberCache    = map[string]cachedMember{}
+)
+
+func parseMemberCacheTTL(value string) (time.Duration, error) {
+	if value == "" {
+		return memberCacheTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid member cache ttl %q, expected a duration, 0 disables the cache", value)
+	}
+
+	return ttl, nil
+}
+
+func cacheMember(guildID string, member *discordgo.Member) {
+	if memberCacheTTL == 0 || member == nil || member.User == nil {
+		return
+	}
+
+	key, fetched := guildID+":"+member.User.ID, time.Now()
+
+	memberCacheMu.Lock()
+	memberCache[key] = cachedMember{member: member, fetched: fetched}
+	memberCacheMu.Unlock()
+
+	time.AfterFunc(memberCacheTTL, func() {
+		memberCacheMu.Lock()
+		if memberCache[key].fetched.Equal(fetched) {
+			delete(memberCache, key)
+		}
+		memberCacheMu.Unlock()
+	})
+}
+
+func guildMember(session *discordgo.Session, guildID, userID string) (*discordgo.Member, error) {
+	memberCacheMu.Lock()
+	cached, ok := memberCache[guildID+":"+userID]
+	memberCacheMu.Unlock()
+	if ok && time.Since(cached.fetched) < memberCacheTTL {
+		return cached.member, nil
+	}
+
+	member, err := session.GuildMember(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheMember(guildID, member)
+	return member, nil
+}
+
+func guildMemberUpdate(session *discordgo.Session, update *discordgo.GuildMemberUpdate) {
+	if update.Member != nil {
+		cacheMember(update.GuildID, update.Member)
+	}
+}
+
+func guildMemberRemove(session *discordgo.Session, remove *discordgo.GuildMemberRemove) {
+	if remove.Member == nil || remove.User == nil {
+		return
+	}
+
+	memberCacheMu.Lock()
+	delete(memberCache, remove.GuildID+":"+remove.User.ID)
+	memberCacheMu.Unlock()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Gate struct {
+	Name     string   `json:"name"`
+	Provider string   `json:"provider"`
+	Query    string   `json:"query"`
+	Max      *float64 `json:"max"`
+	Min      *float64 `json:"min"`
+}
+
+type GateError struct {
+	Gate  *Gate
+	Value float64
+}
+
+func (err *GateError) Error() string {
+	if err.Gate.Max != nil && err.Value > *err.Gate.Max {
+		return fmt.Sprintf("gate %s breached: %g is above %g", err.Gate.Name, err.Value, *err.Gate.Max)
+	}
+	return fmt.Sprintf("gate %s breached: %g is below %g", err.Gate.Name, err.Value, *err.Gate.Min)
+}
+
+var errNoData = errors.New("query returned no data")
+
+var metricProviders = map[string]func(ctx context.Context, query string) (float64, error){
+	"prometheus": queryPrometheus,
+	"datadog":    queryDatadog,
+}
+
+func (gate *Gate) compile() error {
+	if _, ok := metricProviders[gate.Provider]; !ok {
+		return fmt.Errorf("gate %s: unknown provider %q, expected prometheus or datadog", gate.Name, gate.Provider)
+	}
+
+	if gate.Query == "" || (gate.Max == nil && gate.Min == nil) {
+		return fmt.Errorf("gate %s: needs a query and a max or min", gate.Name)
+	}
+
+	switch gate.Provider {
+	case "prometheus":
+		if data.PrometheusURL == "" {
+			return fmt.Errorf("gate %s: PROMETHEUS_URL is not set", gate.Name)
+		}
+	case "datadog":
+		if data.DatadogAPIKey == "" || data.DatadogAppKey == "" {
+			return fmt.Errorf("gate %s: DATADOG_API_KEY and DATADOG_APP_KEY are not set", gate.Name)
+		}
+	}
+
+	return nil
+}
+
+func (gate *Gate) evaluate(ctx context.Context, deployment *Deployment) error {
+	value, err := metricProviders[gate.Provider](ctx, deployment.expand(gate.Query))
+	if err != nil {
+		return fmt.Errorf("gate %s: %w", gate.Name, err)
+	}
+
+	if (gate.Max != nil && value > *gate.Max) || (gate.Min != nil && value < *gate.Min) {
+		return &GateError{Gate: gate, Value: value}
+	}
+
+	return nil
+}
+
+func queryPrometheus(ctx context.Context, query string) (float64, error) {
+	var response struct {
+		Data struct {
+			Result []struct {
+				Value [2]any `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+
+	target := strings.TrimSuffix(data.PrometheusURL, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	if _, err := apiRequest(ctx, http.MethodGet, target, nil, func(req *http.Request) {
+		if data.PrometheusToken != "" {
+			req.Header.Set("Authorization", "Bearer "+data.PrometheusToken)
+		}
+	}, &response); err != nil {
+		return 0, err
+	}
+
+	if len(response.Data.Result) == 0 {
+		return 0, errNoData
+	}
+
+	sample, ok := response.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, errors.New("unexpected prometheus sample")
+	}
+	return strconv.ParseFloat(sample, 64)
+}
+
+func queryDatadog(ctx context.Context, query string) (float64, error) {
+	var response struct {
+		Series []struct {
+			Pointlist [][2]*float64 `json:"pointlist"`
+		} `json:"series"`
+	}
+
+	now := time.Now()
+	target := fmt.Sprintf("https://api.%s/api/v1/query?%s", data.DatadogSite, url.Values{
+		"query": {query},
+		"from":  {strconv.FormatInt(now.Add(-5*time.Minute).Unix(), 10)},
+		"to":    {strconv.FormatInt(now.Unix(), 10)},
+	}.Encode())
+	if _, err := apiRequest(ctx, http.MethodGet, target, nil, func(req *http.Request) {
+		req.Header.Set("DD-API-KEY", data.DatadogAPIKey)
+		req.Header.Set("DD-APPLICATION-KEY", data.DatadogAppKey)
+	}, &response); err != nil {
+		return 0, err
+	}
+
+	if len(response.Series) == 0 {
+		return 0, errNoData
+	}
+
+	points := response.Series[0].Pointlist
+	for i := len(points) - 1; i >= 0; i-- {
+		if points[i][1] != nil {
+			return *points[i][1], nil
+		}
+	}
+	return 0, errNoData
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/engine"
+)
+
+type nomadDeployment struct {
+	ID                string `json:"ID"`
+	JobVersion        uint64 `json:"JobVersion"`
+	Status            string `json:"Status"`
+	StatusDescription string `json:"StatusDescription"`
+	TaskGroups        map[string]struct {
+		DesiredTotal    int `json:"DesiredTotal"`
+		HealthyAllocs   int `json:"HealthyAllocs"`
+		UnhealthyAllocs int `json:"UnhealthyAllocs"`
+	} `json:"TaskGroups"`
+}
+
+func (deployment *nomadDeployment) summary() string {
+	groups := []string{}
+	for _, name := range slices.Sorted(maps.Keys(deployment.TaskGroups)) {
+		group := deployment.TaskGroups[name]
+		groups = append(groups, fmt.Sprintf("%s %d/%d healthy", name, group.HealthyAllocs, group.DesiredTotal))
+		if group.UnhealthyAllocs > 0 {
+			groups[len(groups)-1] += fmt.Sprintf(", %d unhealthy", group.UnhealthyAllocs)
+		}
+	}
+	return fmt.Sprintf("Nomad: %s (%s)", deployment.Status, strings.Join(groups, "; "))
+}
+
+type nomadClient struct {
+	address   string
+	token     string
+	namespace string
+}
+
+func (client *nomadClient) do(ctx context.Context, method, path string, body any, result any) error {
+	target := client.address + path
+	if client.namespace != "" {
+		target += "?namespace=" + url.QueryEscape(client.namespace)
+	}
+
+	_, err := apiRequest(ctx, method, target, body, func(req *http.Request) {
+		if client.token != "" {
+			req.Header.Set("X-Nomad-Token", client.token)
+		}
+	}, result)
+	return err
+}
+
+func (client *nomadClient) parse(ctx context.Context, spec []byte, format string) (json.RawMessage, error) {
+	if format == ".json" {
+		wrapped := struct {
+			Job json.RawMessage `json:"Job"`
+		}{}
+		if err := json.Unmarshal(spec, &wrapped); err == nil && len(wrapped.Job) > 0 {
+			return wrapped.Job, nil
+		}
+		return spec, nil
+	}
+
+	job := json.RawMessage{}
+	if err := client.do(ctx, http.MethodPost, "/v1/jobs/parse", map[string]any{"JobHCL": string(spec), "Canonicalize": true}, &job); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	return job, nil
+}
+
+func (client *nomadClient) revert(ctx context.Context, id string, failed uint64) (uint64, error) {
+	versions := struct {
+		Versions []struct {
+			Version uint64 `json:"Version"`
+			Stable  bool   `json:"Stable"`
+		} `json:"Versions"`
+	}{}
+	if err := client.do(ctx, http.MethodGet, "/v1/job/"+url.PathEscape(id)+"/versions", nil, &versions); err != nil {
+		return 0, err
+	}
+
+	for _, version := range versions.Versions {
+		if version.Stable && version.Version < failed {
+			return version.Version, client.do(ctx, http.MethodPost, "/v1/job/"+url.PathEscape(id)+"/revert", map[string]any{"JobID": id, "JobVersion": version.Version}, nil)
+		}
+	}
+
+	return 0, errors.New("no stable version to revert to")
+}
+
+func runNomad(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer, report func(string)) error {
+	with := map[string]string{}
+	for key, value := range step.With {
+		with[key] = request.Expand(value)
+	}
+
+	if with["job"] == "" {
+		return errors.New("nomad steps need a job spec")
+	}
+
+	client := &nomadClient{address: strings.TrimSuffix(with["address"], "/"), token: secret(with["token"], os.Getenv(with["token"])), namespace: with["namespace"]}
+	if client.address == "" {
+		client.address = strings.TrimSuffix(os.Getenv("NOMAD_ADDR"), "/")
+	}
+	if client.address == "" {
+		client.address = "http://127.0.0.1:4646"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, stepTimeout(step))
+	defer cancel()
+
+	path := with["job"]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(request.Location, path)
+	}
+
+	spec, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	job, err := client.parse(ctx, []byte(request.Expand(string(spec))), filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+
+	meta := struct {
+		ID string `json:"ID"`
+	}{}
+	if err := json.Unmarshal(job, &meta); err != nil || meta.ID == "" {
+		return fmt.Errorf("job spec %s has no ID", with["job"])
+	}
+
+	if err := client.do(ctx, http.MethodPost, "/v1/jobs", map[string]any{"Job": job}, nil); err != nil {
+		return fmt.Errorf("register %s: %w", meta.ID, err)
+	}
+
+	current := struct {
+		Version uint64 `json:"Version"`
+	}{}
+	if err := client.do(ctx, http.MethodGet, "/v1/job/"+url.PathEscape(meta.ID), nil, &current); err != nil {
+		return err
+	}
+	fmt.Fprintf(output, "Submitted Nomad job %s version %d\n", meta.ID, current.Version)
+
+	deployment, waited := &nomadDeployment{}, time.Now()
+	err = poll(ctx, pollInterval(with), func() (bool, error) {
+		deployments := []*nomadDeployment{}
+		if err := client.do(ctx, http.MethodGet, "/v1/job/"+url.PathEscape(meta.ID)+"/deployments", nil, &deployments); err != nil {
+			return false, err
+		}
+
+		index := slices.IndexFunc(deployments, func(candidate *nomadDeployment) bool { return candidate.JobVersion == current.Version })
+		if index < 0 {
+			return time.Since(waited) > 30*time.Second, nil
+		}
+
+		deployment = deployments[index]
+		report(deployment.summary())
+		switch deployment.Status {
+		case "successful":
+			return true, nil
+		case "failed", "cancelled":
+			return false, fmt.Errorf("deployment %s: %s", deployment.Status, deployment.StatusDescription)
+		}
+		return false, nil
+	})
+
+	if deployment.ID == "" && err == nil {
+		fmt.Fprintf(output, "No Nomad deployment was created for %s version %d\n", meta.ID, current.Version)
+		return nil
+	}
+	fmt.Fprintln(output, deployment.summary())
+
+	if err == nil {
+		return nil
+	}
+
+	if deployment.ID != "" && deployment.Status == "running" {
+		failCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if failErr := client.do(failCtx, http.MethodPost, "/v1/deployment/fail/"+deployment.ID, nil, nil); failErr != nil {
+			fmt.Fprintf(output, "Could not mark deployment %s as failed: %v\n", deployment.ID, failErr)
+		}
+	}
+
+	if with["rollback"] == "true" {
+		revertCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if version, revertErr := client.revert(revertCtx, meta.ID, current.Version); revertErr != nil {
+			fmt.Fprintf(output, "Rollback of %s failed: %v\n", meta.ID, revertErr)
+		} else {
+			fmt.Fprintf(output, "Reverted %s to stable version %d\n", meta.ID, version)
+			return fmt.Errorf("nomad %s: %w, reverted to version %d", meta.ID, err, version)
+		}
+	}
+
+	return fmt.Errorf("nomad %s: %w", meta.ID, err)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const maxNoteLength = 500
+
+func note(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+	if len(args) < 2 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "note.missing"))
+		return
+	}
+
+	content := strings.TrimSpace(strings.Join(args[1:], " "))
+	if len(content) > maxNoteLength {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "note.too_long", "limit", maxNoteLength))
+		return
+	}
+
+	var record *Record
+	err := store.Update(func(state *State) {
+		for _, candidate := range slices.Backward(state.History) {
+			if candidate.ID == args[0] && candidate.Environment == environment.Name {
+				record = candidate
+				record.Notes = append(record.Notes, &Note{Author: message.Author.ID, Username: message.Author.Username, Text: content, Created: time.Now().UTC()})
+				return
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("store.Update(): %v", err)
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "note.failed"))
+		return
+	}
+
+	if record == nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "note.not_found", "id", args[0], "environment", environment.Name))
+		return
+	}
+
+	log.Printf("Note added to deployment %s. Username: %s (%s)", record.ID, message.Author.Username, message.Author.ID)
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "note.added", "id", record.ID, "key", record.Key, "ref", record.Ref, "count", len(record.Notes)))
+}
+
+func showStatus(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment := environmentByChannel(message.ChannelID)
+
+	var record *Record
+	if len(args) > 0 {
+		record = findRecord(environment, args[0])
+	} else {
+		store.View(func(state *State) {
+			if recent := history.Recent(state.History, environment.Name, 1); len(recent) > 0 {
+				record = recent[0]
+			}
+		})
+	}
+
+	if record == nil {
+		if len(args) > 0 {
+			if current := deploymentStatus(args[0]); current == "running" || current == "queued" {
+				session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "status.active", "id", args[0], "status", current))
+				return
+			}
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "status.not_found", "id", args[0], "environment", environment.Name))
+			return
+		}
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "status.none"))
+		return
+	}
+
+	lines := []string{text(message.ChannelID, "status.summary", "id", record.ID, "key", record.Key, "ref", record.Ref, "sha", fmt.Sprintf("%.7s", record.SHA), "status", record.Status, "author", record.Author, "started", record.Started.Unix(), "duration", record.Duration)}
+	if record.Reason != "" {
+		lines = append(lines, text(message.ChannelID, "status.reason", "reason", truncate(record.Reason, 300)))
+	}
+	if record.Error != "" {
+		lines = append(lines, text(message.ChannelID, "status.error", "error", truncate(sanitizeOutput(record.Error), 300)))
+	}
+
+	if len(record.Notes) > 0 {
+		lines = append(lines, text(message.ChannelID, "status.notes"))
+		for _, note := range record.Notes {
+			lines = append(lines, fmt.Sprintf("• <@%s> <t:%d:R>: %s", note.Author, note.Created.Unix(), note.Text))
+		}
+	}
+
+	session.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{
+		Content:         truncate(strings.Join(lines, "\n"), 2000),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
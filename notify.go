@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"deploy/notify"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const deploymentThumbnail = "https://r2.fivemanage.com/3i2fhQIkHIaRFDy1YIvi8/images/image.png"
+
+type (
+	Field = notify.Field
+	Event = notify.Event
+)
+
+var notifications = &notify.Dispatcher{}
+
+func loadNotifiers(session *discordgo.Session, file string) error {
+	notify.Register("discord", func(sink *notify.Sink) (notify.Notifier, error) {
+		if sink.URL != "" {
+			return &notify.DiscordNotifier{URL: sink.URL}, nil
+		}
+
+		return notify.NotifierFunc(func(ctx context.Context, event *Event) error {
+			return (&notify.DiscordNotifier{URL: secret("DEPLOYMENT_LOG_WEBHOOK", data.DeploymentLogWebhook)}).Notify(ctx, event)
+		}), nil
+	})
+	notify.Register("channel", func(sink *notify.Sink) (notify.Notifier, error) {
+		return &ChannelNotifier{Session: session, Channel: sink.Channel}, nil
+	})
+	notify.Register("email", func(sink *notify.Sink) (notify.Notifier, error) {
+		return notify.NewEmailNotifier(sink, notify.SMTP{
+			Host:     data.SMTPHost,
+			Port:     data.SMTPPort,
+			Username: data.SMTPUsername,
+			Password: secret("SMTP_PASSWORD", data.SMTPPassword),
+			From:     data.SMTPFrom,
+		})
+	})
+
+	dispatcher, err := notify.Load(file)
+	if err != nil {
+		return err
+	}
+
+	notifications = dispatcher
+	return nil
+}
+
+func publish(event *Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	notifications.Notify(ctx, event)
+}
+
+func notifyDeployment(status, environment, branch, author, reason string, output []byte, extra ...Field) {
+	publish(deploymentEvent(status, environment, branch, author, reason, output, extra...))
+}
+
+func (deployment *Deployment) notify(status, reason string, output []byte, extra ...Field) {
+	event := deploymentEvent(status, deployment.Environment.Name, deployment.Branch, deployment.Author.ID, reason, output, extra...)
+	event.Deployment, event.Username = deployment.ID, deployment.Author.Username
+	event.Previous = deployment.previousStatus()
+	if !deployment.Started.IsZero() {
+		event.Duration = time.Since(deployment.Started)
+	}
+
+	anomaly, slow := deployment.durationAnomaly(event.Duration)
+	if slow {
+		event.Fields = append(event.Fields, anomaly)
+	}
+
+	_, span := tracer.Start(deployment.context(), "notify")
+	publish(event)
+	if slow {
+		alert := *event
+		alert.Type, alert.Description, alert.Color, alert.Output = "deployment.anomaly", "Deployment Duration Anomaly!", 0xcc8400, nil
+		publish(&alert)
+	}
+	span.End()
+}
+
+func (deployment *Deployment) previousStatus() string {
+	previous := ""
+	store.View(func(state *State) {
+		for _, record := range slices.Backward(state.History) {
+			if record.Environment == deployment.Environment.Name && record.Key == deployment.Key && record.ID != deployment.ID {
+				previous = record.Status
+				return
+			}
+		}
+	})
+	return previous
+}
+
+func deploymentEvent(status, environment, branch, author, reason string, output []byte, extra ...Field) *Event {
+	color := 0x008000
+	description := "Deployment Successful!"
+	switch status {
+	case "failed":
+		color = 0x800000
+		description = "Deployment Failed!"
+	case "degraded":
+		color = 0xcc8400
+		description = "Deployment Degraded!"
+	}
+
+	fields := []Field{
+		{Name: "Environment", Value: environment, Inline: true},
+		{Name: "Branch", Value: branch, Inline: true},
+	}
+
+	if reason != "" {
+		fields = append(fields, Field{Name: "Reason", Value: reason})
+	}
+	fields = append(fields, extra...)
+	fields = append(fields, Field{Name: "Bot Version", Value: versionString(), Inline: true})
+
+	return &Event{
+		Type:        "deployment." + status,
+		Environment: environment,
+		Title:       "Deployment Status",
+		Description: description,
+		Color:       color,
+		Fields:      fields,
+		Author:      author,
+		Thumbnail:   deploymentThumbnail,
+		Output:      []byte(cleanOutput(string(output))),
+	}
+}
+
+type ChannelNotifier struct {
+	Session *discordgo.Session
+	Channel string
+}
+
+func (notifier *ChannelNotifier) Notify(ctx context.Context, event *Event) error {
+	embed := &discordgo.MessageEmbed{Title: event.Title, Description: event.Description, Color: event.Color, Timestamp: event.Time.Format(time.RFC3339)}
+	for _, field := range event.Fields {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: field.Name, Value: field.Value, Inline: field.Inline})
+	}
+
+	if event.Author != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "User ID: " + event.Author}
+	}
+
+	_, err := notifier.Session.ChannelMessageSendEmbed(notifier.Channel, embed, discordgo.WithContext(ctx))
+	return err
+}
@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+type AlertNotifier struct {
+	Service string
+	URL     string
+	Key     string
+}
+
+func newAlertNotifier(sink *Sink) (Notifier, error) {
+	if sink.Key == "" {
+		return nil, fmt.Errorf("%s sink requires a key", sink.Type)
+	}
+
+	if len(sink.Events) == 0 {
+		sink.Events = []string{"deployment.failed", "deployment.degraded"}
+	}
+
+	url := sink.URL
+	if url == "" {
+		url = map[string]string{"pagerduty": pagerDutyURL, "opsgenie": opsgenieURL}[sink.Type]
+	}
+
+	return &AlertNotifier{Service: sink.Type, URL: url, Key: sink.Key}, nil
+}
+
+func (notifier *AlertNotifier) details(event *Event) map[string]string {
+	details := map[string]string{"deployment": event.Deployment, "requester": fmt.Sprintf("%s (%s)", event.Username, event.Author)}
+	for _, field := range event.Fields {
+		details[field.Name] = field.Value
+	}
+	return details
+}
+
+func (notifier *AlertNotifier) Notify(ctx context.Context, event *Event) error {
+	summary := fmt.Sprintf("%s %s", event.Environment, event.Description)
+	if event.Deployment != "" {
+		summary += " (" + event.Deployment + ")"
+	}
+
+	alias := event.Deployment
+	if alias == "" {
+		alias = fmt.Sprintf("%s-%d", event.Environment, event.Time.Unix())
+	}
+
+	switch notifier.Service {
+	case "pagerduty":
+		severity := "critical"
+		if event.Type == "deployment.degraded" || event.Type == "deployment.anomaly" {
+			severity = "warning"
+		}
+
+		return PostJSON(ctx, notifier.URL, map[string]any{
+			"routing_key":  notifier.Key,
+			"event_action": "trigger",
+			"dedup_key":    "deploy-" + alias,
+			"payload": map[string]any{
+				"summary":        summary,
+				"source":         "deploy-bot",
+				"severity":       severity,
+				"timestamp":      event.Time.Format(time.RFC3339),
+				"component":      event.Environment,
+				"custom_details": notifier.details(event),
+			},
+		})
+	case "opsgenie":
+		message := summary
+		if len(message) > 130 {
+			message = message[:127] + "..."
+		}
+
+		priority := "P1"
+		if event.Type == "deployment.degraded" || event.Type == "deployment.anomaly" {
+			priority = "P3"
+		}
+
+		return PostJSON(ctx, notifier.URL, map[string]any{
+			"message":  message,
+			"alias":    "deploy-" + alias,
+			"priority": priority,
+			"source":   "deploy-bot",
+			"tags":     []string{"deploy", event.Environment},
+			"details":  notifier.details(event),
+		}, "Authorization", "GenieKey "+notifier.Key)
+	}
+
+	return errors.New("unknown alert service " + notifier.Service)
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func eventText(event *Event) string {
+	lines := []string{event.Title, event.Description}
+	for _, field := range event.Fields {
+		lines = append(lines, field.Name+": "+field.Value)
+	}
+
+	if event.Author != "" {
+		lines = append(lines, "User ID: "+event.Author)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func eventHTML(event *Event) string {
+	lines := []string{"<b>" + html.EscapeString(event.Title) + "</b>", html.EscapeString(event.Description)}
+	for _, field := range event.Fields {
+		lines = append(lines, "<b>"+html.EscapeString(field.Name)+":</b> "+html.EscapeString(field.Value))
+	}
+
+	if event.Author != "" {
+		lines = append(lines, "<i>User ID: "+html.EscapeString(event.Author)+"</i>")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+type TelegramNotifier struct {
+	Token string
+	Chat  string
+}
+
+func newTelegramNotifier(sink *Sink) (Notifier, error) {
+	if sink.Key == "" || sink.Channel == "" {
+		return nil, errors.New("telegram sink requires a bot token in key and a chat id in channel")
+	}
+
+	return &TelegramNotifier{Token: sink.Key, Chat: sink.Channel}, nil
+}
+
+func (notifier *TelegramNotifier) Notify(ctx context.Context, event *Event) error {
+	return PostJSON(ctx, "https://api.telegram.org/bot"+notifier.Token+"/sendMessage", map[string]any{
+		"chat_id":    notifier.Chat,
+		"text":       eventHTML(event),
+		"parse_mode": "HTML",
+	})
+}
+
+type MatrixNotifier struct {
+	Homeserver string
+	Room       string
+	Token      string
+}
+
+func newMatrixNotifier(sink *Sink) (Notifier, error) {
+	if sink.URL == "" || sink.Channel == "" || sink.Key == "" {
+		return nil, errors.New("matrix sink requires a homeserver url, a room id in channel and an access token in key")
+	}
+
+	return &MatrixNotifier{Homeserver: strings.TrimSuffix(sink.URL, "/"), Room: sink.Channel, Token: sink.Key}, nil
+}
+
+func (notifier *MatrixNotifier) Notify(ctx context.Context, event *Event) error {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", notifier.Homeserver, url.PathEscape(notifier.Room), strconv.FormatInt(time.Now().UnixNano(), 36))
+	return SendJSON(ctx, http.MethodPut, endpoint, map[string]any{
+		"msgtype":        "m.text",
+		"body":           eventText(event),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": strings.ReplaceAll(eventHTML(event), "\n", "<br>"),
+	}, "Authorization", "Bearer "+notifier.Token)
+}
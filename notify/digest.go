@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Digest struct {
+	Interval     string   `json:"interval"`
+	Events       []string `json:"events"`
+	Environments []string `json:"environments"`
+	Quiet        string   `json:"quiet"`
+
+	interval           time.Duration
+	quietFrom, quietTo time.Duration
+
+	mu      sync.Mutex
+	pending []*Event
+	timer   *time.Timer
+}
+
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func (digest *Digest) compile() (err error) {
+	if digest.interval, err = time.ParseDuration(digest.Interval); err != nil || digest.interval <= 0 {
+		return fmt.Errorf("invalid digest interval %q", digest.Interval)
+	}
+
+	if len(digest.Events) == 0 {
+		digest.Events = []string{"deployment.success"}
+	}
+
+	if digest.Quiet != "" {
+		from, to, ok := strings.Cut(digest.Quiet, "-")
+		if digest.quietFrom, err = parseClock(from); err != nil || !ok {
+			return fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", digest.Quiet)
+		}
+		if digest.quietTo, err = parseClock(to); err != nil {
+			return fmt.Errorf("invalid quiet hours %q, expected HH:MM-HH:MM", digest.Quiet)
+		}
+	}
+
+	return nil
+}
+
+func (digest *Digest) matches(event *Event) bool {
+	if len(digest.Environments) > 0 && !slices.Contains(digest.Environments, event.Environment) {
+		return false
+	}
+
+	return slices.ContainsFunc(digest.Events, func(pattern string) bool {
+		matched, err := path.Match(pattern, event.Type)
+		return err == nil && matched
+	})
+}
+
+func (digest *Digest) quietUntil(now time.Time) (time.Time, bool) {
+	if digest.Quiet == "" || digest.quietFrom == digest.quietTo {
+		return time.Time{}, false
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	clock := now.Sub(midnight)
+	switch {
+	case digest.quietFrom < digest.quietTo && clock >= digest.quietFrom && clock < digest.quietTo:
+		return midnight.Add(digest.quietTo), true
+	case digest.quietFrom > digest.quietTo && clock >= digest.quietFrom:
+		return midnight.Add(24*time.Hour + digest.quietTo), true
+	case digest.quietFrom > digest.quietTo && clock < digest.quietTo:
+		return midnight.Add(digest.quietTo), true
+	}
+	return time.Time{}, false
+}
+
+func (digest *Digest) add(sink *Sink, event *Event) {
+	digest.mu.Lock()
+	defer digest.mu.Unlock()
+
+	digest.pending = append(digest.pending, event)
+	if digest.timer != nil {
+		return
+	}
+
+	flush := time.Now().Add(digest.interval)
+	if until, quiet := digest.quietUntil(flush); quiet {
+		flush = until
+	}
+	digest.timer = time.AfterFunc(time.Until(flush), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		digest.flush(ctx, sink)
+	})
+}
+
+func (digest *Digest) flush(ctx context.Context, sink *Sink) {
+	digest.mu.Lock()
+	pending := digest.pending
+	if digest.timer != nil {
+		digest.timer.Stop()
+	}
+	digest.pending, digest.timer = nil, nil
+	digest.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := sink.Notifier.Notify(ctx, summarize(pending, sink.Type == "discord" || sink.Type == "channel")); err != nil {
+		log.Printf("%s.Notify(): %v", sink.Type, err)
+	}
+}
+
+// summarize renders timestamps as Discord markup only for Discord sinks,
+// other sinks would show it verbatim.
+func summarize(events []*Event, discord bool) *Event {
+	stamp := func(t time.Time) string { return t.UTC().Format("15:04 UTC") }
+	since := events[0].Time.UTC().Format(time.RFC3339)
+	if discord {
+		stamp = func(t time.Time) string { return fmt.Sprintf("<t:%d:t>", t.Unix()) }
+		since = fmt.Sprintf("<t:%d:R>", events[0].Time.Unix())
+	}
+
+	lines := map[string][]string{}
+	for _, event := range events {
+		line := stamp(event.Time) + " " + strings.TrimPrefix(event.Type, "deployment.")
+		if event.Deployment != "" {
+			line += " `" + event.Deployment + "`"
+		}
+		for _, field := range event.Fields {
+			if field.Name == "Branch" {
+				line += " `" + field.Value + "`"
+			}
+		}
+		if event.Username != "" {
+			line += " by " + event.Username
+		}
+		lines[event.Environment] = append(lines[event.Environment], line)
+	}
+
+	fields := []Field{}
+	for _, environment := range slices.Sorted(maps.Keys(lines)) {
+		value := strings.Join(lines[environment], "\n")
+		if len(value) > 1000 {
+			value = value[:997] + "..."
+		}
+
+		name := environment
+		if name == "" {
+			name = "Other"
+		}
+		fields = append(fields, Field{Name: fmt.Sprintf("%s (%d)", name, len(lines[environment])), Value: value})
+	}
+
+	return &Event{
+		Type:        "digest",
+		Title:       "Deployment Digest",
+		Description: fmt.Sprintf("%d notification(s) since %s.", len(events), since),
+		Color:       0x3b82f6,
+		Fields:      fields,
+		Time:        time.Now(),
+	}
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTP struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+type EmailNotifier struct {
+	To     []string
+	Server SMTP
+}
+
+func NewEmailNotifier(sink *Sink, server SMTP) (Notifier, error) {
+	if len(sink.To) == 0 {
+		return nil, errors.New("email sink requires at least one recipient in to")
+	}
+
+	if server.Host == "" || server.From == "" {
+		return nil, errors.New("email sink requires SMTP_HOST and SMTP_FROM")
+	}
+
+	return &EmailNotifier{To: sink.To, Server: server}, nil
+}
+
+func (notifier *EmailNotifier) message(event *Event) []byte {
+	boundary := make([]byte, 12)
+	rand.Read(boundary)
+	mixed := "deploy-" + hex.EncodeToString(boundary)
+
+	subject := event.Description
+	if event.Environment != "" {
+		subject = fmt.Sprintf("[%s] %s", event.Environment, subject)
+	}
+
+	body := &bytes.Buffer{}
+	fmt.Fprintf(body, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n", notifier.Server.From, strings.Join(notifier.To, ", "), mime.QEncoding.Encode("utf-8", subject), event.Time.Format(time.RFC1123Z))
+	fmt.Fprintf(body, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mixed)
+
+	fmt.Fprintf(body, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n%s\r\n\r\n", mixed, event.Title, event.Description)
+	for _, field := range event.Fields {
+		fmt.Fprintf(body, "%s: %s\r\n", field.Name, strings.ReplaceAll(field.Value, "\n", "\r\n  "))
+	}
+	if event.Author != "" {
+		fmt.Fprintf(body, "User ID: %s\r\n", event.Author)
+	}
+
+	if len(event.Output) > 0 {
+		fmt.Fprintf(body, "\r\n--%s\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Disposition: attachment; filename=\"deploy.log\"\r\nContent-Transfer-Encoding: base64\r\n\r\n", mixed)
+		encoded := base64.StdEncoding.EncodeToString(event.Output)
+		for len(encoded) > 76 {
+			body.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		body.WriteString(encoded + "\r\n")
+	}
+
+	fmt.Fprintf(body, "--%s--\r\n", mixed)
+	return body.Bytes()
+}
+
+func (notifier *EmailNotifier) Notify(ctx context.Context, event *Event) error {
+	server := notifier.Server
+	address := net.JoinHostPort(server.Host, server.Port)
+
+	var auth smtp.Auth
+	if server.Username != "" {
+		auth = smtp.PlainAuth("", server.Username, server.Password, server.Host)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(address, auth, server.From, notifier.To, notifier.message(event)) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,319 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Field struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+type Event struct {
+	Type        string        `json:"type"`
+	Environment string        `json:"environment,omitempty"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Color       int           `json:"color"`
+	Fields      []Field       `json:"fields"`
+	Author      string        `json:"author,omitempty"`
+	Username    string        `json:"username,omitempty"`
+	Deployment  string        `json:"deployment,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	Previous    string        `json:"previous,omitempty"`
+	Thumbnail   string        `json:"-"`
+	Output      []byte        `json:"-"`
+	Time        time.Time     `json:"time"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, event *Event) error
+}
+
+type NotifierFunc func(ctx context.Context, event *Event) error
+
+func (f NotifierFunc) Notify(ctx context.Context, event *Event) error {
+	return f(ctx, event)
+}
+
+type Sink struct {
+	Type         string   `json:"type"`
+	URL          string   `json:"url"`
+	Channel      string   `json:"channel"`
+	Events       []string `json:"events"`
+	Environments []string `json:"environments"`
+	To           []string `json:"to"`
+	Key          string   `json:"key"`
+	When         []string `json:"when"`
+	SlowerThan   string   `json:"slower_than"`
+	Digest       *Digest  `json:"digest"`
+
+	Notifier Notifier `json:"-"`
+
+	slowerThan time.Duration
+}
+
+var conditions = map[string]func(sink *Sink, event *Event) bool{
+	"failure": func(sink *Sink, event *Event) bool {
+		return event.Type == "deployment.failed" || event.Type == "deployment.verification_failed"
+	},
+	"recovery": func(sink *Sink, event *Event) bool {
+		return event.Type == "deployment.success" && (event.Previous == "failed" || event.Previous == "degraded")
+	},
+	"slow": func(sink *Sink, event *Event) bool {
+		return strings.HasPrefix(event.Type, "deployment.") && event.Duration > sink.slowerThan
+	},
+}
+
+var optIn = []string{"deployment.anomaly"}
+
+type Factory func(sink *Sink) (Notifier, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{
+		"discord":   newDiscordNotifier,
+		"slack":     func(sink *Sink) (Notifier, error) { return &SlackNotifier{URL: sink.URL}, nil },
+		"http":      func(sink *Sink) (Notifier, error) { return &HTTPNotifier{URL: sink.URL}, nil },
+		"pagerduty": newAlertNotifier,
+		"opsgenie":  newAlertNotifier,
+		"telegram":  newTelegramNotifier,
+		"matrix":    newMatrixNotifier,
+	}
+)
+
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	factories[name] = factory
+}
+
+type Dispatcher struct {
+	Sinks []*Sink
+}
+
+func Load(file string) (*Dispatcher, error) {
+	configured := []*Sink{}
+
+	body, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &configured); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+		}
+	} else {
+		configured = append(configured, &Sink{Type: "discord"})
+	}
+
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	for i, sink := range configured {
+		factory, ok := factories[sink.Type]
+		if !ok {
+			return nil, fmt.Errorf("sink %d: unknown type %s", i, sink.Type)
+		}
+
+		for _, condition := range sink.When {
+			if _, ok := conditions[condition]; !ok {
+				return nil, fmt.Errorf("sink %d: unknown condition %q, expected failure, recovery or slow", i, condition)
+			}
+		}
+
+		if slices.Contains(sink.When, "slow") {
+			if sink.slowerThan, err = time.ParseDuration(sink.SlowerThan); err != nil || sink.slowerThan <= 0 {
+				return nil, fmt.Errorf("sink %d: slow condition needs a valid slower_than duration", i)
+			}
+		}
+
+		if sink.Digest != nil {
+			if err := sink.Digest.compile(); err != nil {
+				return nil, fmt.Errorf("sink %d: %w", i, err)
+			}
+		}
+
+		if sink.Notifier, err = factory(sink); err != nil {
+			return nil, fmt.Errorf("sink %d: %w", i, err)
+		}
+	}
+
+	return &Dispatcher{Sinks: configured}, nil
+}
+
+func (sink *Sink) Accepts(event *Event) bool {
+	if len(sink.Environments) > 0 && event.Environment != "" && !slices.Contains(sink.Environments, event.Environment) {
+		return false
+	}
+
+	if len(sink.When) > 0 && !slices.ContainsFunc(sink.When, func(condition string) bool { return conditions[condition](sink, event) }) {
+		return false
+	}
+
+	if len(sink.Events) == 0 {
+		return !slices.Contains(optIn, event.Type)
+	}
+
+	for _, pattern := range sink.Events {
+		if matched, err := path.Match(pattern, event.Type); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (dispatcher *Dispatcher) Notify(ctx context.Context, event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	var wg sync.WaitGroup
+	for _, sink := range dispatcher.Sinks {
+		if !sink.Accepts(event) {
+			continue
+		}
+
+		if sink.Digest != nil && sink.Digest.matches(event) {
+			sink.Digest.add(sink, event)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sink.Notifier.Notify(ctx, event); err != nil {
+				log.Printf("%s.Notify(): %v", sink.Type, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (dispatcher *Dispatcher) Flush(ctx context.Context) {
+	for _, sink := range dispatcher.Sinks {
+		if sink.Digest != nil {
+			sink.Digest.flush(ctx, sink)
+		}
+	}
+}
+
+func PostJSON(ctx context.Context, url string, payload any, headers ...string) error {
+	return SendJSON(ctx, http.MethodPost, url, payload, headers...)
+}
+
+func SendJSON(ctx context.Context, method, url string, payload any, headers ...string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("json.Marshal(): %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http.Do(): %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return nil
+}
+
+type DiscordNotifier struct {
+	URL string
+}
+
+func newDiscordNotifier(sink *Sink) (Notifier, error) {
+	if sink.URL == "" {
+		return nil, errors.New("discord sink requires a webhook url")
+	}
+
+	return &DiscordNotifier{URL: sink.URL}, nil
+}
+
+func (notifier *DiscordNotifier) embed(event *Event) map[string]any {
+	fields := []map[string]any{}
+	for _, field := range event.Fields {
+		fields = append(fields, map[string]any{"name": field.Name, "value": field.Value, "inline": field.Inline})
+	}
+
+	embed := map[string]any{
+		"title":       event.Title,
+		"description": event.Description,
+		"color":       event.Color,
+		"fields":      fields,
+		"timestamp":   event.Time.Format(time.RFC3339),
+	}
+
+	if event.Thumbnail != "" {
+		embed["thumbnail"] = map[string]any{"url": event.Thumbnail}
+	}
+
+	if event.Author != "" {
+		embed["footer"] = map[string]any{"text": "User ID: " + event.Author}
+	}
+
+	return embed
+}
+
+func (notifier *DiscordNotifier) Notify(ctx context.Context, event *Event) error {
+	return PostJSON(ctx, notifier.URL, map[string]any{"embeds": []map[string]any{notifier.embed(event)}})
+}
+
+type SlackNotifier struct {
+	URL string
+}
+
+func (notifier *SlackNotifier) Notify(ctx context.Context, event *Event) error {
+	fields := []map[string]any{}
+	for _, field := range event.Fields {
+		fields = append(fields, map[string]any{"title": field.Name, "value": field.Value, "short": field.Inline})
+	}
+
+	return PostJSON(ctx, notifier.URL, map[string]any{
+		"attachments": []map[string]any{
+			{
+				"color":  fmt.Sprintf("#%06x", event.Color),
+				"title":  event.Title,
+				"text":   event.Description,
+				"fields": fields,
+				"ts":     event.Time.Unix(),
+			},
+		},
+	})
+}
+
+type HTTPNotifier struct {
+	URL string
+}
+
+func (notifier *HTTPNotifier) Notify(ctx context.Context, event *Event) error {
+	return PostJSON(ctx, notifier.URL, event)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const defaultObserveInterval = 30 * time.Second
+
+type Observation struct {
+	Window   string   `json:"window"`
+	Interval string   `json:"interval"`
+	Checks   []*Check `json:"checks"`
+	Gates    []*Gate  `json:"gates"`
+	Failures int      `json:"failures"`
+	Rollback bool     `json:"rollback"`
+
+	window   time.Duration
+	interval time.Duration
+}
+
+func (observation *Observation) compile(environment *Environment) (err error) {
+	if observation.window, err = time.ParseDuration(observation.Window); err != nil || observation.window <= 0 {
+		return errors.New("observe needs a valid window duration")
+	}
+
+	observation.interval = defaultObserveInterval
+	if observation.Interval != "" {
+		if observation.interval, err = time.ParseDuration(observation.Interval); err != nil || observation.interval <= 0 {
+			return errors.New("observe needs a valid interval duration")
+		}
+	}
+
+	if len(observation.Checks) == 0 {
+		observation.Checks = environment.Smoke
+	}
+	if len(observation.Checks) == 0 && len(observation.Gates) == 0 {
+		return errors.New("observe needs checks, gates or smoke tests to poll")
+	}
+
+	for _, gate := range observation.Gates {
+		if err := gate.compile(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (deployment *Deployment) observe(ctx context.Context) (polls int, err error) {
+	observation := deployment.Environment.Observe
+	ctx, span := tracer.Start(ctx, "observe")
+	defer func() { endSpan(span, err) }()
+
+	ticker := time.NewTicker(observation.interval)
+	defer ticker.Stop()
+	window := time.After(observation.window)
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return polls, context.Cause(ctx)
+		case <-window:
+			return polls, nil
+		case <-ticker.C:
+		}
+
+		results := runChecks(ctx, deployment, observation.Checks)
+		polls++
+		for _, gate := range observation.Gates {
+			var breach *GateError
+			if err := gate.evaluate(ctx, deployment); errors.As(err, &breach) {
+				return polls, breach
+			} else if err != nil {
+				results = append(results, CheckResult{Check: &Check{Name: gate.Name}, Err: err})
+			}
+		}
+		if smokeFailures(results) == 0 {
+			continue
+		}
+
+		if failures++; failures > observation.Failures {
+			return polls, fmt.Errorf("%d of %d observation poll(s) failed:\n%s", failures, polls, smokeSummary(results))
+		}
+	}
+}
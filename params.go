@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const maxModalParams = 5
+
+type PendingParams struct {
+	Environment *Environment
+	Args        []string
+}
+
+var (
+	pendingParamsMu sync.Mutex
+	pendingParams   = map[string]*PendingParams{}
+)
+
+func paramArgs(channelID string, entry *Entry, args []string) ([]string, map[string]string, error) {
+	rest, supplied := []string{}, map[string]string{}
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if ok && slices.ContainsFunc(entry.Params, func(param *Param) bool { return strings.EqualFold(param.Name, name) }) {
+			supplied[strings.ToLower(name)] = value
+			continue
+		}
+		rest = append(rest, arg)
+	}
+
+	params := map[string]string{}
+	for _, param := range entry.Params {
+		value, err := param.Validate(supplied[strings.ToLower(param.Name)])
+		if err != nil {
+			return nil, nil, textError(channelID, "params.invalid", "error", err)
+		}
+		if value != "" {
+			params[param.Variable()] = value
+		}
+	}
+
+	return rest, params, nil
+}
+
+func (deployment *Deployment) paramFields() []Field {
+	if len(deployment.Params) == 0 {
+		return nil
+	}
+
+	lines := []string{}
+	for _, name := range slices.Sorted(maps.Keys(deployment.Params)) {
+		lines = append(lines, fmt.Sprintf("`%s=%s`", strings.ToLower(name), deployment.Params[name]))
+	}
+	return []Field{{Name: "Parameters", Value: truncate(strings.Join(lines, "\n"), 1000), Inline: true}}
+}
+
+func openParamsModal(session *discordgo.Session, interaction *discordgo.InteractionCreate, environment *Environment, key string, args []string) {
+	id := newID()
+	pendingParamsMu.Lock()
+	pendingParams[id] = &PendingParams{Environment: environment, Args: args}
+	pendingParamsMu.Unlock()
+
+	time.AfterFunc(15*time.Minute, func() {
+		pendingParamsMu.Lock()
+		delete(pendingParams, id)
+		pendingParamsMu.Unlock()
+	})
+
+	rows := []discordgo.MessageComponent{}
+	for _, param := range Commands[key].Params {
+		label := param.Label
+		if label == "" {
+			label = param.Name
+		}
+
+		rows = append(rows, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.TextInput{CustomID: param.Name, Label: truncate(label, 45), Style: discordgo.TextInputShort, Placeholder: truncate(param.Hint(), 100), Value: param.Default, Required: param.Required, MaxLength: 100},
+		}})
+	}
+
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID:   "params:" + id,
+			Title:      truncate(text(interaction.ChannelID, "params.title", "key", key), 45),
+			Components: rows,
+		},
+	})
+}
+
+func submitParams(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 1 {
+		return
+	}
+
+	pendingParamsMu.Lock()
+	pending, ok := pendingParams[args[0]]
+	delete(pendingParams, args[0])
+	pendingParamsMu.Unlock()
+
+	if !ok {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "params.expired"))
+		return
+	}
+
+	for _, row := range interaction.ModalSubmitData().Components {
+		if row, ok := row.(*discordgo.ActionsRow); ok {
+			for _, component := range row.Components {
+				if input, ok := component.(*discordgo.TextInput); ok && strings.TrimSpace(input.Value) != "" {
+					pending.Args = append(pending.Args, input.CustomID+"="+strings.TrimSpace(input.Value))
+				}
+			}
+		}
+	}
+
+	deployInteraction(session, interaction, pending.Environment, pending.Args)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const maxSelectOptions = 25
+
+type Picker struct {
+	User        string
+	Environment *Environment
+	Key         string
+	Branch      string
+	Branches    []string
+	Ref         string
+}
+
+var (
+	pickersMu sync.Mutex
+	pickers   = map[string]*Picker{}
+)
+
+func pickerEnvironments(interaction *discordgo.InteractionCreate) []*Environment {
+	environments := []*Environment{}
+	for _, name := range slices.Sorted(maps.Keys(Environments)) {
+		environment := Environments[name]
+		if (environment.Channel == interaction.ChannelID || slices.Contains(environment.Channels, interaction.ChannelID)) && slices.Contains(interaction.Member.Roles, environment.Role) {
+			environments = append(environments, environment)
+		}
+	}
+	return environments
+}
+
+func pickerBranches(environment *Environment, key string) []string {
+	entry := Commands[key]
+	if entry.Strategy == "artifact" {
+		return nil
+	}
+
+	env, cleanup, err := gitCredentials(environment)
+	if err != nil {
+		log.Printf("gitCredentials(): %v", err)
+		return nil
+	}
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	output, err := lsRemote(ctx, &Deployment{Environment: environment, Entry: entry}, env, "--heads")
+	if err != nil {
+		log.Printf("lsRemote(): %v", err)
+		return nil
+	}
+
+	branches := []string{}
+	for line := range strings.Lines(string(output)) {
+		_, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		branch := strings.TrimPrefix(ref, "refs/heads/")
+		if ok && branch != ref && validBranch(environment, branch) && branchAllowed(environment, branch) {
+			branches = append(branches, branch)
+		}
+	}
+
+	slices.Sort(branches)
+	if i := slices.Index(branches, environment.Branch); i > 0 {
+		branches = slices.Insert(slices.Delete(branches, i, i+1), 0, environment.Branch)
+	}
+	return branches[:min(len(branches), maxSelectOptions)]
+}
+
+func selectRow(customID, placeholder string, values []string, selected string) discordgo.ActionsRow {
+	options := []discordgo.SelectMenuOption{}
+	for _, value := range values[:min(len(values), maxSelectOptions)] {
+		options = append(options, discordgo.SelectMenuOption{Label: truncate(value, 100), Value: value, Default: value == selected})
+	}
+
+	return discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.SelectMenu{MenuType: discordgo.StringSelectMenu, CustomID: customID, Placeholder: placeholder, Options: options},
+	}}
+}
+
+func (picker *Picker) view(channelID, id string, environments []*Environment) (string, []discordgo.MessageComponent) {
+	names := []string{}
+	for _, environment := range environments {
+		names = append(names, environment.Name)
+	}
+
+	components := []discordgo.MessageComponent{
+		selectRow("picker:"+id+":environment", text(channelID, "picker.environment"), names, picker.Environment.Name),
+		selectRow("picker:"+id+":key", text(channelID, "picker.key"), slices.Sorted(maps.Keys(Commands)), picker.Key),
+	}
+
+	content := text(channelID, "picker.prompt")
+	switch {
+	case picker.Ref != "":
+		content = text(channelID, "picker.ref", "ref", picker.Ref)
+	case picker.Key == "":
+	case Commands[picker.Key].Strategy == "artifact":
+		content = text(channelID, "picker.artifact", "key", picker.Key)
+	case len(picker.Branches) == 0:
+		content = text(channelID, "picker.no_branches", "environment", picker.Environment.Name)
+	default:
+		components = append(components, selectRow("picker:"+id+":branch", text(channelID, "picker.branch"), picker.Branches, picker.Branch))
+	}
+
+	ready := picker.Key != "" && picker.Branch != ""
+	components = append(components, buttons(discordgo.Button{Label: text(channelID, "picker.deploy"), Style: discordgo.SuccessButton, CustomID: "picker:" + id + ":deploy", Disabled: !ready})...)
+	return content, components
+}
+
+func openPicker(session *discordgo.Session, interaction *discordgo.InteractionCreate, ref string) {
+	id, picker := newID(), &Picker{User: interaction.Member.User.ID, Environment: environmentByChannel(interaction.ChannelID), Branch: ref, Ref: ref}
+	pickersMu.Lock()
+	pickers[id] = picker
+	pickersMu.Unlock()
+
+	time.AfterFunc(15*time.Minute, func() {
+		pickersMu.Lock()
+		delete(pickers, id)
+		pickersMu.Unlock()
+	})
+
+	content, components := picker.view(interaction.ChannelID, id, pickerEnvironments(interaction))
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Content: content, Components: components, Flags: discordgo.MessageFlagsEphemeral},
+	})
+}
+
+func pick(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 2 {
+		return
+	}
+
+	pickersMu.Lock()
+	picker, ok := pickers[args[0]]
+	pickersMu.Unlock()
+
+	if !ok || picker.User != interaction.Member.User.ID {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "picker.expired"))
+		return
+	}
+
+	environments := pickerEnvironments(interaction)
+	if args[1] == "deploy" {
+		pickersMu.Lock()
+		delete(pickers, args[0])
+		pickersMu.Unlock()
+
+		deployArgs := []string{picker.Branch, picker.Key}
+		if entry := Commands[picker.Key]; len(entry.Params) > 0 && len(entry.Params) <= maxModalParams {
+			openParamsModal(session, interaction, picker.Environment, picker.Key, deployArgs)
+			return
+		}
+		deployInteraction(session, interaction, picker.Environment, deployArgs)
+		return
+	}
+
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate})
+
+	values := interaction.MessageComponentData().Values
+	if len(values) > 0 {
+		switch args[1] {
+		case "environment":
+			if i := slices.IndexFunc(environments, func(environment *Environment) bool { return environment.Name == values[0] }); i >= 0 {
+				picker.Environment, picker.Branch, picker.Branches = environments[i], picker.Ref, nil
+			}
+		case "key":
+			if _, ok := Commands[values[0]]; ok {
+				picker.Key, picker.Branch, picker.Branches = values[0], picker.Ref, nil
+			}
+		case "branch":
+			if slices.Contains(picker.Branches, values[0]) {
+				picker.Branch = values[0]
+			}
+		}
+	}
+
+	if picker.Key != "" && picker.Ref == "" && picker.Branches == nil {
+		picker.Branches = pickerBranches(picker.Environment, picker.Key)
+	}
+
+	content, components := picker.view(interaction.ChannelID, args[0], environments)
+	session.InteractionResponseEdit(interaction.Interaction, &discordgo.WebhookEdit{Content: &content, Components: &components})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"deploy/engine"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const confirmWindow = 10 * time.Minute
+
+type Backup struct {
+	Environment string    `json:"environment"`
+	Key         string    `json:"key"`
+	Branch      string    `json:"branch"`
+	Path        string    `json:"path"`
+	Author      string    `json:"author"`
+	Created     time.Time `json:"created"`
+}
+
+func runPipeline(ctx context.Context, deployment *Deployment, prompt func(string, []discordgo.MessageComponent)) ([]byte, error) {
+	request := deployment.request()
+	request.Progress = deployment.Progress.Set
+	request.Completed = deployment.Progress.Finish
+	request.Steps = map[string]engine.StepFunc{
+		"migrations": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			err := runMigrations(ctx, deployment, request, step, output, prompt)
+			request.Vars["BACKUP"] = deployment.Backup
+			return err
+		},
+		"ci":    runCI,
+		"purge": runPurge,
+		"argocd": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			return runArgoCD(ctx, request, step, output, deployment.Progress.Detail)
+		},
+		"flag": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			return runFlag(ctx, deployment, request, step, output)
+		},
+		"drain": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			return runBalancerStep(ctx, deployment, request, step, output)
+		},
+		"register": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			return runBalancerStep(ctx, deployment, request, step, output)
+		},
+		"nomad": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			return runNomad(ctx, request, step, output, deployment.Progress.Detail)
+		},
+	}
+
+	result, err := engine.Run(ctx, *request)
+	return result.Output, err
+}
+
+func runMigrations(ctx context.Context, deployment *Deployment, request *engine.Request, step *Step, output *bytes.Buffer, prompt func(string, []discordgo.MessageComponent)) error {
+	location := deployment.Environment.Location
+
+	pending, err := request.Execute(ctx, location, step.Pending)
+	output.Write(pending)
+	if err != nil {
+		return fmt.Errorf("pending: %w", err)
+	}
+
+	if strings.TrimSpace(string(pending)) == "" {
+		fmt.Fprintln(output, "No pending migrations")
+		return nil
+	}
+
+	id := newID()
+	decision, done := awaitDecision(id)
+	defer done()
+
+	channelID := deployment.Environment.Channel
+	prompt(deployment.text(channelID, "migrations.pending", "pending", truncate(sanitizeOutput(string(pending)), 1500)), decisionButtons(id,
+		discordgo.Button{Label: text(channelID, "migrations.run_button"), Style: discordgo.DangerButton, CustomID: "confirm"},
+		discordgo.Button{Label: text(channelID, "migrations.cancel_button"), Style: discordgo.SecondaryButton, CustomID: "cancel"},
+	))
+
+	select {
+	case choice := <-decision:
+		if choice.Choice != "confirm" {
+			deployment.audit("approval", "denied", choice.User, map[string]string{"gate": "migrations"})
+			return fmt.Errorf("%w by %s", errAborted, choice.User.Username)
+		}
+		deployment.audit("approval", "approved", choice.User, map[string]string{"gate": "migrations"})
+		prompt(text(channelID, "migrations.confirmed", "user", choice.User.ID), nil)
+	case <-time.After(confirmWindow):
+		return fmt.Errorf("%w: confirmation timed out", errAborted)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if step.Backup != "" {
+		dir := step.Backups
+		if dir == "" {
+			dir = filepath.Join(location, ".backups")
+		}
+
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("os.MkdirAll(): %w", err)
+		}
+
+		deployment.Backup = filepath.Join(dir, fmt.Sprintf("%s-%s-%s.sql", deployment.Environment.Name, deployment.Key, time.Now().UTC().Format(releaseFormat)))
+		out, err := request.Execute(ctx, location, step.Backup, "${BACKUP}", deployment.Backup)
+		output.Write(out)
+		if err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+
+		backup := &Backup{
+			Environment: deployment.Environment.Name,
+			Key:         deployment.Key,
+			Branch:      deployment.Branch,
+			Path:        deployment.Backup,
+			Author:      deployment.Author.ID,
+			Created:     time.Now().UTC(),
+		}
+		if err := store.Update(func(state *State) { state.Backups = append(state.Backups, backup) }); err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "Backup written to %s\n", deployment.Backup)
+	}
+
+	prompt(text(channelID, "migrations.running"), nil)
+	out, err := request.Execute(ctx, location, step.Run, "${BACKUP}", deployment.Backup)
+	output.Write(out)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var planGlyphs = map[string]string{
+	"":          "▫️",
+	"running":   "▶️",
+	"success":   "✅",
+	"degraded":  "⚠️",
+	"failed":    "❌",
+	"rejected":  "❌",
+	"cancelled": "⏹️",
+	"skipped":   "⏭️",
+}
+
+func succeeded(status string) bool {
+	return status == "success" || status == "degraded"
+}
+
+func deployPlan(session *discordgo.Session, channelID string, environment *Environment, author *discordgo.User, args []string) {
+	branch, key := args[0], args[1]
+	order, err := Commands.Order(key)
+	if err != nil {
+		session.ChannelMessageSend(channelID, text(channelID, "plan.invalid", "key", key, "error", err.Error()))
+		return
+	}
+
+	statuses, notes := map[string]string{}, map[string]string{}
+	render := func() string {
+		lines := []string{text(channelID, "plan.title", "key", key, "branch", branch, "environment", environment.Name)}
+		for _, name := range order {
+			if Commands[name].Aggregate() {
+				continue
+			}
+
+			line := fmt.Sprintf("%s `%s`", planGlyphs[statuses[name]], name)
+			if notes[name] != "" {
+				line += " - " + notes[name]
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	msg, err := session.ChannelMessageSend(channelID, render())
+	if err != nil {
+		return
+	}
+	update := func() { session.ChannelMessageEdit(channelID, msg.ID, render()) }
+
+	deployed, total := 0, 0
+	for _, name := range order {
+		entry := Commands[name]
+		if blocked := planBlocker(entry.Needs, statuses); blocked != "" {
+			statuses[name], notes[name] = "skipped", text(channelID, "plan.blocked", "need", blocked)
+			if !entry.Aggregate() {
+				total++
+				update()
+			}
+			continue
+		}
+
+		if entry.Aggregate() {
+			statuses[name] = "success"
+			if planBlocker(entry.Keys, statuses) != "" {
+				statuses[name] = "failed"
+			}
+			continue
+		}
+
+		total++
+		statuses[name] = "running"
+		update()
+
+		statuses[name], notes[name] = runPlanDeployment(session, channelID, environment, author, append([]string{branch, name}, args[2:]...))
+		if succeeded(statuses[name]) {
+			deployed++
+		}
+		update()
+	}
+
+	fields := []Field{{Name: "Environment", Value: environment.Name, Inline: true}, {Name: "Branch", Value: branch, Inline: true}}
+	for _, name := range order {
+		if !Commands[name].Aggregate() {
+			value := planGlyphs[statuses[name]] + " " + statuses[name]
+			if notes[name] != "" {
+				value += " - " + notes[name]
+			}
+			fields = append(fields, Field{Name: name, Value: value, Inline: true})
+		}
+	}
+	color := 0x008000
+	if deployed < total {
+		color = 0x800000
+	}
+	publish(&Event{Type: "deployment.plan", Environment: environment.Name, Title: "Deployment Plan: " + key, Description: fmt.Sprintf("%d of %d deployment(s) succeeded.", deployed, total), Color: color, Fields: fields, Author: author.ID, Username: author.Username, Thumbnail: deploymentThumbnail})
+
+	log.Printf("Plan finished. Username: %s (%s) - Environment: %s - Key: %s - Deployed: %d/%d", author.Username, author.ID, environment.Name, key, deployed, total)
+	session.ChannelMessageEdit(channelID, msg.ID, render()+"\n"+text(channelID, "plan.finished", "deployed", deployed, "total", total))
+}
+
+func planBlocker(needs []string, statuses map[string]string) string {
+	for _, need := range needs {
+		if !succeeded(statuses[need]) {
+			return need
+		}
+	}
+
+	return ""
+}
+
+func runPlanDeployment(session *discordgo.Session, channelID string, environment *Environment, author *discordgo.User, args []string) (string, string) {
+	deployment, _, err := newDeployment(environment, author, args)
+	if err != nil {
+		return "failed", err.Error()
+	}
+
+	if deployment.Entry.TOTP && !confirmTOTP(session, channelID, deployment, "") {
+		deployment.finish(errTOTP)
+		return "failed", errTOTP.Error()
+	}
+
+	finished := make(chan string, 1)
+	deployment.wait = true
+	deployment.done = func(outcome string) { finished <- outcome }
+	if err := startDeployment(session, channelID, deployment); err != nil {
+		deployment.finish(err)
+		return "failed", err.Error()
+	}
+
+	outcome := <-finished
+	return outcome, fmt.Sprintf("`%s` in %s", deployment.ID, time.Since(deployment.Started).Round(time.Second))
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/engine"
+	"deploy/executor"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var (
+	templatePattern   = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	templateVariables = []string{"LOCATION", "BRANCH", "REF", "SHA", "BACKUP", "TAG", "RELEASE", "SLOT", "TARGET", "PR_NUMBER"}
+	paramNamePattern  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	paramTypes        = []string{"", "string", "int", "choice"}
+	strategies        = []string{"", "releases", "artifact", "bluegreen", "canary"}
+	serialStepTypes   = []string{"migrations", "git", "ci", "argocd", "nomad"}
+	stepTypes         = []string{"", "command", "migrations", "git", "ci", "argocd", "nomad", "systemd", "pm2", "supervisor", "purge", "drain", "register", "flag"}
+)
+
+func templateStrings(value reflect.Value, visit func(string)) {
+	switch value.Kind() {
+	case reflect.String:
+		visit(value.String())
+	case reflect.Pointer, reflect.Interface:
+		if !value.IsNil() {
+			templateStrings(value.Elem(), visit)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			templateStrings(value.Index(i), visit)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			templateStrings(value.MapIndex(key), visit)
+		}
+	case reflect.Struct:
+		for i := range value.NumField() {
+			if value.Type().Field(i).IsExported() {
+				templateStrings(value.Field(i), visit)
+			}
+		}
+	}
+}
+
+func unknownVariables(value any, allowed []string) []string {
+	unknown := map[string]bool{}
+	templateStrings(reflect.ValueOf(value), func(text string) {
+		for _, match := range templatePattern.FindAllStringSubmatch(text, -1) {
+			if _, ok := os.LookupEnv(match[1]); !ok && !slices.Contains(templateVariables, match[1]) && !slices.Contains(allowed, match[1]) {
+				unknown[match[1]] = true
+			}
+		}
+	})
+	return slices.Sorted(maps.Keys(unknown))
+}
+
+func paramProblems(key string, param *Param) []string {
+	problems := []string{}
+	if !paramNamePattern.MatchString(param.Name) {
+		problems = append(problems, fmt.Sprintf("dictionary key %s: invalid param name %q", key, param.Name))
+	}
+
+	if !slices.Contains(paramTypes, param.Type) {
+		problems = append(problems, fmt.Sprintf("dictionary key %s: param %s has unknown type %q, expected string, int or choice", key, param.Name, param.Type))
+	} else if param.Type == "choice" && len(param.Choices) == 0 {
+		problems = append(problems, fmt.Sprintf("dictionary key %s: choice param %s needs choices", key, param.Name))
+	}
+
+	if param.Default != "" {
+		if _, err := param.Validate(param.Default); err != nil {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: default for %v", key, err))
+		}
+	}
+
+	return problems
+}
+
+func preflight(session *discordgo.Session) []string {
+	problems := []string{}
+
+	for _, key := range slices.Sorted(maps.Keys(Commands)) {
+		entry := Commands[key]
+		if !slices.Contains(strategies, entry.Strategy) {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: unknown strategy %q, expected one of releases, artifact, bluegreen, canary", key, entry.Strategy))
+		}
+
+		if entry.Strategy == "artifact" && entry.Checksums == "" {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: artifact deploys need a checksums asset", key))
+		}
+
+		if !slices.Contains(riskTiers, entry.Risk) {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: unknown risk %q, expected low, medium or high", key, entry.Risk))
+		}
+
+		if len(entry.Needs) > 0 {
+			if _, err := Commands.Order(key); err != nil {
+				problems = append(problems, fmt.Sprintf("dictionary key %s: %v", key, err))
+			}
+		}
+
+		if entry.Shell != "" && executor.Shells[entry.Shell] == nil {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: unknown shell %q", key, entry.Shell))
+		}
+
+		switch lb := entry.Balancer; {
+		case lb == nil:
+			for _, step := range entry.Steps {
+				if step.Type == "drain" || step.Type == "register" {
+					problems = append(problems, fmt.Sprintf("dictionary key %s: %s step %s needs a load_balancer", key, step.Type, step.Name))
+				}
+			}
+		case lb.Type == "alb" && lb.TargetGroup == "":
+			problems = append(problems, fmt.Sprintf("dictionary key %s: alb load balancer needs a target_group", key))
+		case lb.Type == "haproxy" && (lb.Socket == "" || lb.Backend == ""):
+			problems = append(problems, fmt.Sprintf("dictionary key %s: haproxy load balancer needs a socket and a backend", key))
+		case lb.Type != "alb" && lb.Type != "haproxy":
+			problems = append(problems, fmt.Sprintf("dictionary key %s: unknown load balancer type %q, expected alb or haproxy", key, lb.Type))
+		}
+
+		allowed := slices.Clone(entry.Secrets)
+		for _, param := range entry.Params {
+			allowed = append(allowed, param.Variable())
+			problems = append(problems, paramProblems(key, param)...)
+		}
+		if len(entry.Params) > maxModalParams {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: declares %d params, /deploy can only prompt for %d", key, len(entry.Params), maxModalParams))
+		}
+
+		if entry.Script != "" {
+			names, err := engine.ScriptVariables(entry.Script)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("dictionary key %s: invalid script: %v", key, err))
+			}
+			allowed = slices.Concat(allowed, names)
+		}
+
+		for i, step := range entry.Steps {
+			problems = append(problems, stepProblems(key, step.Label(i), step, false)...)
+		}
+
+		for _, name := range unknownVariables(entry, allowed) {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: unknown template variable ${%s}, use $%s for shell variables", key, name, name))
+		}
+	}
+
+	guilds := map[string][]*discordgo.Role{}
+	for _, name := range slices.Sorted(maps.Keys(Environments)) {
+		environment := Environments[name]
+
+		if info, err := os.Stat(environment.Location); err != nil {
+			problems = append(problems, fmt.Sprintf("environment %s: deployment location %s is not accessible: %v", name, environment.Location, err))
+		} else if !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("environment %s: deployment location %s is not a directory", name, environment.Location))
+		}
+
+		if environment.Shell != "" && executor.Shells[environment.Shell] == nil {
+			problems = append(problems, fmt.Sprintf("environment %s: unknown shell %q", name, environment.Shell))
+		}
+
+		for _, variable := range unknownVariables(environment, nil) {
+			problems = append(problems, fmt.Sprintf("environment %s: unknown template variable ${%s}", name, variable))
+		}
+
+		if git := environment.Git; git != nil {
+			for _, file := range []string{git.SSHKey, git.KnownHosts} {
+				if _, err := os.Stat(file); file != "" && err != nil {
+					problems = append(problems, fmt.Sprintf("environment %s: git credential file %s is not readable: %v", name, file, err))
+				}
+			}
+
+			for _, variable := range []string{git.SSHKeySecret, git.Token} {
+				if variable != "" && secret(variable, os.Getenv(variable)) == "" {
+					problems = append(problems, fmt.Sprintf("environment %s: git credential secret %s is not set", name, variable))
+				}
+			}
+		}
+
+		for i, rule := range environment.AutoDeploy {
+			switch {
+			case rule.Event != "push" && rule.Event != "pull_request":
+				problems = append(problems, fmt.Sprintf("environment %s: auto_deploy rule %d has unknown event %q, expected push or pull_request", name, i, rule.Event))
+			case Commands[rule.Key] == nil:
+				problems = append(problems, fmt.Sprintf("environment %s: auto_deploy rule %d references unknown key %q", name, i, rule.Key))
+			case Commands[rule.Key].TOTP:
+				problems = append(problems, fmt.Sprintf("environment %s: auto_deploy rule %d uses key %s which requires a TOTP code", name, i, rule.Key))
+			case data.WebhookAddr == "":
+				problems = append(problems, fmt.Sprintf("environment %s: auto_deploy rules need WEBHOOK_ADDR to receive webhook events", name))
+			}
+
+			if rule.Batch != "" && rule.batchWindow() == 0 {
+				problems = append(problems, fmt.Sprintf("environment %s: auto_deploy rule %d has an invalid batch window %q, batching only applies to push rules", name, i, rule.Batch))
+			}
+
+			for _, pattern := range rule.Branches {
+				if _, err := path.Match(pattern, ""); err != nil {
+					problems = append(problems, fmt.Sprintf("environment %s: auto_deploy rule %d has an invalid branch pattern %q", name, i, pattern))
+				}
+			}
+		}
+
+		if _, ok := concurrencyGroups[environment.Group]; environment.Group != "" && !ok {
+			problems = append(problems, fmt.Sprintf("environment %s: group %q is not listed in CONCURRENCY_GROUPS", name, environment.Group))
+		}
+
+		for i, hook := range environment.Hooks {
+			switch {
+			case hook.Name == "" || strings.Contains(hook.Name, "/"):
+				problems = append(problems, fmt.Sprintf("environment %s: hook %d needs a name without slashes", name, i))
+			case Commands[hook.Key] == nil:
+				problems = append(problems, fmt.Sprintf("environment %s: hook %s references unknown key %q", name, hook.Name, hook.Key))
+			case Commands[hook.Key].TOTP:
+				problems = append(problems, fmt.Sprintf("environment %s: hook %s uses key %s which requires a TOTP code", name, hook.Name, hook.Key))
+			case secret(hook.Secret, os.Getenv(hook.Secret)) == "":
+				problems = append(problems, fmt.Sprintf("environment %s: hook %s secret %s is not set", name, hook.Name, hook.Secret))
+			case data.WebhookAddr == "":
+				problems = append(problems, fmt.Sprintf("environment %s: hooks need WEBHOOK_ADDR to receive requests", name))
+			}
+		}
+
+		if preview := environment.Preview; preview != nil {
+			if Commands[preview.Key] == nil {
+				problems = append(problems, fmt.Sprintf("environment %s: preview references unknown key %q", name, preview.Key))
+			}
+			if !strings.Contains(preview.Location, "${PR_NUMBER}") {
+				problems = append(problems, fmt.Sprintf("environment %s: preview location must contain ${PR_NUMBER}", name))
+			}
+			if _, err := time.ParseDuration(preview.TTL); preview.TTL != "" && err != nil {
+				problems = append(problems, fmt.Sprintf("environment %s: invalid preview ttl %q: %v", name, preview.TTL, err))
+			}
+			if data.WebhookAddr == "" {
+				problems = append(problems, fmt.Sprintf("environment %s: previews need WEBHOOK_ADDR to receive webhook events", name))
+			}
+		}
+
+		guild := ""
+		for _, id := range append([]string{environment.Channel}, environment.Channels...) {
+			channel, err := session.Channel(id)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("environment %s: channel %s does not exist or the bot cannot see it: %v", name, id, err))
+				continue
+			}
+			guild = channel.GuildID
+		}
+
+		if environment.Forum != "" {
+			if forum, err := session.Channel(environment.Forum); err != nil {
+				problems = append(problems, fmt.Sprintf("environment %s: forum %s does not exist or the bot cannot see it: %v", name, environment.Forum, err))
+			} else if forum.Type != discordgo.ChannelTypeGuildForum {
+				problems = append(problems, fmt.Sprintf("environment %s: channel %s is not a forum channel", name, environment.Forum))
+			}
+		}
+
+		if guild == "" {
+			continue
+		}
+
+		if _, ok := guilds[guild]; !ok {
+			roles, err := session.GuildRoles(guild)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("environment %s: could not list roles of guild %s: %v", name, guild, err))
+			}
+			guilds[guild] = roles
+		}
+
+		if !slices.ContainsFunc(guilds[guild], func(role *discordgo.Role) bool { return role.ID == environment.Role }) {
+			problems = append(problems, fmt.Sprintf("environment %s: role %s does not exist in guild %s", name, environment.Role, guild))
+		}
+
+		if !guildAllowed(guild) {
+			problems = append(problems, fmt.Sprintf("environment %s: guild %s is not listed in ALLOWED_GUILDS", name, guild))
+		}
+	}
+
+	for i, sink := range notifications.Sinks {
+		if sink.Type != "discord" {
+			continue
+		}
+
+		url := sink.URL
+		if url == "" {
+			url = secret("DEPLOYMENT_LOG_WEBHOOK", data.DeploymentLogWebhook)
+		}
+
+		if err := checkWebhook(url); err != nil {
+			problems = append(problems, fmt.Sprintf("notification sink %d: discord webhook does not respond: %v", i, err))
+		}
+	}
+
+	if archiveEnabled() {
+		credentials := awsCredentials()
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" || credentials.Region == "" {
+			problems = append(problems, "LOGS_S3_BUCKET is set but AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_REGION are required")
+		}
+
+		if _, err := time.ParseDuration(data.LogsExpiry); err != nil {
+			problems = append(problems, fmt.Sprintf("LOGS_S3_EXPIRY: %v", err))
+		}
+	}
+
+	return problems
+}
+
+func stepProblems(key, label string, step *Step, parallel bool) []string {
+	problems := []string{}
+	if step.When != "" {
+		if err := engine.ParseCondition(step.When); err != nil {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: %s has an invalid when condition: %v", key, label, err))
+		}
+	}
+
+	switch {
+	case len(step.Parallel) > 0:
+	case slices.Contains(serialStepTypes, step.Type) && parallel:
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s is a %s step and cannot run in parallel", key, label, step.Type))
+	case step.Type == "ci" && ciProviders[step.With["provider"]] == nil:
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s has unknown ci provider %q, expected github or jenkins", key, label, step.With["provider"]))
+	case step.Type == "ci" && step.With["provider"] == "github" && data.GithubToken == "":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s triggers a GitHub Actions workflow but GITHUB_TOKEN is not set", key, label))
+	case step.Type == "argocd" && (step.With["server"] == "" || step.With["app"] == ""):
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s is an argocd step and needs a server and an app", key, label))
+	case step.Type == "nomad" && step.With["job"] == "":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s is a nomad step and needs a job spec", key, label))
+	case step.Type == "systemd" && strings.TrimSpace(step.With["units"]) == "":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s is a systemd step and needs units", key, label))
+	case (step.Type == "pm2" || step.Type == "supervisor") && strings.TrimSpace(step.With["process"]) == "":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s is a %s step and needs a process", key, label, step.Type))
+	case step.Type == "purge" && step.With["provider"] != "" && step.With["provider"] != "cloudflare" && step.With["provider"] != "generic":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s has unknown purge provider %q, expected cloudflare or generic", key, label, step.With["provider"]))
+	case step.Type == "purge" && step.With["provider"] != "generic" && step.With["zone"] == "":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s purges Cloudflare and needs a zone", key, label))
+	case step.Type == "flag" && flagProviders[step.With["provider"]] == nil:
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s has unknown flag provider %q, expected launchdarkly, unleash or flagsmith", key, label, step.With["provider"]))
+	case step.Type == "flag" && step.With["state"] != "on" && step.With["state"] != "off":
+		problems = append(problems, fmt.Sprintf("dictionary key %s: %s must set state to on or off", key, label))
+	case slices.Contains(stepTypes, step.Type):
+	default:
+		if _, err := engine.FindPlugin(data.PluginsDir, step.Type); err != nil {
+			problems = append(problems, fmt.Sprintf("dictionary key %s: %s has unknown type %q, expected command, migrations, git, ci, argocd, nomad, systemd, pm2, supervisor, purge, drain, register, flag or a plugin in %s", key, label, step.Type, data.PluginsDir))
+		}
+	}
+
+	for i, child := range step.Parallel {
+		problems = append(problems, stepProblems(key, label+"/"+child.Label(i), child, true)...)
+	}
+
+	return problems
+}
+
+func checkWebhook(url string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const presenceInterval = 15 * time.Second
+
+func presence() string {
+	running := scheduler.Running()
+	if len(running) > 0 {
+		status := fmt.Sprintf("Deploying %s (%s elapsed)", running[0].Environment.Name, elapsed(running[0].Started))
+		if len(running) > 1 {
+			status += fmt.Sprintf(" +%d more", len(running)-1)
+		}
+		return status
+	}
+
+	status := "Idle"
+	store.View(func(state *State) {
+		if len(state.History) > 0 {
+			last := state.History[len(state.History)-1]
+			status += " — last deploy " + last.Started.Add(last.Duration).UTC().Format("15:04 MST")
+		}
+	})
+	return status
+}
+
+func updatePresence(session *discordgo.Session) {
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	current := ""
+	for ; ; <-ticker.C {
+		if status := presence(); status != current {
+			if err := session.UpdateCustomStatus(status); err != nil {
+				log.Printf("session.UpdateCustomStatus(): %v", err)
+				continue
+			}
+			current = status
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type Preset struct {
+	Name        string            `json:"-"`
+	Environment string            `json:"environment"`
+	Key         string            `json:"key"`
+	Branch      string            `json:"branch"`
+	Reason      string            `json:"reason"`
+	Params      map[string]string `json:"params"`
+}
+
+var Presets = map[string]*Preset{}
+
+func getPresets(path string) error {
+	body, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	presets := map[string]*Preset{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &presets); err != nil {
+			return fmt.Errorf("json.Unmarshal(): %w", err)
+		}
+	}
+
+	for name, preset := range presets {
+		preset.Name = name
+		environment, ok := Environments[preset.Environment]
+		if !ok {
+			return fmt.Errorf("preset %s: unknown environment %q", name, preset.Environment)
+		}
+
+		if Commands[preset.Key] == nil {
+			return fmt.Errorf("preset %s: unknown key %q", name, preset.Key)
+		}
+
+		if preset.Branch == "" {
+			preset.Branch = environment.Branch
+		}
+	}
+
+	Presets = presets
+	return nil
+}
+
+func (preset *Preset) args(extra []string) []string {
+	args := []string{preset.Branch, preset.Key}
+	for _, name := range slices.Sorted(maps.Keys(preset.Params)) {
+		args = append(args, name+"="+preset.Params[name])
+	}
+
+	return slices.Concat(args, strings.Fields(preset.Reason), extra)
+}
+
+func deployPreset(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 1 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "preset.usage", "available", strings.Join(slices.Sorted(maps.Keys(Presets)), ", ")))
+		return
+	}
+
+	preset, ok := Presets[args[0]]
+	if !ok {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "preset.unknown", "preset", args[0], "available", strings.Join(slices.Sorted(maps.Keys(Presets)), ", ")))
+		return
+	}
+
+	environment := Environments[preset.Environment]
+	if !slices.Contains(message.Member.Roles, environment.Role) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	log.Printf("Running preset %s. Username: %s (%s) - Environment: %s", preset.Name, message.Author.Username, message.Author.ID, environment.Name)
+	if environment.Channel != message.ChannelID {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "preset.started", "preset", preset.Name, "environment", environment.Name, "channel", environment.Channel))
+	}
+
+	args = preset.args(args[1:])
+	if Commands[preset.Key].Aggregate() {
+		go deployPlan(session, environment.Channel, environment, message.Author, args)
+		return
+	}
+
+	deployment, code, err := newDeployment(environment, message.Author, args)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, err.Error())
+		return
+	}
+
+	go func() {
+		if deployment.Entry.TOTP && !confirmTOTP(session, environment.Channel, deployment, code) {
+			deployment.finish(errTOTP)
+			return
+		}
+		startDeployment(session, environment.Channel, deployment)
+	}()
+}
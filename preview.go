@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/user"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"deploy/engine"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+type Preview struct {
+	Key        string   `json:"key"`
+	Location   string   `json:"location"`
+	URL        string   `json:"url"`
+	Teardown   string   `json:"teardown"`
+	TTL        string   `json:"ttl"`
+	Branches   []string `json:"branches"`
+	Repository string   `json:"repository"`
+}
+
+type PreviewState struct {
+	Environment string    `json:"environment"`
+	Number      int       `json:"number"`
+	Branch      string    `json:"branch"`
+	SHA         string    `json:"sha"`
+	URL         string    `json:"url"`
+	Location    string    `json:"location"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+func previewName(host *Environment, number int) string {
+	return fmt.Sprintf("%s-pr-%d", host.Name, number)
+}
+
+func previewVars(number int) map[string]string {
+	return map[string]string{"PR_NUMBER": strconv.Itoa(number)}
+}
+
+func previewEnvironment(host *Environment, number int) *Environment {
+	environment := *host
+	environment.Name = previewName(host, number)
+	environment.Location = strings.ReplaceAll(host.Preview.Location, "${PR_NUMBER}", strconv.Itoa(number))
+	environment.Maintenance, environment.Smoke = nil, nil
+	environment.AutoDeploy, environment.Preview = nil, nil
+	environment.host = host
+	return &environment
+}
+
+func (preview *Preview) matches(event *WebhookEvent) bool {
+	if preview.Repository != "" && !strings.EqualFold(preview.Repository, event.Repository) {
+		return false
+	}
+
+	return len(preview.Branches) == 0 || slices.ContainsFunc(preview.Branches, func(pattern string) bool {
+		matched, err := path.Match(pattern, event.Branch)
+		return err == nil && matched
+	})
+}
+
+func (preview *Preview) ttl() time.Duration {
+	ttl, _ := time.ParseDuration(preview.TTL)
+	return ttl
+}
+
+func (deployment *Deployment) previewURL() string {
+	host := deployment.Environment.host
+	if host == nil || host.Preview == nil {
+		return ""
+	}
+
+	return deployment.expand(host.Preview.URL)
+}
+
+func trackPreview(deployment *Deployment, url string) {
+	name := deployment.Environment.Name
+	store.Update(func(state *State) {
+		preview, ok := state.Previews[name]
+		if !ok {
+			preview = &PreviewState{Environment: deployment.Environment.host.Name, Number: deployment.PullRequest, Created: time.Now().UTC()}
+			state.Previews[name] = preview
+		}
+		preview.Branch, preview.SHA, preview.URL = deployment.Branch, deployment.SHA, url
+		preview.Location, preview.Updated = deployment.Environment.Location, time.Now().UTC()
+	})
+}
+
+func createPreviewLocation(location string) error {
+	if err := os.MkdirAll(location, 0o755); err != nil {
+		return fmt.Errorf("os.MkdirAll(): %w", err)
+	}
+
+	if data.DeployUser == "" {
+		return nil
+	}
+
+	account, err := user.Lookup(data.DeployUser)
+	if err != nil {
+		return err
+	}
+
+	uid, _ := strconv.Atoi(account.Uid)
+	gid, _ := strconv.Atoi(account.Gid)
+	if err := os.Chown(location, uid, gid); err != nil {
+		return fmt.Errorf("os.Chown(): %w", err)
+	}
+
+	return nil
+}
+
+func previewEvent(session *discordgo.Session, event *WebhookEvent) {
+	if event.Name != "pull_request" {
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(Environments)) {
+		host := Environments[name]
+		if host.Preview == nil || !host.Preview.matches(event) {
+			continue
+		}
+
+		switch event.Action {
+		case "opened", "reopened", "synchronize":
+			deployPreview(session, host, event)
+		case "closed":
+			var preview *PreviewState
+			store.View(func(state *State) { preview = state.Previews[previewName(host, event.Number)] })
+			if preview != nil {
+				teardownPreview(session, previewName(host, event.Number), preview, text(host.Channel, "preview.closed", "number", event.Number, "sender", event.Sender))
+			}
+		}
+	}
+}
+
+func deployPreview(session *discordgo.Session, host *Environment, event *WebhookEvent) {
+	environment := previewEnvironment(host, event.Number)
+	if err := createPreviewLocation(environment.Location); err != nil {
+		log.Printf("createPreviewLocation(): %v", err)
+		session.ChannelMessageSend(host.Channel, text(host.Channel, "preview.location_failed", "preview", environment.Name, "location", environment.Location))
+		return
+	}
+
+	reason := text(host.Channel, "preview.reason", "event", event.describe(), "sha", fmt.Sprintf("%.7s", event.SHA))
+	deployment, _, err := newDeployment(environment, session.State.User, []string{event.Branch, host.Preview.Key, reason})
+	if err != nil {
+		session.ChannelMessageSend(host.Channel, text(host.Channel, "preview.skipped", "preview", environment.Name, "error", err.Error()))
+		return
+	}
+	deployment.PullRequest, deployment.SHA = event.Number, event.SHA
+
+	log.Printf("Deploying preview %s for %s", environment.Name, event.describe())
+	session.ChannelMessageSend(host.Channel, text(host.Channel, "preview.deploying", "preview", environment.Name, "branch", event.Branch, "event", event.describe()))
+	startDeployment(session, host.Channel, deployment)
+}
+
+func teardownPreview(session *discordgo.Session, name string, preview *PreviewState, why string) {
+	host := Environments[preview.Environment]
+	if host != nil && host.Preview != nil && host.Preview.Teardown != "" {
+		if _, err := os.Stat(preview.Location); preview.Location == "" || err != nil {
+			log.Printf("teardownPreview(%s): skipping teardown, location %q is missing", name, preview.Location)
+		} else if err := runTeardown(session, host, name, preview); err != nil {
+			return
+		}
+	}
+
+	store.Update(func(state *State) {
+		delete(state.Previews, name)
+		delete(state.Deployed, name)
+	})
+
+	log.Printf("Preview %s torn down after %s", name, why)
+	if host != nil {
+		session.ChannelMessageSend(host.Channel, text(host.Channel, "preview.torn_down", "preview", name, "why", why))
+	}
+}
+
+func runTeardown(session *discordgo.Session, host *Environment, name string, preview *PreviewState) error {
+	entry, ok := Commands[host.Preview.Key]
+	if !ok {
+		entry = &Entry{}
+	}
+
+	environment := previewEnvironment(host, preview.Number)
+	environment.Location = preview.Location
+	deployment := &Deployment{
+		ID:          newID(),
+		Environment: environment,
+		Key:         host.Preview.Key,
+		Entry:       entry,
+		Branch:      preview.Branch,
+		SHA:         preview.SHA,
+		Author:      session.State.User,
+		Started:     time.Now(),
+		PullRequest: preview.Number,
+	}
+
+	queue, cancelQueue := context.WithCancelCause(context.Background())
+	defer cancelQueue(nil)
+	deployment.cancel = cancelQueue
+
+	if err := scheduler.Acquire(queue, deployment, true, func(conflict *ConflictError) {
+		log.Printf("teardownPreview(%s): waiting for deployment %s on %s", name, conflict.Deployment.ID, conflict.Location)
+	}); err != nil {
+		if cause := context.Cause(queue); cause != nil {
+			err = cause
+		}
+		log.Printf("teardownPreview(%s): %v", name, err)
+		session.ChannelMessageSend(host.Channel, text(host.Channel, "preview.teardown_failed", "preview", name, "error", err.Error()))
+		return err
+	}
+	defer scheduler.Release(deployment)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	request := &engine.Request{
+		Entry:       entry,
+		Environment: name,
+		Location:    preview.Location,
+		Ref:         preview.Branch,
+		SHA:         preview.SHA,
+		Vars:        previewVars(preview.Number),
+		Executor:    executorOptions(host.Shell, nil),
+	}
+
+	output, err := request.Execute(ctx, preview.Location, host.Preview.Teardown)
+	if err != nil {
+		log.Printf("teardownPreview(%s): %v\n%s", name, err, output)
+		session.ChannelMessageSend(host.Channel, text(host.Channel, "preview.teardown_failed", "preview", name, "error", err.Error())+"\n```\n"+tail(sanitizeOutput(string(output)), 1500)+"\n```")
+	}
+	return err
+}
+
+func expirePreviews(session *discordgo.Session) {
+	for range time.Tick(10 * time.Minute) {
+		expired := map[string]*PreviewState{}
+		store.View(func(state *State) {
+			for name, preview := range state.Previews {
+				host := Environments[preview.Environment]
+				if host == nil || host.Preview == nil || host.Preview.ttl() <= 0 {
+					continue
+				}
+				if time.Since(preview.Updated) > host.Preview.ttl() {
+					expired[name] = preview
+				}
+			}
+		})
+
+		for _, name := range slices.Sorted(maps.Keys(expired)) {
+			teardownPreview(session, name, expired[name], text(Environments[expired[name].Environment].Channel, "preview.expiring"))
+		}
+	}
+}
+
+func previews(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) == 0 || !strings.EqualFold(args[0], "list") {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "preview.usage"))
+		return
+	}
+
+	host := environmentByChannel(message.ChannelID)
+	lines := []string{}
+	store.View(func(state *State) {
+		for _, name := range slices.Sorted(maps.Keys(state.Previews)) {
+			preview := state.Previews[name]
+			if preview.Environment != host.Name {
+				continue
+			}
+
+			line := text(message.ChannelID, "preview.line", "preview", name, "number", preview.Number, "branch", preview.Branch, "sha", fmt.Sprintf("%.7s", preview.SHA), "url", preview.URL, "updated", preview.Updated.Unix())
+			if host.Preview != nil && host.Preview.ttl() > 0 {
+				line += text(message.ChannelID, "preview.expires", "expires", preview.Updated.Add(host.Preview.ttl()).Unix())
+			}
+			lines = append(lines, line)
+		}
+	})
+
+	if len(lines) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "preview.none", "environment", host.Name))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, truncate(strings.Join(lines, "\n"), 1900))
+}
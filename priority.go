@@ -0,0 +1,53 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+var priorities = map[string]int{"routine": 0, "hotfix": 1}
+
+func flagArg(args []string, name string) ([]string, string, bool) {
+	for i, arg := range args {
+		value, ok := strings.CutPrefix(arg, "--"+name+"=")
+		if ok {
+			return append(args[:i:i], args[i+1:]...), value, true
+		}
+
+		if arg == "--"+name {
+			if i+1 >= len(args) {
+				return args[:i:i], "", true
+			}
+			return append(args[:i:i], args[i+2:]...), args[i+1], true
+		}
+	}
+
+	return args, "", false
+}
+
+func priorityArgs(channelID string, args []string) ([]string, string, bool, error) {
+	preempt := false
+	if i := slices.Index(args, "--preempt"); i >= 0 {
+		args, preempt = append(args[:i:i], args[i+1:]...), true
+	}
+
+	rest, priority, found := flagArg(args, "priority")
+	if !found {
+		priority, rest = "routine", args
+	}
+
+	if _, ok := priorities[strings.ToLower(priority)]; !ok {
+		return nil, "", false, textError(channelID, "priority.invalid", "priority", priority, "expected", strings.Join(slices.Sorted(maps.Keys(priorities)), " or "))
+	}
+
+	if preempt && priorities[strings.ToLower(priority)] == 0 {
+		return nil, "", false, textError(channelID, "priority.preempt")
+	}
+
+	return rest, strings.ToLower(priority), preempt, nil
+}
+
+func (deployment *Deployment) priority() int {
+	return priorities[deployment.Priority]
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"deploy/engine"
+)
+
+const progressInterval = 10 * time.Second
+
+type progressStep struct {
+	label    string
+	glyph    string
+	duration time.Duration
+}
+
+type Progress struct {
+	mu      sync.Mutex
+	step    int
+	steps   []progressStep
+	detail  string
+	started time.Time
+	current time.Time
+	changed chan struct{}
+}
+
+func newProgress(steps []*Step) *Progress {
+	progress := &Progress{started: time.Now(), changed: make(chan struct{}, 1)}
+	for i, step := range steps {
+		progress.steps = append(progress.steps, progressStep{label: step.Label(i), glyph: "▫️"})
+	}
+	return progress
+}
+
+func (progress *Progress) signal() {
+	select {
+	case progress.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (progress *Progress) Set(step int, label string) {
+	if progress == nil {
+		return
+	}
+
+	progress.mu.Lock()
+	progress.step, progress.detail, progress.current = step, "", time.Now()
+	if step > 0 && step <= len(progress.steps) {
+		progress.steps[step-1].label, progress.steps[step-1].glyph = label, "▶️"
+	}
+	progress.mu.Unlock()
+
+	progress.signal()
+}
+
+func (progress *Progress) Finish(step int, result engine.StepResult) {
+	if progress == nil {
+		return
+	}
+
+	progress.mu.Lock()
+	if step > 0 && step <= len(progress.steps) {
+		switch current := &progress.steps[step-1]; {
+		case result.Err != nil:
+			current.glyph, current.duration = "❌", time.Since(progress.current)
+		case result.Skipped:
+			current.glyph = "⏭️"
+		default:
+			current.glyph, current.duration = "✅", result.Duration
+		}
+	}
+	progress.detail = ""
+	progress.mu.Unlock()
+
+	progress.signal()
+}
+
+func (progress *Progress) Detail(detail string) {
+	if progress == nil {
+		return
+	}
+
+	progress.mu.Lock()
+	changed := progress.detail != detail
+	progress.detail = detail
+	progress.mu.Unlock()
+
+	if changed {
+		progress.signal()
+	}
+}
+
+func elapsed(since time.Time) string {
+	d := time.Since(since).Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func (progress *Progress) Render() string {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	lines := []string{fmt.Sprintf("`[%d/%d]` %s elapsed", progress.step, len(progress.steps), elapsed(progress.started))}
+	for _, step := range progress.steps {
+		line := step.glyph + " " + step.label
+		switch {
+		case step.glyph == "▶️":
+			line += "… " + elapsed(progress.current)
+			if progress.detail != "" {
+				line += "\n" + progress.detail
+			}
+		case step.duration > 0:
+			line += fmt.Sprintf(" (%s)", step.duration.Round(time.Second))
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (progress *Progress) Track(ctx context.Context, update func(string)) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-progress.changed:
+		}
+		update(progress.Render())
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func promotionSource(environment *Environment, key string) *Record {
+	var source *Record
+	store.View(func(state *State) {
+		for _, record := range slices.Backward(state.History) {
+			if record.Environment == environment.Name && (key == "" || record.Key == key) && record.Status == "success" && record.SHA != "" {
+				source = record
+				return
+			}
+		}
+	})
+	return source
+}
+
+func promote(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 2 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "promote.usage"))
+		return
+	}
+
+	from, to := environmentByName(args[0]), environmentByName(args[1])
+	for i, environment := range []*Environment{from, to} {
+		if environment == nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "maintenance.invalid_environment", "environment", args[i]))
+			return
+		}
+	}
+
+	if from == to || !slices.Contains(message.Member.Roles, to.Role) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	key, rest := "", args[2:]
+	if len(rest) > 0 && Commands[rest[0]] != nil {
+		key, rest = rest[0], rest[1:]
+	}
+
+	source := promotionSource(from, key)
+	if source == nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "promote.nothing", "from", from.Name, "key", key))
+		return
+	}
+
+	deployment, code, err := promotion(message.ChannelID, from, to, source, message.Author, rest)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, err.Error())
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, deployment.text(message.ChannelID, "promote.started", "from", from.Name, "sha", fmt.Sprintf("%.7s", source.SHA), "source", source.Ref, "channel", to.Channel))
+	log.Printf("Promoting %s@%.7s from %s to %s. Username: %s (%s)", source.Key, source.SHA, from.Name, to.Name, message.Author.Username, message.Author.ID)
+	launchPromotion(session, deployment, code)
+}
+
+func promotion(channelID string, from, to *Environment, source *Record, author *discordgo.User, rest []string) (*Deployment, string, error) {
+	ref := source.SHA
+	if entry := Commands[source.Key]; entry != nil && entry.Strategy == "artifact" {
+		ref = source.Ref
+	} else if !refAllowed(to, "commit") {
+		return nil, "", errors.New(text(channelID, "promote.commit_refs", "to", to.Name))
+	}
+
+	deployment, code, err := newDeployment(to, author, append([]string{ref, source.Key}, rest...))
+	if err != nil {
+		return nil, "", err
+	}
+	deployment.Promoted = fmt.Sprintf("%s (`%.7s`, %s)", from.Name, source.SHA, source.ID)
+
+	return deployment, code, nil
+}
+
+func launchPromotion(session *discordgo.Session, deployment *Deployment, code string) {
+	go func() {
+		if deployment.Entry.TOTP && !confirmTOTP(session, deployment.Environment.Channel, deployment, code) {
+			deployment.finish(errTOTP)
+			return
+		}
+		startDeployment(session, deployment.Environment.Channel, deployment)
+	}()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"deploy/engine"
+)
+
+const cloudflarePurgeBatch = 30
+
+func runPurge(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+	with := map[string]string{}
+	for key, value := range step.With {
+		with[key] = request.Expand(value)
+	}
+
+	switch with["provider"] {
+	case "", "cloudflare":
+		return purgeCloudflare(ctx, with, output)
+	case "generic":
+		return purgeGeneric(ctx, with, output)
+	}
+
+	return fmt.Errorf("unknown purge provider %q", with["provider"])
+}
+
+func purgeCloudflare(ctx context.Context, with map[string]string, output *bytes.Buffer) error {
+	name := with["token"]
+	if name == "" {
+		name = "CLOUDFLARE_API_TOKEN"
+	}
+
+	token := secret(name, os.Getenv(name))
+	if token == "" {
+		return fmt.Errorf("cloudflare token %s is not set", name)
+	}
+
+	authorize := func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	bodies := []map[string]any{}
+	if with["everything"] == "true" {
+		bodies = append(bodies, map[string]any{"purge_everything": true})
+	}
+
+	for _, scope := range []string{"files", "tags", "hosts", "prefixes"} {
+		values := strings.Fields(with[scope])
+		for start := 0; start < len(values); start += cloudflarePurgeBatch {
+			bodies = append(bodies, map[string]any{scope: values[start:min(start+cloudflarePurgeBatch, len(values))]})
+		}
+	}
+
+	if len(bodies) == 0 {
+		return errors.New("cloudflare purge needs files, tags, hosts, prefixes or everything")
+	}
+
+	for _, zone := range strings.Fields(with["zone"]) {
+		for _, body := range bodies {
+			result := struct {
+				Success bool `json:"success"`
+				Errors  []struct {
+					Code    int    `json:"code"`
+					Message string `json:"message"`
+				} `json:"errors"`
+			}{}
+
+			target := "https://api.cloudflare.com/client/v4/zones/" + url.PathEscape(zone) + "/purge_cache"
+			if _, err := apiRequest(ctx, http.MethodPost, target, body, authorize, &result); err != nil {
+				return fmt.Errorf("cloudflare zone %s: %w", zone, err)
+			}
+
+			if !result.Success {
+				messages := []string{}
+				for _, failure := range result.Errors {
+					messages = append(messages, fmt.Sprintf("%d %s", failure.Code, failure.Message))
+				}
+				return fmt.Errorf("cloudflare zone %s: %s", zone, strings.Join(messages, "; "))
+			}
+
+			for scope, values := range body {
+				fmt.Fprintf(output, "Purged %s %v from zone %s\n", scope, values, zone)
+			}
+		}
+	}
+
+	return nil
+}
+
+func purgeGeneric(ctx context.Context, with map[string]string, output *bytes.Buffer) error {
+	method := with["method"]
+	if method == "" {
+		method = "PURGE"
+	}
+
+	files := strings.Fields(with["files"])
+	if len(files) == 0 {
+		return errors.New("generic purge needs files")
+	}
+
+	name := with["token"]
+	token := secret(name, os.Getenv(name))
+	authorize := func(req *http.Request) {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	for _, file := range files {
+		if _, err := apiRequest(ctx, method, file, nil, authorize, nil); err != nil {
+			return err
+		}
+		fmt.Fprintf(output, "Purged %s\n", file)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func queueView(channelID string, environment *Environment) (string, []discordgo.MessageComponent) {
+	lines, cancels := []string{}, []discordgo.Button{}
+	for _, queued := range scheduler.Queued() {
+		if queued.Environment != environment {
+			continue
+		}
+
+		line := queued.text(channelID, "queue.line", "position", len(lines)+1, "location", queued.Environment.Location, "queued", queued.Started.Unix())
+		if queued.priority() > 0 {
+			line += fmt.Sprintf(" **%s**", queued.Priority)
+			if queued.jumped > 0 {
+				line += text(channelID, "queue.jumped", "count", queued.jumped)
+			}
+		}
+		lines = append(lines, line)
+		if len(cancels) < 25 {
+			cancels = append(cancels, discordgo.Button{Label: text(channelID, "queue.cancel", "id", queued.ID), Style: discordgo.DangerButton, CustomID: "queue:" + queued.ID})
+		}
+	}
+
+	if len(lines) == 0 {
+		return text(channelID, "queue.empty", "environment", environment.Name), []discordgo.MessageComponent{}
+	}
+
+	components := []discordgo.MessageComponent{}
+	for chunk := range slices.Chunk(cancels, 5) {
+		components = append(components, buttons(chunk...)...)
+	}
+
+	return text(channelID, "queue.title", "environment", environment.Name) + "\n" + strings.Join(lines, "\n"), components
+}
+
+func queue(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	content, components := queueView(message.ChannelID, environmentByChannel(message.ChannelID))
+	session.ChannelMessageSendComplex(message.ChannelID, &discordgo.MessageSend{Content: content, Components: components})
+}
+
+func cancelQueued(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 1 {
+		return
+	}
+
+	user := interaction.Member.User
+	for _, queued := range scheduler.Queued() {
+		if queued.ID != args[0] {
+			continue
+		}
+
+		if queued.Author.ID != user.ID && (data.AdminRole == "" || !slices.Contains(interaction.Member.Roles, data.AdminRole)) {
+			respondEphemeral(session, interaction, text(interaction.ChannelID, "queue.cancel_forbidden"))
+			return
+		}
+
+		if _, ok := scheduler.Cancel(queued.ID, user); ok {
+			log.Printf("Queued deployment cancelled. Username: %s (%s) - Deployment: %s", user.Username, user.ID, queued.ID)
+		}
+		break
+	}
+
+	content, components := queueView(interaction.ChannelID, environmentByChannel(interaction.ChannelID))
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Content: content, Components: components},
+	})
+}
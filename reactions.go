@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const quickActionTTL = 24 * time.Hour
+
+var (
+	quickReactions = map[string]string{"retry": "🔁", "rollback": "⏪", "logs": "📄"}
+	quickActionsMu sync.Mutex
+	quickActions   = map[string]*Deployment{}
+)
+
+var quickActionHandlers = map[string]func(*discordgo.Session, *discordgo.MessageCreate, *Deployment){
+	"retry":    retryDeployment,
+	"rollback": rollbackDeployment,
+	"logs": func(session *discordgo.Session, message *discordgo.MessageCreate, deployment *Deployment) {
+		logs(session, message, []string{deployment.ID})
+	},
+}
+
+func parseQuickReactions(value string) (map[string]string, error) {
+	switch strings.TrimSpace(value) {
+	case "":
+		return quickReactions, nil
+	case "none":
+		return map[string]string{}, nil
+	}
+
+	reactions := map[string]string{}
+	for pair := range strings.SplitSeq(value, ",") {
+		action, emoji, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if _, known := quickActionHandlers[action]; !ok || !known || strings.TrimSpace(emoji) == "" {
+			return nil, fmt.Errorf("invalid quick reaction %q, expected retry, rollback or logs=<emoji>", pair)
+		}
+		reactions[action] = strings.TrimSpace(emoji)
+	}
+
+	return reactions, nil
+}
+
+func (deployment *Deployment) addQuickReactions(session *discordgo.Session, msg *discordgo.Message) {
+	if len(quickReactions) == 0 {
+		return
+	}
+
+	quickActionsMu.Lock()
+	quickActions[msg.ID] = deployment
+	quickActionsMu.Unlock()
+
+	time.AfterFunc(quickActionTTL, func() {
+		quickActionsMu.Lock()
+		delete(quickActions, msg.ID)
+		quickActionsMu.Unlock()
+	})
+
+	for _, action := range []string{"retry", "rollback", "logs"} {
+		if emoji, ok := quickReactions[action]; ok {
+			session.MessageReactionAdd(msg.ChannelID, msg.ID, emoji)
+		}
+	}
+}
+
+func messageReactionAdd(session *discordgo.Session, reaction *discordgo.MessageReactionAdd) {
+	if reaction.UserID == session.State.User.ID || !guildAllowed(reaction.GuildID) {
+		return
+	}
+
+	quickActionsMu.Lock()
+	deployment, ok := quickActions[reaction.MessageID]
+	quickActionsMu.Unlock()
+	if !ok {
+		return
+	}
+
+	action := ""
+	for name, emoji := range quickReactions {
+		if emoji == reaction.Emoji.APIName() || emoji == reaction.Emoji.Name {
+			action = name
+		}
+	}
+	if action == "" {
+		return
+	}
+
+	session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+
+	member := reaction.Member
+	if member != nil && member.User != nil {
+		cacheMember(reaction.GuildID, member)
+	} else {
+		var err error
+		if member, err = guildMember(session, reaction.GuildID, reaction.UserID); err != nil {
+			return
+		}
+	}
+
+	if !slices.Contains(member.Roles, deployment.Environment.Role) {
+		session.ChannelMessageSend(reaction.ChannelID, fmt.Sprintf("<@%s> %s", reaction.UserID, text(reaction.ChannelID, "interaction.forbidden")))
+		return
+	}
+
+	message := &discordgo.MessageCreate{Message: &discordgo.Message{ChannelID: reaction.ChannelID, GuildID: reaction.GuildID, Author: member.User}}
+	message.Member = member
+	quickActionHandlers[action](session, message, deployment)
+}
+
+func retryDeployment(session *discordgo.Session, message *discordgo.MessageCreate, deployment *Deployment) {
+	requestDeployment(session, deployment.Environment.Channel, deployment.Environment, message.Author, []string{deployment.Branch, deployment.Key})
+}
+
+func rollbackDeployment(session *discordgo.Session, message *discordgo.MessageCreate, deployment *Deployment) {
+	rollbackTo(session, deployment, message.Author, false)
+}
+
+func previousSuccess(deployment *Deployment) *Record {
+	var previous *Record
+	store.View(func(state *State) {
+		started := false
+		for _, record := range slices.Backward(state.History) {
+			if record.ID == deployment.ID {
+				started = true
+				continue
+			}
+			if started && record.Environment == deployment.Environment.Name && record.Key == deployment.Key && record.Status == "success" && (record.SHA != "" || deployment.Entry.Strategy == "artifact") {
+				previous = record
+				return
+			}
+		}
+	})
+	return previous
+}
+
+// rollbackTo redeploys the last successful deployment of the same key pinned
+// to its recorded commit, bypassing the ref policy. System rollbacks skip the
+// TOTP prompt since nobody is waiting to answer it.
+func rollbackTo(session *discordgo.Session, deployment *Deployment, author *discordgo.User, system bool) {
+	channelID := deployment.Environment.Channel
+	previous := previousSuccess(deployment)
+	if previous == nil {
+		session.ChannelMessageSend(channelID, deployment.text(channelID, "deploy.no_rollback"))
+		return
+	}
+
+	rollback, err := newRollback(deployment.Environment, previous, author)
+	if err != nil {
+		session.ChannelMessageSend(channelID, err.Error())
+		return
+	}
+
+	if system || !rollback.Entry.TOTP {
+		startDeployment(session, channelID, rollback)
+		return
+	}
+
+	go func() {
+		if !confirmTOTP(session, channelID, rollback, "") {
+			rollback.finish(errTOTP)
+			return
+		}
+		startDeployment(session, channelID, rollback)
+	}()
+}
+
+func newRollback(environment *Environment, previous *Record, author *discordgo.User) (*Deployment, error) {
+	entry, ok := Commands[previous.Key]
+	if !ok {
+		return nil, textError(environment.Channel, "validate.invalid_key", "key", previous.Key)
+	}
+
+	if current := lockOf(environment); current != nil && current.Author != author.ID {
+		return nil, errors.New(current.describe(environment) + " - !unlock")
+	}
+
+	deployment := &Deployment{
+		ID:          newID(),
+		Environment: environment,
+		Key:         previous.Key,
+		Entry:       entry,
+		Branch:      previous.SHA,
+		RefType:     "commit",
+		SHA:         previous.SHA,
+		Author:      author,
+		Started:     time.Now(),
+		Reason:      text(environment.Channel, "deploy.rollback_reason", "id", previous.ID),
+	}
+	if entry.Strategy == "artifact" {
+		deployment.Branch, deployment.RefType = previous.Ref, previous.RefType
+	}
+	if current := lockOf(environment); current != nil {
+		deployment.LockedBy = current.Author
+	}
+
+	deployment.trace, _ = tracer.Start(context.Background(), "deployment", trace.WithAttributes(attribute.String("deploy.environment", environment.Name), attribute.String("deploy.requester", author.ID), attribute.String("deploy.id", deployment.ID), attribute.String("deploy.key", deployment.Key), attribute.String("deploy.ref", deployment.Branch), attribute.String("deploy.ref_type", deployment.RefType)))
+	deployment.audit("attempt", "accepted", nil, map[string]string{"reason": deployment.Reason, "rollback": previous.ID})
+
+	return deployment, nil
+}
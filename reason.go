@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func changeReason(environment *Environment, args []string) (reason, ticket string, err error) {
+	reason = strings.Join(args, " ")
+	if environment.Reason == "required" && reason == "" {
+		return "", "", fmt.Errorf("`%s` requires a reason - !deploy <branch> <key> <reason>", environment.Name)
+	}
+
+	if environment.ticket != nil {
+		ticket = environment.ticket.FindString(reason)
+		if ticket == "" {
+			return "", "", fmt.Errorf("`%s` requires a ticket reference matching `%s` in the reason", environment.Name, environment.Ticket)
+		}
+	}
+
+	return reason, ticket, nil
+}
+
+func (deployment *Deployment) changeFields() []Field {
+	fields := []Field{}
+	if deployment.Reason != "" {
+		fields = append(fields, Field{Name: "Change Reason", Value: truncate(deployment.Reason, 1000)})
+	}
+
+	if deployment.Ticket != "" {
+		fields = append(fields, Field{Name: "Ticket", Value: deployment.Ticket, Inline: true})
+	}
+
+	if deployment.Commits != "" {
+		fields = append(fields, Field{Name: "Commits", Value: deployment.Commits, Inline: true})
+	}
+
+	if deployment.Incident != "" {
+		fields = append(fields, Field{Name: "Incident", Value: deployment.Incident, Inline: true})
+	}
+
+	if deployment.Approved != "" {
+		fields = append(fields, Field{Name: "Approved By", Value: fmt.Sprintf("<@%s>", deployment.Approved), Inline: true})
+	}
+
+	if deployment.Promoted != "" {
+		fields = append(fields, Field{Name: "Promoted From", Value: deployment.Promoted, Inline: true})
+	}
+
+	return append(fields, deployment.paramFields()...)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func refAllowed(environment *Environment, refType string) bool {
+	if len(environment.Refs) == 0 {
+		return refType == "branch"
+	}
+
+	return slices.Contains(environment.Refs, refType)
+}
+
+func refDir(deployment *Deployment) string {
+	if deployment.Entry.Strategy == "releases" {
+		return filepath.Join(deployment.Environment.Location, "repo")
+	}
+
+	return deployment.Environment.Location
+}
+
+func resolveRef(ctx context.Context, deployment *Deployment, env []string) error {
+	ref := deployment.Branch
+
+	if commitPattern.MatchString(ref) && refAllowed(deployment.Environment, "commit") {
+		dir := refDir(deployment)
+		if err := gitEnv(ctx, env, &bytes.Buffer{}, "-C", dir, "fetch", "--quiet", "origin"); err != nil {
+			return err
+		}
+
+		output := &bytes.Buffer{}
+		if err := git(ctx, output, "-C", dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			return errMissingRef
+		}
+
+		deployment.RefType, deployment.SHA = "commit", strings.TrimSpace(output.String())
+		return nil
+	}
+
+	if branchAllowed(deployment.Environment, ref) {
+		deployment.RefType = "branch"
+		if len(deployment.Environment.Branches) == 0 {
+			return nil
+		}
+
+		output, err := lsRemote(ctx, deployment, env, "--heads", "refs/heads/"+ref)
+		if err != nil {
+			return err
+		}
+
+		deployment.SHA = firstSHA(output, "")
+		return nil
+	}
+
+	if refAllowed(deployment.Environment, "tag") {
+		output, err := lsRemote(ctx, deployment, env, "--tags", "refs/tags/"+ref, "refs/tags/"+ref+"^{}")
+		if err != nil {
+			return err
+		}
+
+		deployment.RefType, deployment.SHA = "tag", firstSHA(output, "^{}")
+		return nil
+	}
+
+	return errMissingRef
+}
+
+func firstSHA(output []byte, preferred string) string {
+	sha := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		if sha == "" || (preferred != "" && strings.HasSuffix(fields[1], preferred)) {
+			sha = fields[0]
+		}
+	}
+
+	return sha
+}
+
+func deployedRevision(ctx context.Context, deployment *Deployment) string {
+	if deployment.SHA != "" {
+		return deployment.SHA
+	}
+
+	dir := deployment.Environment.Location
+	if deployment.Entry.Strategy == "releases" {
+		dir = currentLink(deployment.Environment)
+	}
+
+	output := &bytes.Buffer{}
+	if err := git(ctx, output, "-C", dir, "rev-parse", "HEAD"); err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(output.String())
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/executor"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const releaseFormat = "20060102150405"
+
+var releasePattern = regexp.MustCompile(`^[0-9]{14}$`)
+
+type Release struct {
+	Name     string    `json:"name"`
+	Key      string    `json:"key"`
+	Branch   string    `json:"branch"`
+	Revision string    `json:"revision"`
+	Author   string    `json:"author"`
+	Created  time.Time `json:"created"`
+}
+
+func releasesDir(environment *Environment) string {
+	return filepath.Join(environment.Location, "releases")
+}
+
+func currentLink(environment *Environment) string {
+	return filepath.Join(environment.Location, "current")
+}
+
+func git(ctx context.Context, output *bytes.Buffer, args ...string) error {
+	return gitEnv(ctx, nil, output, args...)
+}
+
+func gitEnv(ctx context.Context, env []string, output *bytes.Buffer, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Stdout, cmd.Stderr = output, output
+	release, err := executor.Prepare(cmd, executorOptions("", env))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %w", args[0], err)
+	}
+
+	return nil
+}
+
+func deployRelease(ctx context.Context, deployment *Deployment) ([]byte, error) {
+	output := &bytes.Buffer{}
+	entry, environment := deployment.Entry, deployment.Environment
+	mirror := filepath.Join(environment.Location, "repo")
+
+	if _, err := os.Stat(mirror); os.IsNotExist(err) {
+		if err := gitEnv(ctx, deployment.gitEnv, output, "clone", "--mirror", entry.Repository, mirror); err != nil {
+			return output.Bytes(), err
+		}
+	} else if err := gitEnv(ctx, deployment.gitEnv, output, "-C", mirror, "fetch", "--prune", "origin"); err != nil {
+		return output.Bytes(), err
+	}
+
+	release := Release{Name: time.Now().UTC().Format(releaseFormat), Key: deployment.Key, Branch: deployment.Branch, Author: deployment.Author.ID, Created: time.Now().UTC()}
+	dir := filepath.Join(releasesDir(environment), release.Name)
+
+	if err := git(ctx, output, "clone", "--no-checkout", mirror, dir); err != nil {
+		return output.Bytes(), err
+	}
+
+	ref := deployment.Branch
+	if deployment.SHA != "" {
+		ref = deployment.SHA
+	}
+
+	if err := git(ctx, output, "-C", dir, "checkout", "--quiet", ref); err != nil {
+		os.RemoveAll(dir)
+		return output.Bytes(), err
+	}
+
+	revision := &bytes.Buffer{}
+	if err := git(ctx, revision, "-C", dir, "rev-parse", "HEAD"); err != nil {
+		os.RemoveAll(dir)
+		return output.Bytes(), err
+	}
+	release.Revision = strings.TrimSpace(revision.String())
+
+	if err := writeRelease(dir, &release); err != nil {
+		os.RemoveAll(dir)
+		return output.Bytes(), err
+	}
+
+	if entry.Build != "" {
+		out, err := deployment.execute(ctx, dir, entry.Build, "${RELEASE}", dir)
+		output.Write(out)
+		if err != nil {
+			os.RemoveAll(dir)
+			return output.Bytes(), fmt.Errorf("build: %w", err)
+		}
+	}
+
+	if err := activateRelease(ctx, deployment, dir, output); err != nil {
+		return output.Bytes(), err
+	}
+
+	keep := entry.Keep
+	if keep <= 0 {
+		keep = 5
+	}
+
+	_, err := pruneReleases(environment, keep)
+	return output.Bytes(), err
+}
+
+func switchCurrent(environment *Environment, dir string) error {
+	tmp := currentLink(environment) + ".tmp"
+	os.Remove(tmp)
+
+	if err := os.Symlink(dir, tmp); err != nil {
+		return fmt.Errorf("os.Symlink(): %w", err)
+	}
+
+	if err := os.Rename(tmp, currentLink(environment)); err != nil {
+		return fmt.Errorf("os.Rename(): %w", err)
+	}
+
+	return nil
+}
+
+func activateRelease(ctx context.Context, deployment *Deployment, dir string, output *bytes.Buffer) error {
+	entry, environment := deployment.Entry, deployment.Environment
+	previous, _ := os.Readlink(currentLink(environment))
+
+	if err := switchCurrent(environment, dir); err != nil {
+		return err
+	}
+
+	if entry.Restart == "" {
+		return nil
+	}
+
+	out, err := deployment.execute(ctx, dir, entry.Restart, "${RELEASE}", dir)
+	output.Write(out)
+	if err == nil {
+		return nil
+	}
+
+	if previous == "" {
+		return fmt.Errorf("restart: %w", err)
+	}
+
+	if restoreErr := switchCurrent(environment, previous); restoreErr != nil {
+		return fmt.Errorf("restart: %w, restoring %s: %v", err, filepath.Base(previous), restoreErr)
+	}
+	fmt.Fprintf(output, "Restart failed, current restored to %s\n", filepath.Base(previous))
+	return fmt.Errorf("restart: %w, current restored to %s", err, filepath.Base(previous))
+}
+
+func writeRelease(dir string, release *Release) error {
+	body, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent(): %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".release"), body, 0o644); err != nil {
+		return fmt.Errorf("os.WriteFile(): %w", err)
+	}
+
+	return nil
+}
+
+func readRelease(environment *Environment, name string) (*Release, error) {
+	body, err := os.ReadFile(filepath.Join(releasesDir(environment), name, ".release"))
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	release := &Release{}
+	if err := json.Unmarshal(body, release); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+
+	return release, nil
+}
+
+func listReleases(environment *Environment) ([]string, error) {
+	entries, err := os.ReadDir(releasesDir(environment))
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir(): %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && releasePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
+
+func currentRelease(environment *Environment) string {
+	target, err := os.Readlink(currentLink(environment))
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Base(target)
+}
+
+func pruneReleases(environment *Environment, keep int) (pruned int, err error) {
+	names, err := listReleases(environment)
+	if err != nil || len(names) <= keep {
+		return 0, err
+	}
+
+	current := currentRelease(environment)
+	for _, name := range names[:len(names)-keep] {
+		if name == current {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(releasesDir(environment), name)); err != nil {
+			return pruned, fmt.Errorf("os.RemoveAll(): %w", err)
+		}
+		pruned++
+	}
+
+	return pruned, nil
+}
+
+func releases(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 1 || strings.ToLower(args[0]) != "list" {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "releases.usage"))
+		return
+	}
+
+	environment := environmentByChannel(message.ChannelID)
+	names, err := listReleases(environment)
+	if err != nil || len(names) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "releases.none"))
+		return
+	}
+
+	current := currentRelease(environment)
+	lines := []string{}
+	for _, name := range slices.Backward(names) {
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+
+		line := fmt.Sprintf("%s %s", marker, name)
+		if release, err := readRelease(environment, name); err == nil {
+			line += fmt.Sprintf("  %-12s %-20s %.7s", release.Key, release.Branch, release.Revision)
+		}
+		lines = append(lines, line)
+	}
+
+	session.ChannelMessageSend(message.ChannelID, "```\n"+strings.Join(lines, "\n")+"\n```")
+}
+
+func rollback(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 1 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "rollback.usage"))
+		return
+	}
+
+	name := args[0]
+	if !releasePattern.MatchString(name) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "rollback.invalid_release", "release", name))
+		return
+	}
+
+	environment := environmentByChannel(message.ChannelID)
+	release, err := readRelease(environment, name)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "rollback.invalid_release", "release", name))
+		return
+	}
+
+	if name == currentRelease(environment) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "rollback.already_active", "release", name))
+		return
+	}
+
+	if current := lockOf(environment); current != nil && current.Author != message.Author.ID {
+		session.ChannelMessageSend(message.ChannelID, current.describe(environment)+" - !unlock")
+		return
+	}
+
+	entry, ok := Commands[release.Key]
+	if !ok {
+		entry = &Entry{}
+	}
+
+	msg, err := session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "rollback.ongoing"))
+	if err != nil {
+		return
+	}
+
+	deployment := &Deployment{
+		ID:          newID(),
+		Environment: environment,
+		Key:         release.Key,
+		Entry:       entry,
+		Branch:      release.Branch,
+		SHA:         release.Revision,
+		Author:      message.Author,
+		Started:     time.Now(),
+		Reason:      text(message.ChannelID, "rollback.release_reason", "release", name),
+	}
+	if current := lockOf(environment); current != nil {
+		deployment.LockedBy = current.Author
+	}
+	detail := map[string]string{"release": name}
+
+	go func() {
+		queue, cancelQueue := context.WithCancelCause(context.Background())
+		defer cancelQueue(nil)
+		deployment.cancel = cancelQueue
+
+		err := scheduler.Acquire(queue, deployment, environment.OnConflict == "queue", func(conflict *ConflictError) {
+			session.ChannelMessageEdit(message.ChannelID, msg.ID, text(message.ChannelID, "rollback.queued", "location", conflict.Location, "other", conflict.Deployment.ID))
+		})
+		if err != nil {
+			if cause := context.Cause(queue); cause != nil {
+				err = cause
+			}
+			session.ChannelMessageEdit(message.ChannelID, msg.ID, text(message.ChannelID, "rollback.rejected", "error", err.Error()))
+			deployment.audit("rollback", "rejected", nil, map[string]string{"release": name, "error": err.Error()})
+			return
+		}
+		defer scheduler.Release(deployment)
+		deployment.audit("rollback", "started", nil, detail)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		output := &bytes.Buffer{}
+		if err := activateRelease(ctx, deployment, filepath.Join(releasesDir(environment), name), output); err != nil {
+			recordDeployment(deployment, "failed", err)
+			storeLog(deployment, output.Bytes())
+			deployment.audit("rollback", "failed", nil, map[string]string{"release": name, "error": err.Error()})
+			session.ChannelMessageEdit(message.ChannelID, msg.ID, text(message.ChannelID, "rollback.failed", "error", err.Error()))
+			log.Printf("activateRelease(): %v\n%s", err, output.String())
+			return
+		}
+
+		recordDeployment(deployment, "success", nil)
+		storeLog(deployment, output.Bytes())
+		deployment.audit("rollback", "success", nil, detail)
+		session.ChannelMessageEdit(message.ChannelID, msg.ID, text(message.ChannelID, "rollback.released", "release", name, "sha", fmt.Sprintf("%.7s", release.Revision)))
+		log.Printf("Rollback successful. Username: %s (%s) - Environment: %s - Release: %s", message.Author.Username, message.Author.ID, environment.Name, name)
+	}()
+}
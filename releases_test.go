@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestActivateReleaseRestoresCurrent(t *testing.T) {
+	data = &Config{}
+	testStore(t)
+	environment := &Environment{Name: "production", Location: t.TempDir()}
+	for _, name := range []string{"20260101000000", "20260102000000"} {
+		if err := os.MkdirAll(filepath.Join(releasesDir(environment), name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := filepath.Join(releasesDir(environment), "20260101000000")
+	if err := switchCurrent(environment, previous); err != nil {
+		t.Fatalf("switchCurrent(): %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		restart string
+		want    string
+	}{
+		{"RestartFails", "exit 1", "20260101000000"},
+		{"RestartSucceeds", "true", "20260102000000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deployment := &Deployment{Environment: environment, Entry: &Entry{Restart: test.restart}}
+			err := activateRelease(context.Background(), deployment, filepath.Join(releasesDir(environment), "20260102000000"), &bytes.Buffer{})
+			if (err != nil) != (test.restart == "exit 1") {
+				t.Errorf("activateRelease() error = %v", err)
+			}
+			if got := currentRelease(environment); got != test.want {
+				t.Errorf("currentRelease() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
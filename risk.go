@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const riskWindow = 30 * time.Minute
+
+var (
+	riskTiers = []string{"", "low", "medium", "high"}
+
+	errRiskRejected = errors.New("rejected")
+	errRiskExpired  = errors.New("was not confirmed in time")
+)
+
+func (deployment *Deployment) confirmRisk(session *discordgo.Session, msg *discordgo.Message) (err error) {
+	risk := deployment.Entry.Risk
+	if risk == "" || risk == "low" {
+		return nil
+	}
+
+	defer func() {
+		status := "approved"
+		if err != nil {
+			status = "denied"
+		}
+		deployment.audit("approval", status, nil, map[string]string{"gate": "risk", "risk": risk})
+	}()
+
+	prompt, label := "risk.confirm", "risk.confirm_button"
+	if risk == "high" {
+		prompt, label = "risk.approval", "risk.approve_button"
+	} else if deployment.Author.Bot {
+		prompt = "risk.confirm_automated"
+	}
+
+	id := newID()
+	decision, done := awaitDecision(id)
+	defer done()
+	guardDecision(id, deployment.riskGuard(msg.ChannelID))
+
+	content := deployment.text(msg.ChannelID, prompt, "risk", risk, "window", riskWindow)
+	components := decisionButtons(id,
+		discordgo.Button{Label: text(msg.ChannelID, label), Style: discordgo.SuccessButton, CustomID: "approve"},
+		discordgo.Button{Label: text(msg.ChannelID, "risk.reject_button"), Style: discordgo.DangerButton, CustomID: "reject"},
+	)
+	session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+	defer func() {
+		if err == nil {
+			content, components = deployment.text(msg.ChannelID, "deploy.ongoing"), []discordgo.MessageComponent{}
+			session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+		}
+	}()
+
+	select {
+	case choice := <-decision:
+		if choice.Choice == "reject" {
+			return fmt.Errorf("%w by <@%s>", errRiskRejected, choice.User.ID)
+		}
+		if risk == "high" {
+			deployment.Approved = choice.User.ID
+		}
+		return nil
+	case <-time.After(riskWindow):
+		return errRiskExpired
+	}
+}
+
+func (deployment *Deployment) riskGuard(channelID string) func(Decision) string {
+	return func(choice Decision) string {
+		requester := choice.User.ID == deployment.Author.ID
+		switch {
+		case deployment.Author.Bot:
+			return ""
+		case deployment.Entry.Risk == "medium" && !requester:
+			return deployment.text(channelID, "risk.requester_only")
+		case deployment.Entry.Risk == "high" && requester && choice.Choice == "approve":
+			return deployment.text(channelID, "risk.self_approval")
+		}
+		return ""
+	}
+}
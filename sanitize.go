@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+func sanitizeOutput(output string) string {
+	return strings.ReplaceAll(cleanOutput(output), "```", "`​``")
+}
+
+func cleanOutput(output string) string {
+	output = ansiPattern.ReplaceAllString(output, "")
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if index := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); index >= 0 {
+			line = line[index+1:]
+		}
+
+		lines[i] = strings.Map(func(r rune) rune {
+			if r == '\t' || r >= ' ' && r != 0x7f {
+				return r
+			}
+			return -1
+		}, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+
+	cut := text[:limit]
+	if index := strings.LastIndex(cut, "\n"); index > 0 {
+		cut = cut[:index]
+	}
+
+	return cut + fmt.Sprintf("\n... (%d more lines)", strings.Count(text[len(cut):], "\n"))
+}
+
+func tail(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+
+	start := len(text) - limit
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+
+	cut := text[start:]
+	if index := strings.Index(cut, "\n"); index >= 0 && index < len(cut)-1 {
+		cut = cut[index+1:]
+	}
+
+	return fmt.Sprintf("... (%d earlier lines)\n", strings.Count(text[:len(text)-len(cut)], "\n")) + cut
+}
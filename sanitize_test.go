@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"Short", "ok", 10, "ok"},
+		{"Lines", "one\ntwo\nthree", 9, "one\ntwo\n... (1 more lines)"},
+		{"SingleLine", "abcdef", 3, "abc\n... (0 more lines)"},
+		{"SplitRune", "ab€cd", 4, "ab\n... (0 more lines)"},
+		{"RuneBoundary", "ab€cd", 5, "ab€\n... (0 more lines)"},
+		{"SplitEmoji", "deploy 🚀 done", 9, "deploy \n... (0 more lines)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := truncate(test.text, test.limit)
+			if got != test.want || !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q, want %q", test.text, test.limit, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{"Short", "ok", 10, "ok"},
+		{"Lines", "one\ntwo\nthree", 10, "... (1 earlier lines)\ntwo\nthree"},
+		{"SplitRune", "ab€cd", 4, "... (0 earlier lines)\ncd"},
+		{"RuneBoundary", "ab€cd", 5, "... (0 earlier lines)\n€cd"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := tail(test.text, test.limit)
+			if got != test.want || !utf8.ValidString(got) {
+				t.Errorf("tail(%q, %d) = %q, want %q", test.text, test.limit, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTruncateLongOutput(t *testing.T) {
+	text := strings.Repeat("ä", 1000)
+	for limit := range 20 {
+		if got := truncate(text, limit); !utf8.ValidString(got) {
+			t.Fatalf("truncate(%d) = %q, not valid UTF-8", limit, got)
+		}
+		if got := tail(text, limit); !utf8.ValidString(got) {
+			t.Fatalf("tail(%d) = %q, not valid UTF-8", limit, got)
+		}
+	}
+}
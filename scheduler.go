@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var errCancelled = errors.New("cancelled")
+
+type ConflictError struct {
+	Location   string
+	Deployment *Deployment
+}
+
+func (err *ConflictError) Error() string {
+	return fmt.Sprintf("%s is in use by deployment %s (%s@%s) requested by %s", err.Location, err.Deployment.ID, err.Deployment.Key, err.Deployment.Branch, err.Deployment.Author.Username)
+}
+
+type Scheduler struct {
+	mu      sync.Mutex
+	running map[string]*Deployment
+	queue   []*Deployment
+	changed chan struct{}
+}
+
+var scheduler = &Scheduler{running: map[string]*Deployment{}, changed: make(chan struct{})}
+
+func (deployment *Deployment) locations() []string {
+	locations := []string{deployment.Environment.Location}
+	for _, slot := range deployment.Entry.Slots {
+		locations = append(locations, slot.Location)
+	}
+
+	for _, target := range deployment.Entry.Targets {
+		locations = append(locations, "target:"+target)
+	}
+
+	return locations
+}
+
+func (scheduler *Scheduler) conflict(deployment *Deployment) *ConflictError {
+	for _, location := range deployment.locations() {
+		if other, ok := scheduler.running[location]; ok {
+			return &ConflictError{Location: location, Deployment: other}
+		}
+	}
+
+	return nil
+}
+
+func (scheduler *Scheduler) blocker(deployment *Deployment) *ConflictError {
+	if conflict := scheduler.conflict(deployment); conflict != nil {
+		return conflict
+	}
+
+	for _, queued := range scheduler.queue {
+		if queued == deployment {
+			return nil
+		}
+
+		for _, location := range queued.locations() {
+			if slices.Contains(deployment.locations(), location) {
+				return &ConflictError{Location: location, Deployment: queued}
+			}
+		}
+	}
+
+	return scheduler.groupBlocker(deployment)
+}
+
+func (scheduler *Scheduler) notify() {
+	close(scheduler.changed)
+	scheduler.changed = make(chan struct{})
+}
+
+func (scheduler *Scheduler) dequeue(deployment *Deployment) {
+	scheduler.queue = slices.DeleteFunc(scheduler.queue, func(queued *Deployment) bool { return queued == deployment })
+}
+
+func (scheduler *Scheduler) enqueue(deployment *Deployment) {
+	i := slices.IndexFunc(scheduler.queue, func(queued *Deployment) bool { return queued.priority() < deployment.priority() })
+	if i < 0 {
+		scheduler.queue = append(scheduler.queue, deployment)
+		return
+	}
+
+	deployment.jumped = len(scheduler.queue) - i
+	scheduler.queue = slices.Insert(scheduler.queue, i, deployment)
+
+	if deployment.Preempt {
+		for _, queued := range slices.Clone(scheduler.queue[i+1:]) {
+			if queued.Environment == deployment.Environment && queued.priority() < deployment.priority() && queued.cancel != nil {
+				queued.cancel(fmt.Errorf("%w, preempted by %s deployment `%s`", errCancelled, deployment.Priority, deployment.ID))
+				scheduler.dequeue(queued)
+			}
+		}
+		scheduler.notify()
+	}
+}
+
+func (scheduler *Scheduler) Acquire(ctx context.Context, deployment *Deployment, wait bool, queued func(*ConflictError)) error {
+	scheduler.mu.Lock()
+
+	conflict := scheduler.blocker(deployment)
+	if conflict != nil && !wait {
+		scheduler.mu.Unlock()
+		return conflict
+	}
+
+	scheduler.enqueue(deployment)
+	if conflict != nil {
+		conflict = scheduler.blocker(deployment)
+	}
+	for scheduler.blocker(deployment) != nil {
+		changed := scheduler.changed
+		scheduler.mu.Unlock()
+
+		if conflict != nil {
+			queued(conflict)
+			conflict = nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+		}
+
+		scheduler.mu.Lock()
+		if ctx.Err() != nil {
+			scheduler.dequeue(deployment)
+			scheduler.notify()
+			scheduler.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+
+	scheduler.dequeue(deployment)
+	deployment.Started = time.Now()
+	for _, location := range deployment.locations() {
+		scheduler.running[location] = deployment
+	}
+	scheduler.mu.Unlock()
+
+	return nil
+}
+
+func (scheduler *Scheduler) Running() []*Deployment {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	running := []*Deployment{}
+	for _, deployment := range scheduler.running {
+		if !slices.Contains(running, deployment) {
+			running = append(running, deployment)
+		}
+	}
+
+	slices.SortFunc(running, func(a, b *Deployment) int { return a.Started.Compare(b.Started) })
+	return running
+}
+
+func (scheduler *Scheduler) Queued() []Deployment {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	queued := make([]Deployment, len(scheduler.queue))
+	for i, deployment := range scheduler.queue {
+		queued[i] = *deployment
+	}
+
+	return queued
+}
+
+func (scheduler *Scheduler) Cancel(id string, user *discordgo.User) (*Deployment, bool) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	for _, queued := range scheduler.queue {
+		if queued.ID == id && queued.cancel != nil {
+			queued.cancel(fmt.Errorf("%w by <@%s>", errCancelled, user.ID))
+			scheduler.dequeue(queued)
+			scheduler.notify()
+			return queued, true
+		}
+	}
+
+	return nil, false
+}
+
+func (scheduler *Scheduler) Release(deployment *Deployment) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	for _, location := range deployment.locations() {
+		if scheduler.running[location] == deployment {
+			delete(scheduler.running, location)
+		}
+	}
+	scheduler.notify()
+}
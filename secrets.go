@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SecretsProvider interface {
+	Fetch(ctx context.Context) (map[string]string, error)
+}
+
+type VaultProvider struct {
+	Address string
+	Token   string
+	Path    string
+}
+
+type SSMProvider struct {
+	Credentials AWSCredentials
+	Path        string
+}
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = map[string]string{}
+)
+
+func newSecretsProvider() (SecretsProvider, error) {
+	switch provider := os.Getenv("SECRETS_PROVIDER"); provider {
+	case "":
+		return nil, nil
+	case "vault":
+		return &VaultProvider{Address: strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"), Token: os.Getenv("VAULT_TOKEN"), Path: os.Getenv("SECRETS_PATH")}, nil
+	case "ssm":
+		return &SSMProvider{Credentials: awsCredentials(), Path: os.Getenv("SECRETS_PATH")}, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider: %s", provider)
+	}
+}
+
+func (provider *VaultProvider) Fetch(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.Address+"/v1/"+strings.TrimLeft(provider.Path, "/"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequestWithContext(): %w", err)
+	}
+	req.Header.Set("X-Vault-Token", provider.Token)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http.Do(): %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault: %s returned %s", provider.Path, res.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("json.Decode(): %w", err)
+	}
+
+	fields := body.Data
+	if nested, ok := body.Data["data"]; ok {
+		if err := json.Unmarshal(nested, &fields); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+		}
+	}
+
+	values := map[string]string{}
+	for name, raw := range fields {
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			values[name] = value
+		}
+	}
+
+	return values, nil
+}
+
+func (provider *SSMProvider) Fetch(ctx context.Context) (map[string]string, error) {
+	values := map[string]string{}
+	endpoint := fmt.Sprintf("https://ssm.%s.amazonaws.com/", provider.Credentials.Region)
+	token := ""
+
+	for {
+		input := map[string]any{"Path": provider.Path, "Recursive": true, "WithDecryption": true}
+		if token != "" {
+			input["NextToken"] = token
+		}
+		body, _ := json.Marshal(input)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("http.NewRequestWithContext(): %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+		req.Header.Set("X-Amz-Target", "AmazonSSM.GetParametersByPath")
+		signAWSRequest(req, body, provider.Credentials, "ssm", time.Now())
+
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("http.Do(): %w", err)
+		}
+
+		var page struct {
+			Parameters []struct {
+				Name  string `json:"Name"`
+				Value string `json:"Value"`
+			} `json:"Parameters"`
+			NextToken string `json:"NextToken"`
+			Message   string `json:"message"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("ssm: GetParametersByPath returned %s: %s", res.Status, page.Message)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("json.Decode(): %w", err)
+		}
+
+		for _, parameter := range page.Parameters {
+			values[path.Base(parameter.Name)] = parameter.Value
+		}
+
+		if token = page.NextToken; token == "" {
+			return values, nil
+		}
+	}
+}
+
+func loadSecrets(ctx context.Context, provider SecretsProvider) error {
+	values, err := provider.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	for name, value := range values {
+		if previous, ok := secrets[name]; ok && previous != value && name == "TOKEN" {
+			log.Println("Secret TOKEN changed, restart the bot to reconnect with the new token.")
+		}
+		secrets[name] = value
+	}
+
+	return nil
+}
+
+func secret(name, fallback string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+
+	if value, ok := secrets[name]; ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+func refreshSecrets(provider SecretsProvider, interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := loadSecrets(ctx, provider); err != nil {
+			log.Printf("loadSecrets(): %v", err)
+		}
+		cancel()
+	}
+}
+
+func secretEnv(names []string) []string {
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		if value := secret(name, os.Getenv(name)); value != "" {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
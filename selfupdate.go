@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"deploy/executor"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+func selfUpdate(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if data.AdminRole == "" || !slices.Contains(message.Member.Roles, data.AdminRole) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	if data.SelfUpdateRepository == "" {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "selfupdate.unconfigured"))
+		return
+	}
+
+	if running := scheduler.Running(); len(running) > 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "selfupdate.refused", "id", running[0].ID))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tag, err := installLatestRelease(ctx)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "selfupdate.failed", "error", err.Error()))
+		log.Printf("installLatestRelease(): %v", err)
+		return
+	}
+
+	if tag == "" {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "selfupdate.current", "version", version))
+		return
+	}
+
+	log.Printf("Self-update to %s requested by %s (%s)", tag, message.Author.Username, message.Author.ID)
+	announce(session, fmt.Sprintf("Deploy bot updated from `%s` to `%s` by <@%s>, restarting...", version, tag, message.Author.ID), 0x3b82f6)
+	session.Close()
+
+	if err := restartSelf(); err != nil {
+		log.Fatalf("restartSelf(): %v", err)
+	}
+}
+
+func installLatestRelease(ctx context.Context) (string, error) {
+	release, err := getGithubRelease(ctx, data.SelfUpdateRepository, "latest")
+	if err != nil {
+		return "", err
+	}
+
+	if release.TagName == version {
+		return "", nil
+	}
+
+	name := strings.NewReplacer("${TAG}", release.TagName, "${OS}", runtime.GOOS, "${ARCH}", runtime.GOARCH).Replace(data.SelfUpdateAsset)
+	asset, ok := release.Asset(name)
+	if !ok {
+		return "", fmt.Errorf("asset %s not found in release %s", name, release.TagName)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("os.Executable(): %w", err)
+	}
+
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return "", fmt.Errorf("filepath.EvalSymlinks(): %w", err)
+	}
+
+	tmp, err := os.MkdirTemp("", "deploy-self-update-")
+	if err != nil {
+		return "", fmt.Errorf("os.MkdirTemp(): %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	staged := filepath.Join(filepath.Dir(executable), "."+filepath.Base(executable)+".new")
+	defer os.Remove(staged)
+
+	sum, err := downloadGithubAsset(ctx, asset, staged)
+	if err != nil {
+		return "", err
+	}
+
+	entry := &Entry{Checksums: data.SelfUpdateChecksums, Signature: data.SelfUpdateSignature, Keyring: data.SelfUpdateKeyring}
+	if err := verifyArtifact(ctx, entry, release, release.TagName, tmp, asset.Name, sum); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(staged, 0o755); err != nil {
+		return "", fmt.Errorf("os.Chmod(): %w", err)
+	}
+
+	if err := os.Rename(staged, executable); err != nil {
+		return "", fmt.Errorf("os.Rename(): %w", err)
+	}
+
+	return release.TagName, nil
+}
+
+func restartSelf() error {
+	if data.SelfUpdateService != "" {
+		return exec.Command("systemctl", "restart", data.SelfUpdateService).Start()
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	return executor.Reexec(executable)
+}
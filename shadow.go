@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"deploy/engine"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var shadowSkipped = map[string]bool{
+	"systemd":    true,
+	"pm2":        true,
+	"supervisor": true,
+	"drain":      true,
+	"register":   true,
+	"purge":      true,
+	"argocd":     true,
+	"nomad":      true,
+	"flag":       true,
+	"ci":         true,
+}
+
+func deployShadow(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 2 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "shadow.usage"))
+		return
+	}
+
+	deployment, _, err := newDeployment(environmentByChannel(message.ChannelID), message.Author, args)
+	if err != nil {
+		session.ChannelMessageSend(message.ChannelID, err.Error())
+		return
+	}
+
+	if entry := deployment.Entry; entry.Strategy != "releases" && (entry.Strategy != "" || len(entry.Steps) == 0) {
+		deployment.finish(nil)
+		session.ChannelMessageSend(message.ChannelID, deployment.text(message.ChannelID, "shadow.unsupported"))
+		return
+	}
+
+	msg, err := session.ChannelMessageSend(message.ChannelID, deployment.text(message.ChannelID, "shadow.started"))
+	if err != nil {
+		deployment.finish(err)
+		return
+	}
+	log.Printf("Shadow deployment started. Username: %s (%s) - Environment: %s - Key: %s - Branch: %s", message.Author.Username, message.Author.ID, deployment.Environment.Name, deployment.Key, deployment.Branch)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(deployment.context(), deployment.timeout())
+		defer cancel()
+
+		output, err := deployment.shadow(ctx, session, msg)
+		deployment.finish(err)
+
+		content, status := deployment.text(msg.ChannelID, "shadow.passed"), "success"
+		if err != nil {
+			content, status = deployment.text(msg.ChannelID, "shadow.failed", "error", err.Error()), "failed"
+		}
+		deployment.audit("shadow", status, nil, nil)
+
+		session.ChannelMessageEdit(msg.ChannelID, msg.ID, content)
+		session.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+			Files:     []*discordgo.File{{Name: deployment.ID + "-shadow.log", ContentType: "text/plain", Reader: bytes.NewReader([]byte(cleanOutput(string(output))))}},
+			Reference: msg.Reference(),
+		})
+	}()
+}
+
+func (deployment *Deployment) shadow(ctx context.Context, session *discordgo.Session, msg *discordgo.Message) ([]byte, error) {
+	output := &bytes.Buffer{}
+	entry, environment := deployment.Entry, deployment.Environment
+
+	scratch, err := os.MkdirTemp("", "deploy-shadow-")
+	if err != nil {
+		return nil, fmt.Errorf("os.MkdirTemp(): %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	var cleanup func()
+	if deployment.gitEnv, cleanup, err = gitCredentials(environment); err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	repository := entry.Repository
+	if repository == "" {
+		dir, _ := environmentRepo(environment)
+		remote, err := gitLines(ctx, dir, 1, "remote", "get-url", "origin")
+		if err != nil || len(remote) == 0 {
+			return nil, fmt.Errorf("no origin remote configured in %s", dir)
+		}
+		repository = remote[0]
+	}
+
+	ref := deployment.Branch
+	if deployment.SHA != "" {
+		ref = deployment.SHA
+	}
+
+	if err := gitEnv(ctx, deployment.gitEnv, output, "clone", "--quiet", repository, scratch); err != nil {
+		return output.Bytes(), err
+	}
+	if err := git(ctx, output, "-C", scratch, "checkout", "--quiet", ref); err != nil {
+		return output.Bytes(), err
+	}
+
+	if entry.Strategy == "releases" {
+		if entry.Build == "" {
+			return output.Bytes(), nil
+		}
+
+		out, err := deployment.execute(ctx, scratch, entry.Build, "${RELEASE}", scratch)
+		output.Write(out)
+		if err != nil {
+			return output.Bytes(), fmt.Errorf("build: %w", err)
+		}
+		return output.Bytes(), nil
+	}
+
+	deployment.Progress = newProgress(entry.Steps)
+	track, stop := context.WithCancel(ctx)
+	tracked := make(chan struct{})
+	go func() {
+		defer close(tracked)
+		deployment.Progress.Track(track, func(status string) {
+			embeds := []*discordgo.MessageEmbed{{Description: status, Color: 0x3b82f6}}
+			session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Embeds: &embeds})
+		})
+	}()
+
+	request := deployment.request()
+	request.Location = scratch
+	request.Vars["SHADOW"] = "1"
+	request.Skip = shadowSkipped
+	request.Progress = deployment.Progress.Set
+	request.Completed = deployment.Progress.Finish
+	request.Steps = map[string]engine.StepFunc{
+		"migrations": func(ctx context.Context, request *engine.Request, step *Step, output *bytes.Buffer) error {
+			out, err := request.Execute(ctx, request.Location, step.Pending)
+			output.Write(out)
+			if err != nil {
+				return fmt.Errorf("pending: %w", err)
+			}
+			return nil
+		},
+	}
+
+	result, err := engine.Run(ctx, *request)
+	output.Write(result.Output)
+	stop()
+	<-tracked
+
+	color := 0x008000
+	if err != nil {
+		color = 0x800000
+	}
+	embeds := []*discordgo.MessageEmbed{{Description: deployment.Progress.Render(), Color: color}}
+	session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Embeds: &embeds})
+
+	return output.Bytes(), err
+}
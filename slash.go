@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var applicationCommands = []*discordgo.ApplicationCommand{
+	{
+		Name:        "deploy",
+		Description: "Deploy a branch, tag or commit",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "ref", Description: "Branch, tag or commit to deploy, leave empty to pick interactively"},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "key", Description: "Dictionary key to run, leave empty to pick interactively"},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "reason", Description: "Reason or ticket reference for the change"},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "otp", Description: "TOTP code for protected keys"},
+		},
+	},
+	{
+		Name: "Deploy this",
+		Type: discordgo.MessageApplicationCommand,
+	},
+}
+
+var slashHandlers = map[string]func(*discordgo.Session, *discordgo.InteractionCreate){
+	"deploy":      slashDeploy,
+	"Deploy this": deployThis,
+}
+
+func registerCommands(session *discordgo.Session) {
+	guilds := []string{""}
+	if data.AllowedGuilds != "" {
+		guilds = strings.Split(data.AllowedGuilds, ",")
+	}
+
+	for _, guild := range guilds {
+		if _, err := session.ApplicationCommandBulkOverwrite(session.State.User.ID, guild, applicationCommands); err != nil {
+			log.Printf("session.ApplicationCommandBulkOverwrite(): %v", err)
+		}
+	}
+}
+
+func slashOptions(interaction *discordgo.InteractionCreate) map[string]string {
+	options := map[string]string{}
+	for _, option := range interaction.ApplicationCommandData().Options {
+		options[option.Name] = option.StringValue()
+	}
+	return options
+}
+
+func editEphemeral(session *discordgo.Session, interaction *discordgo.InteractionCreate, content string) {
+	session.InteractionResponseEdit(interaction.Interaction, &discordgo.WebhookEdit{Content: &content})
+}
+
+func slashDeploy(session *discordgo.Session, interaction *discordgo.InteractionCreate) {
+	options := slashOptions(interaction)
+	if options["ref"] == "" && options["key"] == "" {
+		openPicker(session, interaction, "")
+		return
+	}
+
+	args := append([]string{options["ref"], options["key"]}, strings.Fields(options["reason"])...)
+	if options["otp"] != "" {
+		args = append(args, "otp:"+options["otp"])
+	}
+
+	environment := environmentByChannel(interaction.ChannelID)
+	if entry, ok := Commands[options["key"]]; ok && len(entry.Params) > 0 && len(entry.Params) <= maxModalParams {
+		openParamsModal(session, interaction, environment, options["key"], args)
+		return
+	}
+
+	deployInteraction(session, interaction, environment, args)
+}
+
+func deployInteraction(session *discordgo.Session, interaction *discordgo.InteractionCreate, environment *Environment, args []string) {
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	if entry, ok := Commands[args[1]]; ok && entry.Aggregate() {
+		editEphemeral(session, interaction, text(interaction.ChannelID, "plan.started", "key", args[1]))
+		go deployPlan(session, interaction.ChannelID, environment, interaction.Member.User, args)
+		return
+	}
+
+	deployment, code, err := newDeployment(environment, interaction.Member.User, args)
+	if err != nil {
+		editEphemeral(session, interaction, err.Error())
+		return
+	}
+
+	if deployment.Entry.TOTP && !confirmTOTP(session, interaction.ChannelID, deployment, code) {
+		deployment.finish(errTOTP)
+		editEphemeral(session, interaction, deployment.text(interaction.ChannelID, "deploy.totp_failed"))
+		return
+	}
+
+	editEphemeral(session, interaction, deployment.text(interaction.ChannelID, "deploy.started"))
+	startDeployment(session, interaction.ChannelID, deployment)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type Check struct {
+	Name   string `json:"name"`
+	Run    string `json:"run"`
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+type CheckResult struct {
+	Check *Check
+	Err   error
+}
+
+func runSmokeTests(ctx context.Context, deployment *Deployment) []CheckResult {
+	ctx, span := tracer.Start(ctx, "smoke tests")
+	defer span.End()
+
+	return runChecks(ctx, deployment, deployment.Environment.Smoke)
+}
+
+func runChecks(ctx context.Context, deployment *Deployment, checks []*Check) []CheckResult {
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		var err error
+		switch {
+		case check.URL != "":
+			err = probe(ctx, deployment.expand(check.URL), check.Status)
+		case check.Run != "":
+			if output, runErr := deployment.execute(ctx, deployment.Environment.Location, check.Run); runErr != nil {
+				err = fmt.Errorf("%w: %s", runErr, tail(sanitizeOutput(string(output)), 200))
+			}
+		default:
+			err = fmt.Errorf("check has neither url nor run")
+		}
+		results = append(results, CheckResult{Check: check, Err: err})
+	}
+
+	return results
+}
+
+func smokeFailures(results []CheckResult) (failures int) {
+	for _, result := range results {
+		if result.Err != nil {
+			failures++
+		}
+	}
+
+	return failures
+}
+
+func smokeSummary(results []CheckResult) string {
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			lines = append(lines, fmt.Sprintf("❌ %s: %s", result.Check.Name, result.Err.Error()))
+		} else {
+			lines = append(lines, fmt.Sprintf("✅ %s", result.Check.Name))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
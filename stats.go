@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"deploy/history"
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const statsPeriod = 30 * 24 * time.Hour
+
+func reliabilities(environment string, period time.Duration) []*history.Reliability {
+	var reliabilities []*history.Reliability
+	store.View(func(state *State) {
+		reliabilities = history.Reliabilities(state.History, environment, time.Now().Add(-period))
+	})
+	return reliabilities
+}
+
+func reliabilityBadges(reliability *history.Reliability) string {
+	badges := []string{}
+	if reliability.Streak >= 10 {
+		badges = append(badges, fmt.Sprintf("🔥 %d in a row", reliability.Streak))
+	}
+	if reliability.Deployments >= 10 && reliability.Failures == 0 {
+		badges = append(badges, "🏅 flawless")
+	}
+	return strings.Join(badges, " ")
+}
+
+func stats(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	environment, period, label := environmentByChannel(message.ChannelID), statsPeriod, "30d"
+	if len(args) > 0 {
+		d, err := parsePeriod(args[0])
+		if err != nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "stats.usage"))
+			return
+		}
+		period, label = d, args[0]
+	}
+
+	lines := []string{}
+	for _, reliability := range reliabilities(environment.Name, period) {
+		mttr := "-"
+		if reliability.Recoveries > 0 {
+			mttr = reliability.MTTR.Round(time.Second).String()
+		}
+		lines = append(lines, fmt.Sprintf("%-12s %4d deploys %6.1f%% success  %5.2f/day  MTTR %-8s streak %d %s", reliability.Key, reliability.Deployments, reliability.SuccessRate()*100, reliability.Frequency, mttr, reliability.Streak, reliabilityBadges(reliability)))
+	}
+
+	if len(lines) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "stats.none"))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "stats.title", "environment", environment.Name, "period", label)+"\n```\n"+strings.Join(lines, "\n")+"\n```")
+}
+
+func debugMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics := []struct {
+		name, help string
+		value      func(*history.Reliability) float64
+	}{
+		{"deploy_deployments", "Deployments over the last 30 days.", func(r *history.Reliability) float64 { return float64(r.Deployments) }},
+		{"deploy_success_ratio", "Share of successful deployments over the last 30 days.", (*history.Reliability).SuccessRate},
+		{"deploy_frequency_per_day", "Average deployments per day over the last 30 days.", func(r *history.Reliability) float64 { return r.Frequency }},
+		{"deploy_mttr_seconds", "Mean time to restore after a failed deployment over the last 30 days.", func(r *history.Reliability) float64 { return r.MTTR.Seconds() }},
+		{"deploy_success_streak", "Consecutive successful deployments.", func(r *history.Reliability) float64 { return float64(r.Streak) }},
+	}
+
+	reliabilities := reliabilities("", statsPeriod)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", metric.name, metric.help, metric.name)
+		for _, reliability := range reliabilities {
+			fmt.Fprintf(w, "%s{environment=%q,key=%q} %g\n", metric.name, reliability.Environment, reliability.Key, metric.value(reliability))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type State struct {
+	Slots       map[string]string             `json:"slots"`
+	Maintenance map[string]*MaintenanceState  `json:"maintenance"`
+	Backups     []*Backup                     `json:"backups"`
+	History     []*Record                     `json:"history"`
+	Deployed    map[string]string             `json:"deployed"`
+	Locks       map[string]*Lock              `json:"locks"`
+	TOTP        map[string]*TOTPEnrollment    `json:"totp"`
+	Previews    map[string]*PreviewState      `json:"previews"`
+	Tokens      map[string]*APIToken          `json:"tokens"`
+	Idempotency map[string]*IdempotentRequest `json:"idempotency"`
+	Locales     map[string]string             `json:"locales"`
+}
+
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	state State
+}
+
+var store *Store
+
+func openStore(path string) (*Store, error) {
+	store := &Store{path: path}
+
+	body, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("os.ReadFile(): %w", err)
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &store.state); err != nil {
+			return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+		}
+	}
+
+	if store.state.Slots == nil {
+		store.state.Slots = map[string]string{}
+	}
+
+	if store.state.Deployed == nil {
+		store.state.Deployed = map[string]string{}
+	}
+
+	if store.state.TOTP == nil {
+		store.state.TOTP = map[string]*TOTPEnrollment{}
+	}
+
+	if store.state.Locks == nil {
+		store.state.Locks = map[string]*Lock{}
+	}
+
+	if store.state.Maintenance == nil {
+		store.state.Maintenance = map[string]*MaintenanceState{}
+	}
+
+	if store.state.Previews == nil {
+		store.state.Previews = map[string]*PreviewState{}
+	}
+
+	if store.state.Tokens == nil {
+		store.state.Tokens = map[string]*APIToken{}
+	}
+
+	if store.state.Idempotency == nil {
+		store.state.Idempotency = map[string]*IdempotentRequest{}
+	}
+
+	if store.state.Locales == nil {
+		store.state.Locales = map[string]string{}
+	}
+
+	return store, nil
+}
+
+func (store *Store) View(fn func(state *State)) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	fn(&store.state)
+}
+
+func (store *Store) Update(fn func(state *State)) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	fn(&store.state)
+
+	body, err := json.MarshalIndent(&store.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.MarshalIndent(): %w", err)
+	}
+
+	tmp := store.path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0o600); err != nil {
+		return fmt.Errorf("os.WriteFile(): %w", err)
+	}
+
+	if err := os.Rename(tmp, store.path); err != nil {
+		return fmt.Errorf("os.Rename(): %w", err)
+	}
+
+	return nil
+}
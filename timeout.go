@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const (
+	timeoutWarning   = time.Minute
+	timeoutExtension = 5 * time.Minute
+)
+
+var errTimeout = errors.New("deployment timed out")
+
+func (deployment *Deployment) watchTimeout(session *discordgo.Session, channelID string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(deployment.context())
+	deadline := time.Now().Add(deployment.timeout())
+
+	if stallTimeout > 0 {
+		deployment.active = &atomic.Int64{}
+		deployment.touch()
+		go deployment.watchStall(ctx, cancel, session, channelID)
+	}
+
+	go func() {
+		for {
+			warning := time.NewTimer(time.Until(deadline.Add(-timeoutWarning)))
+			select {
+			case <-ctx.Done():
+				warning.Stop()
+				return
+			case <-warning.C:
+			}
+
+			id := newID()
+			decision, done := awaitDecision(id)
+			msg, err := session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+				Content:    deployment.text(channelID, "timeout.warning", "deadline", deadline.Unix()),
+				Components: decisionButtons(id, discordgo.Button{Label: text(channelID, "timeout.extend"), Style: discordgo.PrimaryButton, CustomID: "extend"}),
+			})
+
+			edit := func(content string) {
+				if err == nil {
+					components := []discordgo.MessageComponent{}
+					session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+				}
+			}
+
+			expired := time.NewTimer(time.Until(deadline))
+			select {
+			case <-ctx.Done():
+				edit(deployment.text(channelID, "timeout.finished"))
+			case <-expired.C:
+				edit(deployment.text(channelID, "timeout.expired"))
+				cancel(errTimeout)
+			case choice := <-decision:
+				deadline = deadline.Add(timeoutExtension)
+				edit(deployment.text(channelID, "timeout.extended", "user", choice.User.ID, "deadline", deadline.Unix()))
+			}
+			expired.Stop()
+			done()
+
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return ctx, func() { cancel(context.Canceled) }
+}
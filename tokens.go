@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var tokenActions = []string{"deploy", "read"}
+
+type APIToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Hash      string    `json:"hash"`
+	Scopes    []string  `json:"scopes"`
+	CreatedBy string    `json:"created_by"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires,omitzero"`
+}
+
+type tokenContext struct{}
+
+func (token *APIToken) Allows(action, environment string) bool {
+	return slices.ContainsFunc(token.Scopes, func(scope string) bool {
+		scoped, pattern, _ := strings.Cut(scope, ":")
+		matched, err := path.Match(pattern, environment)
+		return scoped == action && err == nil && matched
+	})
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func authenticateToken(raw string) *APIToken {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, "dpl_"), "_")
+	if !ok || !strings.HasPrefix(raw, "dpl_") {
+		return nil
+	}
+
+	var token *APIToken
+	store.View(func(state *State) { token = state.Tokens[id] })
+	if token == nil || !token.Expires.IsZero() && time.Now().After(token.Expires) {
+		return nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(token.Hash)) != 1 {
+		return nil
+	}
+	return token
+}
+
+func requestToken(r *http.Request) *APIToken {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+	return authenticateToken(raw)
+}
+
+func withToken(ctx context.Context, token *APIToken) context.Context {
+	return context.WithValue(ctx, tokenContext{}, token)
+}
+
+func tokenFrom(ctx context.Context) *APIToken {
+	token, _ := ctx.Value(tokenContext{}).(*APIToken)
+	return token
+}
+
+func scopeAllowed(ctx context.Context, action, environment string) bool {
+	token := tokenFrom(ctx)
+	return token == nil || token.Allows(action, environment)
+}
+
+func tokens(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if data.AdminRole == "" || !slices.Contains(message.Member.Roles, data.AdminRole) {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "interaction.forbidden"))
+		return
+	}
+
+	if len(args) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.usage"))
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "create":
+		createToken(session, message, args[1:])
+	case "revoke":
+		revokeToken(session, message, args[1:])
+	case "list":
+		listTokens(session, message)
+	default:
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.unknown", "command", args[0]))
+	}
+}
+
+func createToken(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	token := &APIToken{ID: newID(), CreatedBy: message.Author.ID, Created: time.Now().UTC()}
+	name := []string{}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case (args[i] == "--scope" || args[i] == "--expires") && i+1 < len(args):
+			flag, value := args[i], args[i+1]
+			i++
+
+			if flag == "--expires" {
+				d, err := parsePeriod(value)
+				if err != nil {
+					session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.invalid_expiry", "value", value))
+					return
+				}
+				token.Expires = token.Created.Add(d)
+				continue
+			}
+
+			action, pattern, ok := strings.Cut(value, ":")
+			if _, err := path.Match(pattern, ""); !ok || err != nil || pattern == "" || !slices.Contains(tokenActions, action) {
+				session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.invalid_scope", "scope", value, "actions", strings.Join(tokenActions, "|")))
+				return
+			}
+			token.Scopes = append(token.Scopes, value)
+		default:
+			name = append(name, args[i])
+		}
+	}
+
+	if len(token.Scopes) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.create_usage"))
+		return
+	}
+	token.Name = strings.Join(name, " ")
+
+	secret := make([]byte, 24)
+	rand.Read(secret)
+	raw := "dpl_" + token.ID + "_" + hex.EncodeToString(secret)
+	token.Hash = hashToken(hex.EncodeToString(secret))
+
+	if err := store.Update(func(state *State) { state.Tokens[token.ID] = token }); err != nil {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.save_failed", "error", err.Error()))
+		return
+	}
+
+	channel, err := session.UserChannelCreate(message.Author.ID)
+	if err == nil {
+		_, err = session.ChannelMessageSend(channel.ID, text(message.ChannelID, "token.secret", "id", token.ID, "scopes", strings.Join(token.Scopes, ", "), "token", raw))
+	}
+	if err != nil {
+		store.Update(func(state *State) { delete(state.Tokens, token.ID) })
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.dm_failed"))
+		return
+	}
+
+	auditLog.Record(&AuditEvent{Type: "token", Status: "created", Actor: message.Author.ID, Username: message.Author.Username, Detail: map[string]string{"token": token.ID, "scopes": strings.Join(token.Scopes, " ")}})
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.created", "id", token.ID, "scopes", strings.Join(token.Scopes, " ")))
+}
+
+func revokeToken(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.revoke_usage"))
+		return
+	}
+
+	found := false
+	store.Update(func(state *State) {
+		if _, found = state.Tokens[args[0]]; found {
+			delete(state.Tokens, args[0])
+		}
+	})
+
+	if !found {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.not_found", "id", args[0]))
+		return
+	}
+
+	auditLog.Record(&AuditEvent{Type: "token", Status: "revoked", Actor: message.Author.ID, Username: message.Author.Username, Detail: map[string]string{"token": args[0]}})
+	session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.revoked", "id", args[0]))
+}
+
+func listTokens(session *discordgo.Session, message *discordgo.MessageCreate) {
+	lines := []string{}
+	store.View(func(state *State) {
+		for _, id := range slices.Sorted(maps.Keys(state.Tokens)) {
+			token := state.Tokens[id]
+			expires := "never"
+			if !token.Expires.IsZero() {
+				expires = token.Expires.Format("2006-01-02")
+			}
+			lines = append(lines, fmt.Sprintf("%s %-30s created %s by %s, expires %s %s", token.ID, strings.Join(token.Scopes, " "), token.Created.Format("2006-01-02"), token.CreatedBy, expires, token.Name))
+		}
+	})
+
+	if len(lines) == 0 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "token.none"))
+		return
+	}
+
+	session.ChannelMessageSend(message.ChannelID, codeBlock(lines))
+}
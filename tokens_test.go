@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPITokenAllows(t *testing.T) {
+	token := &APIToken{Scopes: []string{"deploy:staging", "read:*", "deploy:preview-*", "deploy:["}}
+
+	tests := []struct {
+		action      string
+		environment string
+		want        bool
+	}{
+		{"deploy", "staging", true},
+		{"deploy", "production", false},
+		{"deploy", "preview-42", true},
+		{"deploy", "preview", false},
+		{"read", "production", true},
+		{"read", "", true},
+		{"admin", "staging", false},
+		{"deploy", "[", false},
+	}
+
+	for _, test := range tests {
+		if got := token.Allows(test.action, test.environment); got != test.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", test.action, test.environment, got, test.want)
+		}
+	}
+
+	if (&APIToken{}).Allows("read", "staging") {
+		t.Errorf("Allows() = true for a token without scopes")
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	testStore(t)
+	store.Update(func(state *State) {
+		state.Tokens["abc"] = &APIToken{ID: "abc", Hash: hashToken("secret")}
+		state.Tokens["old"] = &APIToken{ID: "old", Hash: hashToken("secret"), Expires: time.Now().Add(-time.Minute)}
+		state.Tokens["new"] = &APIToken{ID: "new", Hash: hashToken("secret"), Expires: time.Now().Add(time.Hour)}
+	})
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"Valid", "dpl_abc_secret", "abc"},
+		{"NotExpired", "dpl_new_secret", "new"},
+		{"Expired", "dpl_old_secret", ""},
+		{"WrongSecret", "dpl_abc_other", ""},
+		{"UnknownID", "dpl_xyz_secret", ""},
+		{"MissingPrefix", "abc_secret", ""},
+		{"MissingSecret", "dpl_abc", ""},
+		{"EmptySecret", "dpl_abc_", ""},
+		{"Empty", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ""
+			if token := authenticateToken(test.raw); token != nil {
+				got = token.ID
+			}
+			if got != test.want {
+				t.Errorf("authenticateToken(%q) = %q, want %q", test.raw, got, test.want)
+			}
+		})
+	}
+}
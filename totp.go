@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const (
+	totpStep   = 30
+	totpWindow = 2 * time.Minute
+)
+
+var (
+	errTOTP      = errors.New("totp verification failed")
+	totpPattern  = regexp.MustCompile(`^otp:([0-9]{6})$`)
+	totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	totpPromptsMu sync.Mutex
+	totpPrompts   = map[string]string{}
+)
+
+type TOTPEnrollment struct {
+	Secret    string    `json:"secret"`
+	Confirmed bool      `json:"confirmed"`
+	Enrolled  time.Time `json:"enrolled"`
+	Counter   int64     `json:"counter"`
+}
+
+func totpCode(secret []byte, counter int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000)
+}
+
+func verifyTOTP(userID, code string, enrolling bool) bool {
+	valid := false
+	store.Update(func(state *State) {
+		enrollment := state.TOTP[userID]
+		if enrollment == nil || enrollment.Confirmed == enrolling {
+			return
+		}
+
+		secret, err := totpEncoding.DecodeString(enrollment.Secret)
+		if err != nil {
+			return
+		}
+
+		now := time.Now().Unix() / totpStep
+		for counter := now - 1; counter <= now+1; counter++ {
+			if counter > enrollment.Counter && hmac.Equal([]byte(totpCode(secret, counter)), []byte(code)) {
+				enrollment.Counter, enrollment.Confirmed, valid = counter, true, true
+				return
+			}
+		}
+	})
+	return valid
+}
+
+func totpEnrolled(userID string) (enrolled bool) {
+	store.View(func(state *State) { enrolled = state.TOTP[userID] != nil && state.TOTP[userID].Confirmed })
+	return enrolled
+}
+
+func totpArgs(args []string) ([]string, string) {
+	for i, arg := range args {
+		if match := totpPattern.FindStringSubmatch(arg); match != nil {
+			return append(args[:i:i], args[i+1:]...), match[1]
+		}
+	}
+
+	return args, ""
+}
+
+func confirmTOTP(session *discordgo.Session, channelID string, deployment *Deployment, code string) (verified bool) {
+	defer func() {
+		status := "denied"
+		if verified {
+			status = "approved"
+		}
+		deployment.audit("approval", status, nil, map[string]string{"gate": "totp"})
+	}()
+
+	if !totpEnrolled(deployment.Author.ID) {
+		session.ChannelMessageSend(channelID, deployment.text(channelID, "totp.enroll_first"))
+		return false
+	}
+
+	if code != "" {
+		if !verifyTOTP(deployment.Author.ID, code, false) {
+			session.ChannelMessageSend(channelID, text(channelID, "totp.invalid"))
+			return false
+		}
+		return true
+	}
+
+	id := newID()
+	decision, done := awaitDecision(id)
+	defer done()
+
+	totpPromptsMu.Lock()
+	totpPrompts[id] = deployment.Author.ID
+	totpPromptsMu.Unlock()
+	defer func() {
+		totpPromptsMu.Lock()
+		delete(totpPrompts, id)
+		totpPromptsMu.Unlock()
+	}()
+
+	prompt, err := session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:    deployment.text(channelID, "totp.prompt"),
+		Components: buttons(discordgo.Button{Label: text(channelID, "totp.enter_button"), Style: discordgo.PrimaryButton, CustomID: "totp:" + id}),
+	})
+	if err != nil {
+		return false
+	}
+
+	result := text(channelID, "totp.not_entered")
+	select {
+	case choice := <-decision:
+		if verified = verifyTOTP(deployment.Author.ID, choice.Choice, false); verified {
+			result = text(channelID, "totp.accepted")
+		} else {
+			result = text(channelID, "totp.rejected")
+		}
+	case <-time.After(totpWindow):
+	}
+
+	components := []discordgo.MessageComponent{}
+	session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: prompt.ChannelID, ID: prompt.ID, Content: &result, Components: &components})
+	return verified
+}
+
+func totpButton(session *discordgo.Session, interaction *discordgo.InteractionCreate, args []string) {
+	if len(args) < 1 {
+		return
+	}
+
+	totpPromptsMu.Lock()
+	owner, ok := totpPrompts[args[0]]
+	totpPromptsMu.Unlock()
+
+	if !ok {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "interaction.inactive"))
+		return
+	}
+
+	if owner != interaction.Member.User.ID {
+		respondEphemeral(session, interaction, text(interaction.ChannelID, "totp.requester_only"))
+		return
+	}
+
+	session.InteractionRespond(interaction.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "decision:" + args[0],
+			Title:    text(interaction.ChannelID, "totp.modal_title"),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{CustomID: "code", Label: text(interaction.ChannelID, "totp.code_label"), Style: discordgo.TextInputShort, Required: true, MinLength: 6, MaxLength: 6},
+				}},
+			},
+		},
+	})
+}
+
+func totp(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	if len(args) < 1 {
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.usage"))
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "enroll":
+		if totpEnrolled(message.Author.ID) && (len(args) < 2 || !verifyTOTP(message.Author.ID, args[1], false)) {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.already_enrolled"))
+			return
+		}
+
+		secret := make([]byte, 20)
+		rand.Read(secret)
+		encoded := totpEncoding.EncodeToString(secret)
+
+		channel, err := session.UserChannelCreate(message.Author.ID)
+		if err != nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.dm_unavailable"))
+			return
+		}
+
+		uri := fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s", url.PathEscape("Deploy"), url.PathEscape(message.Author.Username), encoded, url.QueryEscape("Deploy"))
+		if _, err := session.ChannelMessageSend(channel.ID, text(message.ChannelID, "totp.secret", "secret", encoded, "uri", uri)); err != nil {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.dm_failed"))
+			return
+		}
+
+		store.Update(func(state *State) {
+			state.TOTP[message.Author.ID] = &TOTPEnrollment{Secret: encoded, Enrolled: time.Now().UTC()}
+		})
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.check_dm"))
+		log.Printf("TOTP enrollment started. Username: %s (%s)", message.Author.Username, message.Author.ID)
+	case "verify":
+		if len(args) < 2 || !verifyTOTP(message.Author.ID, args[1], true) {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.verify_failed"))
+			return
+		}
+
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.confirmed"))
+		log.Printf("TOTP enrollment confirmed. Username: %s (%s)", message.Author.Username, message.Author.ID)
+	case "reset":
+		if data.AdminRole == "" || !slices.Contains(message.Member.Roles, data.AdminRole) {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.reset_forbidden"))
+			return
+		}
+
+		if len(args) < 2 {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.reset_usage"))
+			return
+		}
+
+		user := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(args[1], "<@"), "!"), ">")
+		removed := false
+		store.Update(func(state *State) {
+			_, removed = state.TOTP[user]
+			delete(state.TOTP, user)
+		})
+		if !removed {
+			session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.not_enrolled", "user", user))
+			return
+		}
+
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.reset", "user", user))
+		log.Printf("TOTP enrollment of %s reset. Username: %s (%s)", user, message.Author.Username, message.Author.ID)
+	default:
+		session.ChannelMessageSend(message.ChannelID, text(message.ChannelID, "totp.usage"))
+	}
+}
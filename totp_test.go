@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T) {
+	t.Helper()
+
+	var err error
+	if store, err = openStore(t.TempDir() + "/store.json"); err != nil {
+		t.Fatalf("openStore(): %v", err)
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	// RFC 6238 appendix B, SHA-1, truncated to six digits.
+	tests := []struct {
+		time int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, test := range tests {
+		if got := totpCode(secret, test.time/totpStep); got != test.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", test.time, got, test.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	// Stay clear of a step boundary so the codes below keep their offsets.
+	if time.Now().Unix()%totpStep == totpStep-1 {
+		time.Sleep(time.Second)
+	}
+	now := time.Now().Unix() / totpStep
+
+	tests := []struct {
+		name      string
+		counter   int64
+		confirmed bool
+		enrolling bool
+		code      string
+		want      bool
+	}{
+		{"Current", 0, true, false, totpCode(secret, now), true},
+		{"PreviousStep", 0, true, false, totpCode(secret, now-1), true},
+		{"NextStep", 0, true, false, totpCode(secret, now+1), true},
+		{"TooOld", 0, true, false, totpCode(secret, now-2), false},
+		{"TooNew", 0, true, false, totpCode(secret, now+2), false},
+		{"Replayed", now, true, false, totpCode(secret, now), false},
+		{"OlderThanLastUsed", now, true, false, totpCode(secret, now-1), false},
+		{"NewerThanLastUsed", now - 1, true, false, totpCode(secret, now), true},
+		{"Wrong", 0, true, false, "000000", totpCode(secret, now) == "000000"},
+		{"Unconfirmed", 0, false, false, totpCode(secret, now), false},
+		{"Enrolling", 0, false, true, totpCode(secret, now), true},
+		{"EnrollingConfirmed", 0, true, true, totpCode(secret, now), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testStore(t)
+			store.Update(func(state *State) {
+				state.TOTP["user"] = &TOTPEnrollment{Secret: totpEncoding.EncodeToString(secret), Confirmed: test.confirmed, Counter: test.counter}
+			})
+
+			if got := verifyTOTP("user", test.code, test.enrolling); got != test.want {
+				t.Errorf("verifyTOTP(%s) = %v, want %v", test.code, got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	testStore(t)
+	secret := []byte("12345678901234567890")
+	store.Update(func(state *State) {
+		state.TOTP["user"] = &TOTPEnrollment{Secret: totpEncoding.EncodeToString(secret), Confirmed: true}
+	})
+
+	code := totpCode(secret, time.Now().Unix()/totpStep)
+	if !verifyTOTP("user", code, false) {
+		t.Fatalf("verifyTOTP(%s) = false on first use", code)
+	}
+	if verifyTOTP("user", code, false) {
+		t.Errorf("verifyTOTP(%s) = true on second use", code)
+	}
+	if verifyTOTP("other", code, false) {
+		t.Errorf("verifyTOTP() = true for a user without an enrollment")
+	}
+}
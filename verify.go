@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	errVerification = errors.New("artifact verification failed")
+
+	gpgRejected = []string{"BADSIG", "ERRSIG", "EXPSIG", "EXPKEYSIG", "REVKEYSIG", "NO_PUBKEY"}
+)
+
+func verifyArtifact(ctx context.Context, entry *Entry, release *GithubRelease, tag, dir, name, sum string) error {
+	if entry.Checksums == "" {
+		return fmt.Errorf("%w: no checksums asset configured for %s", errVerification, name)
+	}
+
+	checksums, err := fetchVerificationAsset(ctx, release, strings.ReplaceAll(entry.Checksums, "${TAG}", tag), dir)
+	if err != nil {
+		return err
+	}
+
+	if entry.Signature != "" {
+		signature, err := fetchVerificationAsset(ctx, release, strings.ReplaceAll(entry.Signature, "${TAG}", tag), dir)
+		if err != nil {
+			return err
+		}
+
+		if err := verifySignature(ctx, entry.Keyring, signature, checksums); err != nil {
+			return err
+		}
+	}
+
+	expected, err := lookupChecksum(checksums, name)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(expected, sum) {
+		return fmt.Errorf("%w: checksum mismatch for %s: expected %s, got %s", errVerification, name, expected, sum)
+	}
+
+	return nil
+}
+
+func fetchVerificationAsset(ctx context.Context, release *GithubRelease, name, dir string) (string, error) {
+	asset, ok := release.Asset(name)
+	if !ok {
+		return "", fmt.Errorf("%w: asset %s not found in release %s", errVerification, name, release.TagName)
+	}
+
+	path := filepath.Join(dir, asset.Name)
+	if _, err := downloadGithubAsset(ctx, asset, path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func verifySignature(ctx context.Context, keyring, signature, path string) error {
+	args := []string{"--batch", "--status-fd", "1"}
+	if keyring != "" {
+		args = append(args, "--no-default-keyring", "--keyring", keyring)
+	}
+
+	output, err := exec.CommandContext(ctx, "gpg", append(args, "--verify", signature, path)...).CombinedOutput()
+	if err != nil || !validSignature(string(output)) {
+		return fmt.Errorf("%w: bad signature for %s: %s", errVerification, filepath.Base(path), strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func validSignature(status string) bool {
+	good, valid := false, false
+	for line := range strings.Lines(status) {
+		fields := strings.Fields(strings.TrimPrefix(line, "[GNUPG:] "))
+		if !strings.HasPrefix(line, "[GNUPG:] ") || len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "GOODSIG":
+			good = true
+		case fields[0] == "VALIDSIG":
+			valid = true
+		case slices.Contains(gpgRejected, fields[0]):
+			return false
+		}
+	}
+
+	return good && valid
+}
+
+func (deployment *Deployment) notifyVerification(err error) {
+	event := deploymentEvent("verification_failed", deployment.Environment.Name, deployment.Branch, deployment.Author.ID, err.Error(), nil, Field{Name: "Key", Value: deployment.Key, Inline: true})
+	event.Deployment, event.Username = deployment.ID, deployment.Author.Username
+	event.Description = "Artifact Verification Failed!"
+	event.Color = 0x800000
+	publish(event)
+}
+
+func lookupChecksum(path, name string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("os.Open(): %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("scanner.Err(): %w", err)
+	}
+
+	return "", fmt.Errorf("%w: no checksum listed for %s", errVerification, name)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidSignature(t *testing.T) {
+	const (
+		good    = "[GNUPG:] GOODSIG 1234567890ABCDEF Release Signing <release@example.com>\n"
+		valid   = "[GNUPG:] VALIDSIG 0123456789ABCDEF0123456789ABCDEF01234567 2026-01-01 1767225600 0 4 0 1 10 00 0123456789ABCDEF0123456789ABCDEF01234567\n"
+		trusted = "[GNUPG:] TRUST_UNDEFINED 0 pgp\n"
+	)
+
+	tests := []struct {
+		name   string
+		status string
+		want   bool
+	}{
+		{"Valid", "[GNUPG:] NEWSIG\n" + good + valid + trusted, true},
+		{"GoodOnly", good, false},
+		{"ValidOnly", valid, false},
+		{"ExpiredKey", "[GNUPG:] EXPKEYSIG 1234567890ABCDEF Release Signing\n" + valid, false},
+		{"ExpiredSignature", "[GNUPG:] EXPSIG 1234567890ABCDEF Release Signing\n" + valid, false},
+		{"RevokedKey", good + "[GNUPG:] REVKEYSIG 1234567890ABCDEF Release Signing\n" + valid, false},
+		{"BadSignature", "[GNUPG:] BADSIG 1234567890ABCDEF Release Signing\n", false},
+		{"UnknownKey", "[GNUPG:] ERRSIG 1234567890ABCDEF 1 10 00 1767225600 9\n[GNUPG:] NO_PUBKEY 1234567890ABCDEF\n", false},
+		{"Unprefixed", "GOODSIG 1234567890ABCDEF\nVALIDSIG 0123456789ABCDEF\n", false},
+		{"Empty", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := validSignature(test.status); got != test.want {
+				t.Errorf("validSignature() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVerifyArtifactRequiresChecksums(t *testing.T) {
+	err := verifyArtifact(context.Background(), &Entry{}, &GithubRelease{TagName: "v1.0.0"}, "v1.0.0", t.TempDir(), "app.tar.gz", "")
+	if !errors.Is(err, errVerification) {
+		t.Errorf("verifyArtifact() = %v, want %v", err, errVerification)
+	}
+}
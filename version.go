@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+var (
+	version = "dev"
+	commit  = ""
+	built   = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && commit == "":
+			commit = setting.Value
+		case setting.Key == "vcs.time" && built == "":
+			built = setting.Value
+		}
+	}
+}
+
+func versionString() string {
+	if commit == "" {
+		return version
+	}
+
+	return fmt.Sprintf("%s (%.7s)", version, commit)
+}
+
+func deployVersion(session *discordgo.Session, message *discordgo.MessageCreate, args []string) {
+	buildTime := "unknown"
+	if t, err := time.Parse(time.RFC3339, built); err == nil {
+		buildTime = fmt.Sprintf("<t:%d:f>", t.Unix())
+	}
+
+	session.ChannelMessageSend(message.ChannelID, fmt.Sprintf("Deploy bot `%s`\nCommit: `%s`\nBuilt: %s\nGo: `%s %s/%s`\nUptime: `%s`", version, commit, buildTime, runtime.Version(), runtime.GOOS, runtime.GOARCH, uptime()))
+}
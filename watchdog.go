@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const stallPoll = 15 * time.Second
+
+var (
+	errStalled   = errors.New("deployment killed after producing no output")
+	stallTimeout time.Duration
+)
+
+func parseStallTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < time.Minute {
+		return 0, fmt.Errorf("invalid stall timeout %q, expected a duration of at least 1m", value)
+	}
+
+	return timeout, nil
+}
+
+func (deployment *Deployment) touch() {
+	if deployment.active != nil {
+		deployment.active.Store(time.Now().UnixNano())
+	}
+}
+
+func (deployment *Deployment) lastActivity() time.Time {
+	if deployment.active == nil {
+		return deployment.Started
+	}
+	return time.Unix(0, deployment.active.Load())
+}
+
+func (deployment *Deployment) watchStall(ctx context.Context, cancel context.CancelCauseFunc, session *discordgo.Session, channelID string) {
+	for {
+		if idle := time.Since(deployment.lastActivity()); idle < stallTimeout {
+			timer := time.NewTimer(stallTimeout - idle)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+
+		id := newID()
+		decision, done := awaitDecision(id)
+		last := deployment.lastActivity()
+		msg, err := session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content: deployment.text(channelID, "watchdog.warning", "since", last.Unix()),
+			Components: decisionButtons(id,
+				discordgo.Button{Label: text(channelID, "watchdog.kill"), Style: discordgo.DangerButton, CustomID: "kill"},
+				discordgo.Button{Label: text(channelID, "watchdog.wait"), Style: discordgo.SecondaryButton, CustomID: "wait"},
+			),
+		})
+
+		edit := func(content string) {
+			if err == nil {
+				components := []discordgo.MessageComponent{}
+				session.ChannelMessageEditComplex(&discordgo.MessageEdit{Channel: msg.ChannelID, ID: msg.ID, Content: &content, Components: &components})
+			}
+		}
+
+		ticker := time.NewTicker(stallPoll)
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				edit(deployment.text(channelID, "watchdog.finished"))
+				break wait
+			case <-ticker.C:
+				if deployment.lastActivity().After(last) {
+					edit(deployment.text(channelID, "watchdog.resumed"))
+					break wait
+				}
+			case choice := <-decision:
+				if choice.Choice == "kill" {
+					edit(deployment.text(channelID, "watchdog.killed", "user", choice.User.ID))
+					cancel(fmt.Errorf("%w, killed by <@%s>", errStalled, choice.User.ID))
+				} else {
+					deployment.touch()
+					edit(deployment.text(channelID, "watchdog.waiting", "user", choice.User.ID))
+				}
+				break wait
+			}
+		}
+		ticker.Stop()
+		done()
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/jacobbernoulli/discordgo"
+)
+
+const deletedSHA = "0000000000000000000000000000000000000000"
+
+type WebhookEvent struct {
+	Provider   string
+	Name       string
+	Action     string
+	Repository string
+	Branch     string
+	Before     string
+	SHA        string
+	Sender     string
+	Number     int
+}
+
+type WebhookProvider struct {
+	Verify func(r *http.Request, body []byte) bool
+	Parse  func(r *http.Request, body []byte) ([]*WebhookEvent, error)
+}
+
+var webhookProviders = map[string]*WebhookProvider{
+	"github":    {Verify: hmacSignature("X-Hub-Signature-256"), Parse: parseGithubEvent},
+	"gitlab":    {Verify: gitlabToken, Parse: parseGitlabEvent},
+	"bitbucket": {Verify: hmacSignature("X-Hub-Signature"), Parse: parseBitbucketEvent},
+}
+
+var (
+	gitlabActions    = map[string]string{"open": "opened", "reopen": "reopened", "update": "synchronize", "close": "closed", "merge": "closed"}
+	bitbucketActions = map[string]string{"pullrequest:created": "opened", "pullrequest:updated": "synchronize", "pullrequest:fulfilled": "closed", "pullrequest:rejected": "closed"}
+)
+
+func (event *WebhookEvent) describe() string {
+	if event.Name == "pull_request" {
+		return fmt.Sprintf("pull request #%d %s by %s", event.Number, event.Action, event.Sender)
+	}
+	return fmt.Sprintf("push to %s by %s", event.Branch, event.Sender)
+}
+
+func hmacSignature(header string) func(r *http.Request, body []byte) bool {
+	return func(r *http.Request, body []byte) bool {
+		return validHMAC(data.WebhookSecret, r.Header.Get(header), body)
+	}
+}
+
+func validHMAC(secret, signature string, body []byte) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok || secret == "" {
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func gitlabToken(r *http.Request, body []byte) bool {
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(data.WebhookSecret)) == 1
+}
+
+func parseGithubEvent(r *http.Request, body []byte) ([]*WebhookEvent, error) {
+	payload := struct {
+		Ref        string `json:"ref"`
+		Before     string `json:"before"`
+		After      string `json:"after"`
+		Deleted    bool   `json:"deleted"`
+		Action     string `json:"action"`
+		Number     int    `json:"number"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+		PullRequest struct {
+			Head struct {
+				Ref string `json:"ref"`
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+
+	event := &WebhookEvent{Provider: "github", Action: payload.Action, Repository: payload.Repository.FullName, Sender: payload.Sender.Login, Number: payload.Number}
+	switch r.Header.Get("X-GitHub-Event") {
+	case "push":
+		branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/")
+		if !ok || payload.Deleted {
+			return nil, nil
+		}
+		event.Name, event.Branch, event.Before, event.SHA = "push", branch, payload.Before, payload.After
+	case "pull_request":
+		event.Name, event.Branch, event.SHA = "pull_request", payload.PullRequest.Head.Ref, payload.PullRequest.Head.SHA
+	default:
+		return nil, nil
+	}
+
+	return []*WebhookEvent{event}, nil
+}
+
+func parseGitlabEvent(r *http.Request, body []byte) ([]*WebhookEvent, error) {
+	payload := struct {
+		Ref          string `json:"ref"`
+		Before       string `json:"before"`
+		After        string `json:"after"`
+		UserUsername string `json:"user_username"`
+		User         struct {
+			Username string `json:"username"`
+		} `json:"user"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+		ObjectAttributes struct {
+			IID          int    `json:"iid"`
+			Action       string `json:"action"`
+			SourceBranch string `json:"source_branch"`
+			LastCommit   struct {
+				ID string `json:"id"`
+			} `json:"last_commit"`
+		} `json:"object_attributes"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+
+	event := &WebhookEvent{Provider: "gitlab", Repository: payload.Project.PathWithNamespace}
+	switch r.Header.Get("X-Gitlab-Event") {
+	case "Push Hook":
+		branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/")
+		if !ok || payload.After == deletedSHA {
+			return nil, nil
+		}
+		event.Name, event.Branch, event.Before, event.SHA, event.Sender = "push", branch, payload.Before, payload.After, payload.UserUsername
+	case "Merge Request Hook":
+		attributes := payload.ObjectAttributes
+		action, ok := gitlabActions[attributes.Action]
+		if !ok {
+			return nil, nil
+		}
+		event.Name, event.Action, event.Number, event.Sender = "pull_request", action, attributes.IID, payload.User.Username
+		event.Branch, event.SHA = attributes.SourceBranch, attributes.LastCommit.ID
+	default:
+		return nil, nil
+	}
+
+	return []*WebhookEvent{event}, nil
+}
+
+func parseBitbucketEvent(r *http.Request, body []byte) ([]*WebhookEvent, error) {
+	type commit struct {
+		Hash string `json:"hash"`
+	}
+
+	payload := struct {
+		Actor struct {
+			Nickname string `json:"nickname"`
+		} `json:"actor"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Push struct {
+			Changes []struct {
+				Old *struct {
+					Target commit `json:"target"`
+				} `json:"old"`
+				New *struct {
+					Type   string `json:"type"`
+					Name   string `json:"name"`
+					Target commit `json:"target"`
+				} `json:"new"`
+			} `json:"changes"`
+		} `json:"push"`
+		PullRequest struct {
+			ID     int `json:"id"`
+			Source struct {
+				Branch struct {
+					Name string `json:"name"`
+				} `json:"branch"`
+				Commit commit `json:"commit"`
+			} `json:"source"`
+		} `json:"pullrequest"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal(): %w", err)
+	}
+
+	key := r.Header.Get("X-Event-Key")
+	if key == "repo:push" {
+		events := []*WebhookEvent{}
+		for _, change := range payload.Push.Changes {
+			if change.New == nil || change.New.Type != "branch" {
+				continue
+			}
+			event := &WebhookEvent{Provider: "bitbucket", Name: "push", Repository: payload.Repository.FullName, Branch: change.New.Name, SHA: change.New.Target.Hash, Sender: payload.Actor.Nickname}
+			if change.Old != nil {
+				event.Before = change.Old.Target.Hash
+			}
+			events = append(events, event)
+		}
+		return events, nil
+	}
+
+	action, ok := bitbucketActions[key]
+	if !ok {
+		return nil, nil
+	}
+
+	pull := payload.PullRequest
+	return []*WebhookEvent{{Provider: "bitbucket", Name: "pull_request", Action: action, Repository: payload.Repository.FullName, Branch: pull.Source.Branch.Name, SHA: pull.Source.Commit.Hash, Sender: payload.Actor.Nickname, Number: pull.ID}}, nil
+}
+
+func webhookHandler(session *discordgo.Session, provider *WebhookProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+		if err != nil {
+			http.Error(w, "could not read body", http.StatusBadRequest)
+			return
+		}
+
+		if !provider.Verify(r, body) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		events, err := provider.Parse(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			for _, event := range events {
+				autoDeploy(session, event)
+			}
+		}()
+	}
+}
+
+func serveWebhooks(session *discordgo.Session, addr string) error {
+	if data.WebhookSecret == "" {
+		return fmt.Errorf("refusing to listen on %s without WEBHOOK_SECRET", addr)
+	}
+
+	mux := http.NewServeMux()
+	for name, provider := range webhookProviders {
+		mux.HandleFunc("/webhooks/"+name, webhookHandler(session, provider))
+	}
+	mux.HandleFunc("POST /hooks/deploy", deployHookHandler(session))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Webhook endpoint listening on %s", listener.Addr())
+	go http.Serve(listener, mux)
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestValidHMAC(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	digest := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		want      bool
+	}{
+		{"Valid", "secret", "sha256=" + digest, true},
+		{"WrongSecret", "other", "sha256=" + digest, false},
+		{"TamperedDigest", "secret", "sha256=" + digest[:62] + "00", false},
+		{"MissingPrefix", "secret", digest, false},
+		{"OtherAlgorithm", "secret", "sha1=" + digest, false},
+		{"Truncated", "secret", "sha256=" + digest[:32], false},
+		{"TooLong", "secret", "sha256=" + digest + "00", false},
+		{"NotHex", "secret", "sha256=" + digest[:62] + "zz", false},
+		{"Empty", "secret", "", false},
+		{"NoSecret", "", "sha256=" + digest, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := validHMAC(test.secret, test.signature, body); got != test.want {
+				t.Errorf("validHMAC(%q, %q) = %v, want %v", test.secret, test.signature, got, test.want)
+			}
+		})
+	}
+}